@@ -1,18 +1,29 @@
 package api
 
 import (
-	"os"
+	"zcelero/config"
+	"zcelero/controller"
 	"zcelero/routes"
 	"zcelero/service"
+	"zcelero/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // Start initializes Gin API
-func Start(textManagementService service.TextManagementServiceInteface) *gin.Engine {
-	gin.SetMode(os.Getenv("GIN_MODE"))
-	router := gin.Default()
+func Start(textManagementService service.TextManagementServiceInteface, jobService service.JobServiceInterface, healthService service.HealthServiceInterface, auditService service.AuditServiceInterface, webhookService service.WebhookServiceInterface, rotationService service.RotationServiceInterface, config config.Config) *gin.Engine {
+	gin.SetMode(config.GinMode)
+	binding.Validator = validation.NewValidator(config.KeySizes)
+	router := gin.New()
+	// X-Forwarded-For is only read from these proxies, the addresses were checked by config.Validate
+	router.SetTrustedProxies(config.TrustedProxies)
+	// the recovery doesn't print the panic itself, controller.Recovery logs it without the secrets
+	router.Use(controller.Metrics(), controller.Trace(), controller.RequestID(), controller.AccessLog(), gin.CustomRecoveryWithWriter(nil, controller.Recovery), controller.Authenticate())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(controller.NotFound)
+	router.NoMethod(controller.MethodNotAllowed)
 
-	routes.GetRoutes(router, textManagementService)
+	routes.GetRoutes(router, textManagementService, jobService, healthService, auditService, webhookService, rotationService)
 	return router
 }
@@ -0,0 +1,135 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	NotFound           Code = "not_found"
+	MethodNotAllowed   Code = "method_not_allowed"
+	InvalidId          Code = "invalid_id"
+	DecryptionFailed   Code = "decryption_failed"
+	WrongPassword      Code = "wrong_password"
+	Forbidden          Code = "forbidden"
+	ValidationFailed   Code = "validation_failed"
+	StorageUnavailable Code = "storage_unavailable"
+	ServiceUnavailable Code = "service_unavailable"
+	Internal           Code = "internal"
+)
+
+var statuses = map[Code]int{
+	NotFound:           http.StatusNotFound,
+	MethodNotAllowed:   http.StatusMethodNotAllowed,
+	InvalidId:          http.StatusBadRequest,
+	DecryptionFailed:   http.StatusUnprocessableEntity,
+	WrongPassword:      http.StatusForbidden,
+	Forbidden:          http.StatusForbidden,
+	ValidationFailed:   http.StatusBadRequest,
+	StorageUnavailable: http.StatusServiceUnavailable,
+	ServiceUnavailable: http.StatusServiceUnavailable,
+	Internal:           http.StatusInternalServerError,
+}
+
+// Error is an error safe to be shown to the client, the wrapped error is only meant for logs
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+	Fields  []FieldError
+}
+
+// FieldError describes why a single request field is not valid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is the RFC 7807 representation of an Error
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// New creates an error with the desired code and client message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// NewValidation creates a validation_failed error listing every invalid field
+func NewValidation(fields []FieldError) *Error {
+	return &Error{Code: ValidationFailed, Message: "request is not valid", Fields: fields}
+}
+
+// Wrap creates an error with the desired code and client message keeping the original error
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so errors.Is(err, apperror.New(apperror.NotFound, "")) works
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// CodeOf returns the code of the error, errors without one are internal
+func CodeOf(err error) Code {
+	var appError *Error
+	if errors.As(err, &appError) {
+		return appError.Code
+	}
+
+	return Internal
+}
+
+// Status returns the HTTP status of the error code
+func Status(code Code) int {
+	status, found := statuses[code]
+	if !found {
+		return http.StatusInternalServerError
+	}
+
+	return status
+}
+
+// ToProblem converts the error into a problem, hiding the message of errors without a code
+func ToProblem(err error, instance string) Problem {
+	code := Internal
+	detail := "internal server error"
+	var fields []FieldError
+
+	var appError *Error
+	if errors.As(err, &appError) {
+		code = appError.Code
+		detail = appError.Message
+		fields = appError.Fields
+	}
+
+	status := Status(code)
+
+	return Problem{
+		Type:     "/problems/" + string(code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+		Code:     code,
+		Errors:   fields,
+	}
+}
@@ -0,0 +1,102 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestToProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		instance string
+		want     Problem
+	}{
+		{
+			name:     "Problem from coded error",
+			err:      New(NotFound, "text not found"),
+			instance: "/v1/text-management",
+			want: Problem{
+				Type:     "/problems/not_found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "text not found",
+				Instance: "/v1/text-management",
+				Code:     NotFound,
+			},
+		},
+		{
+			name: "Problem from wrapped coded error",
+			err:  fmt.Errorf("loading: %w", Wrap(StorageUnavailable, "text could not be read", errors.New("open storage/a.json: permission denied"))),
+			want: Problem{
+				Type:   "/problems/storage_unavailable",
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: "text could not be read",
+				Code:   StorageUnavailable,
+			},
+		},
+		{
+			name: "Problem from error without code",
+			err:  errors.New("open storage/a.json: permission denied"),
+			want: Problem{
+				Type:   "/problems/internal",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "internal server error",
+				Code:   Internal,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToProblem(tt.err, tt.instance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToProblem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		code Code
+		want int
+	}{
+		{NotFound, http.StatusNotFound},
+		{MethodNotAllowed, http.StatusMethodNotAllowed},
+		{InvalidId, http.StatusBadRequest},
+		{DecryptionFailed, http.StatusUnprocessableEntity},
+		{WrongPassword, http.StatusForbidden},
+		{ValidationFailed, http.StatusBadRequest},
+		{StorageUnavailable, http.StatusServiceUnavailable},
+		{Code("unknown"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			if got := Status(tt.code); got != tt.want {
+				t.Errorf("Status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	cause := errors.New("permission denied")
+	err := Wrap(StorageUnavailable, "text could not be stored", cause)
+
+	if !errors.Is(err, New(StorageUnavailable, "")) {
+		t.Error("errors.Is() must match errors with the same code")
+	}
+	if errors.Is(err, New(NotFound, "")) {
+		t.Error("errors.Is() must not match errors with other codes")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is() must match the wrapped error")
+	}
+	if CodeOf(cause) != Internal {
+		t.Errorf("CodeOf() = %v, want %v", CodeOf(cause), Internal)
+	}
+}
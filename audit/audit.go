@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"zcelero/config"
+	"zcelero/entity"
+)
+
+// KeyEnv is the environment variable holding the HMAC key when no key file is configured
+const KeyEnv = "AUDIT_KEY"
+
+// minKeySize is the size of the shortest HMAC key accepted, the one of a SHA-256 output
+const minKeySize = 32
+
+// LoadKey reads the base64 HMAC key from the key file, or from $AUDIT_KEY when there is none. It returns
+// nil when no key is configured
+func LoadKey(auditConfig config.Audit, getenv func(key string) string) ([]byte, error) {
+	encoded := getenv(KeyEnv)
+	if auditConfig.KeyFile != "" {
+		content, err := os.ReadFile(auditConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("audit key could not be read: %w", err)
+		}
+		encoded = string(content)
+	}
+	if encoded = strings.TrimSpace(encoded); encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("audit key is not valid base64")
+	}
+	if len(key) < minKeySize {
+		return nil, fmt.Errorf("audit key must have at least %d bytes", minKeySize)
+	}
+
+	return key, nil
+}
+
+// Hash returns the hash of the entry, computed over all of its fields but the hash itself. Since the
+// entry holds the hash of the previous one, editing or removing an entry breaks every hash after it.
+// With a key it's an HMAC-SHA256, so the hashes can't be recomputed after an edit without the key
+func Hash(key []byte, entry entity.AuditEntry) string {
+	entry.Hash = ""
+	data, _ := json.Marshal(entry)
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Chain fills the sequence and the hashes of the entry following previous, a zero previous starts the log
+func Chain(key []byte, previous entity.AuditEntry, entry entity.AuditEntry) entity.AuditEntry {
+	entry.Sequence = previous.Sequence + 1
+	entry.PreviousHash = previous.Hash
+	entry.Hash = Hash(key, entry)
+
+	return entry
+}
+
+// Verify reads an audit log, one JSON entry per line, and returns the last entry when the chain is intact.
+// The error tells the first entry that was edited, removed or inserted. Entries removed from the end
+// can only be detected comparing the last hash with a copy kept elsewhere
+func Verify(key []byte, log io.Reader) (entity.AuditEntry, error) {
+	scanner := bufio.NewScanner(log)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	previous := entity.AuditEntry{}
+	for line := 1; scanner.Scan(); line++ {
+		entry := entity.AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return previous, fmt.Errorf("line %d is not an audit entry: %w", line, err)
+		}
+		if entry.Sequence != previous.Sequence+1 {
+			return previous, fmt.Errorf("line %d has sequence %d after %d, entries are missing or were reordered", line, entry.Sequence, previous.Sequence)
+		}
+		if entry.PreviousHash != previous.Hash {
+			return previous, fmt.Errorf("entry %d is not chained to entry %d, entries are missing or were replaced", entry.Sequence, previous.Sequence)
+		}
+		if !hmac.Equal([]byte(entry.Hash), []byte(Hash(key, entry))) {
+			return previous, fmt.Errorf("entry %d does not match its hash, it was edited", entry.Sequence)
+		}
+		previous = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return previous, fmt.Errorf("audit log could not be read: %w", err)
+	}
+
+	return previous, nil
+}
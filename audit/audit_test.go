@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zcelero/config"
+	"zcelero/entity"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func chain(t *testing.T, count int) []entity.AuditEntry {
+	entries := []entity.AuditEntry{}
+	previous := entity.AuditEntry{}
+	for i := 0; i < count; i++ {
+		previous = Chain(testKey, previous, entity.AuditEntry{
+			Time:    time.Date(2022, 11, 10, 0, i, 0, 0, time.UTC),
+			Action:  entity.AuditActionRead,
+			Outcome: entity.AuditOutcomeSuccess,
+			TextId:  "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec",
+		})
+		entries = append(entries, previous)
+	}
+
+	return entries
+}
+
+func encode(entries []entity.AuditEntry) *bytes.Buffer {
+	log := &bytes.Buffer{}
+	for _, entry := range entries {
+		line, _ := json.Marshal(entry)
+		log.Write(append(line, '\n'))
+	}
+
+	return log
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name         string
+		log          func(entries []entity.AuditEntry) *bytes.Buffer
+		wantSequence uint64
+		wantErr      string
+	}{
+		{
+			name:         "Intact log",
+			log:          encode,
+			wantSequence: 4,
+		},
+		{
+			name: "Empty log",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				return &bytes.Buffer{}
+			},
+		},
+		{
+			name: "Edited entry",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				entries[2].Principal = "CN=someone else"
+				return encode(entries)
+			},
+			wantSequence: 2,
+			wantErr:      "entry 3 does not match its hash",
+		},
+		{
+			name: "Edited entry with its hash",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				entries[1].Principal = "CN=someone else"
+				entries[1].Hash = Hash(testKey, entries[1])
+				return encode(entries)
+			},
+			wantSequence: 2,
+			wantErr:      "entry 3 is not chained to entry 2",
+		},
+		{
+			name: "Log rehashed without the key",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				previous := entity.AuditEntry{}
+				for i := range entries {
+					entries[i].Principal = "CN=someone else"
+					entries[i] = Chain(nil, previous, entries[i])
+					previous = entries[i]
+				}
+				return encode(entries)
+			},
+			wantErr: "entry 1 does not match its hash",
+		},
+		{
+			name: "Removed entry",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				return encode(append(entries[:1], entries[2:]...))
+			},
+			wantSequence: 1,
+			wantErr:      "line 2 has sequence 3 after 1",
+		},
+		{
+			name: "Malformed line",
+			log: func(entries []entity.AuditEntry) *bytes.Buffer {
+				log := encode(entries)
+				log.WriteString("{\"sequence\":5,\n")
+				return log
+			},
+			wantSequence: 4,
+			wantErr:      "line 5 is not an audit entry",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last, err := Verify(testKey, tt.log(chain(t, 4)))
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+			if last.Sequence != tt.wantSequence {
+				t.Errorf("Verify() last sequence = %d, want %d", last.Sequence, tt.wantSequence)
+			}
+		})
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "audit-key")
+	os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(testKey)+"\n"), 0600)
+
+	tests := []struct {
+		name    string
+		config  config.Audit
+		env     string
+		want    []byte
+		wantErr string
+	}{
+		{
+			name: "No key",
+		},
+		{
+			name:   "Key file",
+			config: config.Audit{KeyFile: keyFile},
+			env:    "ignored",
+			want:   testKey,
+		},
+		{
+			name: "Key from environment",
+			env:  base64.StdEncoding.EncodeToString(testKey),
+			want: testKey,
+		},
+		{
+			name:    "Short key",
+			env:     base64.StdEncoding.EncodeToString([]byte("secret")),
+			wantErr: "audit key must have at least 32 bytes",
+		},
+		{
+			name:    "Missing key file",
+			config:  config.Audit{KeyFile: keyFile + ".missing"},
+			wantErr: "audit key could not be read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadKey(tt.config, func(key string) string {
+				if key == KeyEnv {
+					return tt.env
+				}
+				return ""
+			})
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("LoadKey() error = %v, want %q", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("LoadKey() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
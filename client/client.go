@@ -0,0 +1,286 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+)
+
+// DefaultTimeout limits each attempt of a request when no other timeout is configured
+var DefaultTimeout = 30 * time.Second
+
+// DefaultRetries is how many times a failed request is tried again by default
+var DefaultRetries = 2
+
+// DefaultBackoff is the wait before the first retry, doubled on each following one
+var DefaultBackoff = 200 * time.Millisecond
+
+type ClientInterface interface {
+	Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error)
+	Get(ctx context.Context, textId, privateKey, password string) (string, error)
+	GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error)
+	Delete(ctx context.Context, textId, privateKey, password string) error
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+// Option customizes the client created by NewClient
+type Option func(c *Client)
+
+// WithHTTPClient sends the requests through the desired HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout limits each attempt of a request, zero disables the limit
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries defines how many times a failed request is tried again and the wait before the first retry
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// NewClient creates a client for the API served in baseURL, e.g. http://localhost:8080
+func NewClient(baseURL string, options ...Option) (ClientInterface, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	client := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+		retries:    DefaultRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	if client.retries < 0 {
+		client.retries = 0
+	}
+
+	return client, nil
+}
+
+// Insert stores the text, the returned value has the uuid and, for encrypted texts, the private key
+func (c *Client) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	body := struct {
+		TextData           string `json:"text_data"`
+		Encryption         *bool  `json:"encryption"`
+		EncryptionMode     string `json:"encryption_mode,omitempty"`
+		KeySize            uint64 `json:"key_size,omitempty"`
+		PrivateKeyPassword string `json:"private_key_password,omitempty"`
+	}{
+		TextData:           text.TextData,
+		Encryption:         text.Encryption,
+		EncryptionMode:     text.EncryptionMode,
+		KeySize:            text.KeySize,
+		PrivateKeyPassword: text.PrivateKeyPassword,
+	}
+
+	response := struct {
+		Uuid       string `json:"uuid"`
+		PrivateKey string `json:"private_key"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/v1/text-management", nil, body, &response)
+	if err != nil {
+		return entity.TextManagement{}, err
+	}
+
+	text.Uuid = response.Uuid
+	text.PrivateKey = response.PrivateKey
+	return text, nil
+}
+
+// Get reads the text, the private key and password are only needed for encrypted texts and the passphrase
+// texts only need the password
+func (c *Client) Get(ctx context.Context, textId, privateKey, password string) (string, error) {
+	// the API expects the private key in the body of the GET request
+	body := credentialsBody(privateKey, password)
+
+	response := struct {
+		Text string `json:"text"`
+	}{}
+	err := c.do(ctx, http.MethodGet, "/v1/text-management", url.Values{"id": {textId}}, body, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return response.Text, nil
+}
+
+// GetMetadata describes the text without decrypting it
+func (c *Client) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	metadata := entity.TextMetadata{}
+	err := c.do(ctx, http.MethodGet, "/v1/text-management/metadata", url.Values{"id": {textId}}, nil, &metadata)
+	if err != nil {
+		return entity.TextMetadata{}, err
+	}
+
+	return metadata, nil
+}
+
+// Delete removes the text. Encrypted texts need the private key and password, or only the password for the
+// passphrase texts, unless the client uses the certificate that stored the text
+func (c *Client) Delete(ctx context.Context, textId, privateKey, password string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/text-management", url.Values{"id": {textId}}, credentialsBody(privateKey, password), nil)
+}
+
+// credentialsBody is the body carrying the private key and password, nil when there are none
+func credentialsBody(privateKey, password string) any {
+	if privateKey == "" && password == "" {
+		return nil
+	}
+
+	return struct {
+		PrivateKey         string `json:"private_key"`
+		PrivateKeyPassword string `json:"private_key_password"`
+	}{
+		PrivateKey:         privateKey,
+		PrivateKeyPassword: password,
+	}
+}
+
+// do sends the request, retrying it while the failure is temporary
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, response any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	endpoint := *c.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, endpoint.String(), payload, response)
+		if err == nil || attempt >= c.retries || !retryable(method, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, endpoint string, payload []byte, response any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json, application/problem+json")
+
+	res, err := c.httpClient.Do(request)
+	if err != nil {
+		return &transportError{err: err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return decodeProblem(res)
+	}
+	if response == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(response)
+}
+
+// transportError marks failures where no response was received
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// retryable tells whether the request can be sent again, inserts are only retried when the
+// server answered that it is unavailable since a lost response may hide a stored text
+func retryable(method string, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var transport *transportError
+	if errors.As(err, &transport) {
+		return method != http.MethodPost
+	}
+
+	return apperror.CodeOf(err) == apperror.ServiceUnavailable || apperror.CodeOf(err) == apperror.StorageUnavailable
+}
+
+// decodeProblem converts the problem+json answer into an apperror, keeping its code and invalid fields
+func decodeProblem(res *http.Response) error {
+	problem := apperror.Problem{}
+	if err := json.NewDecoder(res.Body).Decode(&problem); err != nil || problem.Code == "" {
+		return apperror.New(codeForStatus(res.StatusCode), fmt.Sprintf("unexpected response status %d", res.StatusCode))
+	}
+
+	return &apperror.Error{Code: problem.Code, Message: problem.Detail, Fields: problem.Errors}
+}
+
+// codeForStatus guesses the code of answers that are not problems, e.g. from a proxy in front of the API
+func codeForStatus(status int) apperror.Code {
+	switch status {
+	case http.StatusNotFound:
+		return apperror.NotFound
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return apperror.ServiceUnavailable
+	default:
+		return apperror.Internal
+	}
+}
@@ -0,0 +1,249 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zcelero/api"
+	"zcelero/apperror"
+	"zcelero/client"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/repository"
+	"zcelero/service"
+)
+
+// newServer runs the real API with file storage behind an httptest server
+func newServer(t *testing.T) *httptest.Server {
+	os.Mkdir("storage", 0777)
+	t.Cleanup(func() { os.RemoveAll("storage") })
+
+	helper := helper.NewHelper()
+	textManagementService := service.NewService(repository.NewRepository(helper, "storage"), helper, nil, 0)
+	server := httptest.NewServer(api.Start(textManagementService, nil, nil, nil, nil, nil, config.Default()))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(t *testing.T, baseURL string, options ...client.Option) client.ClientInterface {
+	c, err := client.NewClient(baseURL, options...)
+	if err != nil {
+		t.Fatalf("client.NewClient() error = %v", err)
+	}
+
+	return c
+}
+
+func TestClient_EncryptedText(t *testing.T) {
+	c := newClient(t, newServer(t).URL)
+	ctx := context.Background()
+	encryption := true
+
+	inserted, err := c.Insert(ctx, entity.TextManagement{
+		TextData:           "encrypted text data",
+		Encryption:         &encryption,
+		KeySize:            1024,
+		PrivateKeyPassword: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Client.Insert() error = %v", err)
+	}
+	if inserted.Uuid == "" || inserted.PrivateKey == "" {
+		t.Fatalf("Client.Insert() = %v, want uuid and private key", inserted)
+	}
+
+	text, err := c.Get(ctx, inserted.Uuid, inserted.PrivateKey, "password123")
+	if err != nil || text != "encrypted text data" {
+		t.Errorf("Client.Get() = %v, %v, want encrypted text data", text, err)
+	}
+
+	_, err = c.Get(ctx, inserted.Uuid, inserted.PrivateKey, "password456")
+	if apperror.CodeOf(err) != apperror.WrongPassword {
+		t.Errorf("Client.Get() error = %v, want %s", err, apperror.WrongPassword)
+	}
+
+	metadata, err := c.GetMetadata(ctx, inserted.Uuid)
+	if err != nil || !metadata.Encrypted || metadata.KeySize != 1024 || metadata.CreatedAt == nil {
+		t.Errorf("Client.GetMetadata() = %v, %v", metadata, err)
+	}
+
+	if err := c.Delete(ctx, inserted.Uuid, inserted.PrivateKey, "password456"); apperror.CodeOf(err) != apperror.WrongPassword {
+		t.Errorf("Client.Delete() error = %v, want %s", err, apperror.WrongPassword)
+	}
+	if err := c.Delete(ctx, inserted.Uuid, inserted.PrivateKey, "password123"); err != nil {
+		t.Errorf("Client.Delete() error = %v", err)
+	}
+	if _, err := c.GetMetadata(ctx, inserted.Uuid); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("Client.GetMetadata() after delete error = %v, want %s", err, apperror.NotFound)
+	}
+}
+
+func TestClient_UnencryptedText(t *testing.T) {
+	c := newClient(t, newServer(t).URL)
+	ctx := context.Background()
+	encryption := false
+
+	inserted, err := c.Insert(ctx, entity.TextManagement{TextData: "text data", Encryption: &encryption})
+	if err != nil {
+		t.Fatalf("Client.Insert() error = %v", err)
+	}
+
+	text, err := c.Get(ctx, inserted.Uuid, "", "")
+	if err != nil || text != "text data" {
+		t.Errorf("Client.Get() = %v, %v, want text data", text, err)
+	}
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	c := newClient(t, newServer(t).URL)
+	encryption := true
+
+	_, err := c.Insert(context.Background(), entity.TextManagement{TextData: "text data", Encryption: &encryption})
+
+	var appError *apperror.Error
+	if !errors.As(err, &appError) || appError.Code != apperror.ValidationFailed || len(appError.Fields) != 2 {
+		t.Errorf("Client.Insert() error = %#v, want validation_failed with 2 fields", err)
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		failures  int32
+		status    int
+		retries   int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:      "Retry unavailable server until it answers",
+			method:    http.MethodGet,
+			failures:  2,
+			status:    http.StatusServiceUnavailable,
+			retries:   2,
+			wantCalls: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "Give up after the retries",
+			method:    http.MethodGet,
+			failures:  5,
+			status:    http.StatusServiceUnavailable,
+			retries:   2,
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "Do not retry client errors",
+			method:    http.MethodGet,
+			failures:  5,
+			status:    http.StatusNotFound,
+			retries:   2,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "Retry unavailable server on insert",
+			method:    http.MethodPost,
+			failures:  1,
+			status:    http.StatusBadGateway,
+			retries:   2,
+			wantCalls: 2,
+			wantErr:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, _ := url.Parse(newServer(t).URL)
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			calls := int32(0)
+			flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				proxy.ServeHTTP(w, r)
+			}))
+			defer flaky.Close()
+
+			c := newClient(t, flaky.URL, client.WithRetries(tt.retries, time.Millisecond))
+			encryption := false
+
+			var err error
+			if tt.method == http.MethodPost {
+				_, err = c.Insert(context.Background(), entity.TextManagement{TextData: "text data", Encryption: &encryption})
+			} else {
+				_, err = c.GetMetadata(context.Background(), "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec")
+				if apperror.CodeOf(err) == apperror.NotFound && atomic.LoadInt32(&calls) > tt.failures {
+					err = nil
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("request error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("server calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	calls := int32(0)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	c := newClient(t, slow.URL, client.WithTimeout(20*time.Millisecond), client.WithRetries(1, time.Millisecond))
+
+	_, err := c.GetMetadata(context.Background(), "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Client.GetMetadata() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("server calls = %d, want 2", got)
+	}
+
+	encryption := false
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Insert(context.Background(), entity.TextManagement{TextData: "text data", Encryption: &encryption})
+	if got := atomic.LoadInt32(&calls); err == nil || got != 1 {
+		t.Errorf("Client.Insert() error = %v with %d calls, want a single timed out call", err, got)
+	}
+}
+
+func TestClient_ContextCanceled(t *testing.T) {
+	c := newClient(t, newServer(t).URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetMetadata(ctx, "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Client.GetMetadata() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "://"} {
+		if _, err := client.NewClient(baseURL); err == nil {
+			t.Errorf("client.NewClient(%q) error = nil, want error", baseURL)
+		}
+	}
+}
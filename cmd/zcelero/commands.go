@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"zcelero/audit"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/envelope"
+	"zcelero/textcrypto"
+)
+
+// put stores the text and writes the private key of encrypted texts into a 0600 file
+func (c *cli) put(args []string) error {
+	flags := c.newFlagSet("put", "[file]")
+	encrypt := flags.Bool("encrypt", false, "encrypt the text with a new RSA key")
+	passphrase := flags.Bool("passphrase", false, "encrypt the text with a key derived from the password, without a key pair")
+	keySize := flags.Uint64("key-size", 2048, "RSA key size used with -encrypt")
+	passwordFile := flags.String("password-file", "", "file with the private key password, defaults to $ZCELERO_PASSWORD or a prompt")
+	keyOut := flags.String("key-out", "", "file receiving the private key, defaults to <uuid>.pem")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	text, err := c.readText(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	request := entity.TextManagement{TextData: text, Encryption: encrypt}
+	if *passphrase {
+		if *encrypt {
+			return fmt.Errorf("-encrypt and -passphrase can't be used together")
+		}
+		request.Encryption = passphrase
+		request.EncryptionMode = entity.EncryptionModePassphrase
+		request.PrivateKeyPassword, err = c.secret(*passwordFile, "ZCELERO_PASSWORD", "Password")
+		if err != nil {
+			return err
+		}
+	} else if *encrypt {
+		if _, err := os.Stat(*keyOut); *keyOut != "" && err == nil {
+			return fmt.Errorf("%s already exists", *keyOut)
+		}
+		request.KeySize = *keySize
+		request.PrivateKeyPassword, err = c.secret(*passwordFile, "ZCELERO_PASSWORD", "Private key password")
+		if err != nil {
+			return err
+		}
+	}
+
+	inserted, err := c.client.Insert(context.Background(), request)
+	if err != nil {
+		return err
+	}
+
+	if inserted.PrivateKey != "" {
+		path := *keyOut
+		if path == "" {
+			path = inserted.Uuid + ".pem"
+		}
+		if err := writePrivateKey(path, inserted.PrivateKey); err != nil {
+			// the key can't be generated again, so it is printed instead of lost
+			fmt.Fprintln(c.stdout, inserted.Uuid)
+			fmt.Fprint(c.stdout, inserted.PrivateKey)
+			return fmt.Errorf("text %s stored but the private key could not be written: %w", inserted.Uuid, err)
+		}
+		fmt.Fprintf(c.stderr, "private key written to %s\n", path)
+	}
+
+	fmt.Fprintln(c.stdout, inserted.Uuid)
+	return nil
+}
+
+// get prints the text, reading the private key and password only for encrypted texts
+func (c *cli) get(args []string) error {
+	flags := c.newFlagSet("get", "<id>")
+	keyFile := flags.String("key", "", "private key file, defaults to the PEM in $ZCELERO_PRIVATE_KEY")
+	passwordFile := flags.String("password-file", "", "file with the private key password, defaults to $ZCELERO_PASSWORD or a prompt")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	textId := flags.Arg(0)
+
+	privateKey, password, err := c.textCredentials(textId, *keyFile, *passwordFile)
+	if err != nil {
+		return err
+	}
+
+	text, err := c.client.Get(context.Background(), textId, privateKey, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.stdout, text)
+	return nil
+}
+
+// meta prints the metadata of the text as JSON
+func (c *cli) meta(args []string) error {
+	flags := c.newFlagSet("meta", "<id>")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	metadata, err := c.client.GetMetadata(context.Background(), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metadata)
+}
+
+// rm deletes the text, proving its ownership with the private key and password when it is encrypted
+func (c *cli) rm(args []string) error {
+	flags := c.newFlagSet("rm", "<id>")
+	keyFile := flags.String("key", "", "private key file, defaults to the PEM in $ZCELERO_PRIVATE_KEY")
+	passwordFile := flags.String("password-file", "", "file with the private key password, defaults to $ZCELERO_PASSWORD or a prompt")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	textId := flags.Arg(0)
+
+	privateKey, password, err := c.textCredentials(textId, *keyFile, *passwordFile)
+	if err != nil {
+		return err
+	}
+
+	return c.client.Delete(context.Background(), textId, privateKey, password)
+}
+
+// textCredentials reads the private key and password needed by the text, nothing for unencrypted texts and
+// only the password for the passphrase texts
+func (c *cli) textCredentials(textId, keyFile, passwordFile string) (privateKey, password string, err error) {
+	metadata, err := c.client.GetMetadata(context.Background(), textId)
+	if err != nil {
+		return "", "", err
+	}
+
+	if metadata.EncryptionMode == entity.EncryptionModePassphrase {
+		password, err = c.secret(passwordFile, "ZCELERO_PASSWORD", "Password")
+		return "", password, err
+	}
+	if metadata.Encrypted {
+		return c.credentials(keyFile, passwordFile)
+	}
+
+	return "", "", nil
+}
+
+// decrypt reads a file of the storage folder and prints its text, decrypting it like the server does
+func (c *cli) decrypt(args []string) error {
+	flags := c.newFlagSet("decrypt", "<storage/uuid.json>")
+	keyFile := flags.String("key", "", "private key file, defaults to the PEM in $ZCELERO_PRIVATE_KEY")
+	passwordFile := flags.String("password-file", "", "file with the private key password, defaults to $ZCELERO_PASSWORD or a prompt")
+	masterKeys := flags.String("master-keys", "", "file with the master keys of texts encrypted at rest, defaults to $"+envelope.KeysEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if record, sealed := envelope.Parse(data); sealed {
+		keyring, err := envelope.LoadKeyring(config.AtRest{KeysFile: *masterKeys, KeyId: record.KeyId}, c.getenv)
+		if err != nil {
+			return err
+		}
+		// the record id is the file name, as the server stores it
+		data, err = envelope.Open(keyring, strings.TrimSuffix(filepath.Base(flags.Arg(0)), ".json"), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	fileData := textcrypto.FileContent{}
+	if err := json.Unmarshal(data, &fileData); err != nil {
+		return fmt.Errorf("%s is not a stored text: %w", flags.Arg(0), err)
+	}
+
+	privateKey, password := "", ""
+	if fileData.Mode == textcrypto.ModePassphrase {
+		password, err = c.secret(*passwordFile, "ZCELERO_PASSWORD", "Password")
+		if err != nil {
+			return err
+		}
+	} else if fileData.Encrypted {
+		privateKey, password, err = c.credentials(*keyFile, *passwordFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	text, err := textcrypto.Open(fileData, privateKey, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.stdout, text)
+	return nil
+}
+
+// audit verifies the chain of the audit log, printing the last entry so its hash can be compared with a
+// copy kept elsewhere, since entries removed from the end leave the chain intact
+func (c *cli) audit(args []string) error {
+	flags := c.newFlagSet("audit", "<storage/audit/audit.log>")
+	keyFile := flags.String("key-file", "", "file with the HMAC key of the audit log, defaults to $"+audit.KeyEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	key, err := audit.LoadKey(config.Audit{KeyFile: *keyFile}, c.getenv)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	last, err := audit.Verify(key, file)
+	if err != nil {
+		return fmt.Errorf("audit log is not intact: %w", err)
+	}
+
+	fmt.Fprintf(c.stdout, "%d entries verified, last hash %s\n", last.Sequence, last.Hash)
+	return nil
+}
+
+// readText reads the file, or stdin when no file or "-" is given
+func (c *cli) readText(path string) (string, error) {
+	reader := c.stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	text, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	return string(text), nil
+}
+
+// credentials reads the private key and its password needed by encrypted texts
+func (c *cli) credentials(keyFile, passwordFile string) (string, string, error) {
+	privateKey, err := c.privateKey(keyFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err := c.secret(passwordFile, "ZCELERO_PASSWORD", "Private key password")
+	if err != nil {
+		return "", "", err
+	}
+
+	return privateKey, password, nil
+}
+
+// privateKey reads the PEM from the file or from $ZCELERO_PRIVATE_KEY
+func (c *cli) privateKey(path string) (string, error) {
+	if path != "" {
+		key, err := os.ReadFile(path)
+		return string(key), err
+	}
+	if key := c.getenv("ZCELERO_PRIVATE_KEY"); key != "" {
+		return key, nil
+	}
+
+	return "", fmt.Errorf("the text is encrypted, pass the private key with -key or $ZCELERO_PRIVATE_KEY")
+}
+
+// secret reads the value from the file, then from the environment variable and finally prompts for it
+func (c *cli) secret(path, env, label string) (string, error) {
+	if path != "" {
+		secret, err := os.ReadFile(path)
+		return strings.TrimRight(string(secret), "\r\n"), err
+	}
+	if secret := c.getenv(env); secret != "" {
+		return secret, nil
+	}
+
+	return c.prompt(label)
+}
+
+// writePrivateKey creates the key file readable only by its owner, refusing to overwrite an existing one
+func writePrivateKey(path, privateKey string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(privateKey); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
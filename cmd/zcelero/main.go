@@ -0,0 +1,185 @@
+// Command zcelero stores and retrieves texts from a Zcelero server.
+//
+// Usage:
+//
+//	zcelero [-server url] [-timeout duration] <command> [flags]
+//
+// The commands are put, get, meta, rm, decrypt and audit, the last two reading the files of the
+// storage folder directly, to recover texts while the server is down and to verify the audit log. Run "zcelero <command> -h" to see the flags of each one.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"zcelero/apperror"
+	"zcelero/client"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/term"
+)
+
+// exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) error
+	// offline commands don't talk to the server
+	offline bool
+}
+
+var commands = []command{
+	{name: "put", summary: "store a text read from a file or stdin", run: (*cli).put},
+	{name: "get", summary: "read a text, decrypting it with the private key", run: (*cli).get},
+	{name: "meta", summary: "describe a text without decrypting it", run: (*cli).meta},
+	{name: "rm", summary: "delete a text", run: (*cli).rm},
+	{name: "decrypt", summary: "read a stored file directly, without the server", run: (*cli).decrypt, offline: true},
+	{name: "audit", summary: "verify the hash chain of an audit log file", run: (*cli).audit, offline: true},
+}
+
+// cli holds everything the commands use from the outside world, so tests can replace it
+type cli struct {
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+	getenv    func(key string) string
+	prompt    func(label string) (string, error)
+	newClient func(server string, timeout time.Duration) (client.ClientInterface, error)
+
+	client client.ClientInterface
+}
+
+// errUsage is returned when the arguments are wrong, after the usage has been printed
+var errUsage = errors.New("usage")
+
+func main() {
+	// errors are reported by the commands, the logs of the shared packages would only add noise
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c := &cli{
+		stdin:     os.Stdin,
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		getenv:    os.Getenv,
+		prompt:    promptTerminal,
+		newClient: newClient,
+	}
+
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) run(args []string) int {
+	flags := flag.NewFlagSet("zcelero", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	server := flags.String("server", envOr(c.getenv, "ZCELERO_SERVER", "http://localhost:8080"), "server URL, defaults to $ZCELERO_SERVER")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each request")
+	flags.Usage = func() {
+		fmt.Fprintln(c.stderr, "usage: zcelero [-server url] [-timeout duration] <command> [flags]")
+		fmt.Fprintln(c.stderr, "\ncommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(c.stderr, "  %-7s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintln(c.stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != flags.Arg(0) {
+			continue
+		}
+
+		if !cmd.offline {
+			var err error
+			c.client, err = c.newClient(*server, *timeout)
+			if err != nil {
+				fmt.Fprintf(c.stderr, "zcelero: %s\n", err.Error())
+				return exitUsage
+			}
+		}
+
+		return c.report(cmd.run(c, flags.Args()[1:]))
+	}
+
+	fmt.Fprintf(c.stderr, "zcelero: unknown command %q\n", flags.Arg(0))
+	flags.Usage()
+	return exitUsage
+}
+
+// report prints the error, including the invalid fields returned by the server, and picks the exit code
+func (c *cli) report(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		return exitUsage
+	}
+
+	var appError *apperror.Error
+	if errors.As(err, &appError) {
+		fmt.Fprintf(c.stderr, "zcelero: %s (%s)\n", appError.Message, appError.Code)
+		for _, field := range appError.Fields {
+			fmt.Fprintf(c.stderr, "  %s: %s\n", field.Field, field.Message)
+		}
+		return exitError
+	}
+
+	fmt.Fprintf(c.stderr, "zcelero: %s\n", err.Error())
+	return exitError
+}
+
+// newFlagSet creates the flags of a command, printing its usage on errors
+func (c *cli) newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: zcelero %s [flags] %s\n\nflags:\n", name, arguments)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func newClient(server string, timeout time.Duration) (client.ClientInterface, error) {
+	return client.NewClient(server, client.WithTimeout(timeout))
+}
+
+// promptTerminal asks for a secret without echoing it
+func promptTerminal(label string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("%s is required and stdin is not a terminal to prompt for it", label)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+func envOr(getenv func(key string) string, key, fallback string) string {
+	if value := getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/audit"
+	"zcelero/client"
+	"zcelero/entity"
+	"zcelero/envelope"
+	mockclient "zcelero/mocks/client"
+	"zcelero/textcrypto"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type testCli struct {
+	*cli
+	client *mockclient.ClientInterface
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+	env    map[string]string
+}
+
+func newTestCli(stdin string) *testCli {
+	t := &testCli{
+		client: &mockclient.ClientInterface{},
+		stdout: &bytes.Buffer{},
+		stderr: &bytes.Buffer{},
+		env:    map[string]string{},
+	}
+	t.cli = &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: t.stdout,
+		stderr: t.stderr,
+		getenv: func(key string) string { return t.env[key] },
+		prompt: func(label string) (string, error) { return "", errors.New("no terminal") },
+		newClient: func(server string, timeout time.Duration) (client.ClientInterface, error) {
+			return t.client, nil
+		},
+	}
+
+	return t
+}
+
+func TestPut(t *testing.T) {
+	dir := t.TempDir()
+	encrypted := true
+	unencrypted := false
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+
+	tests := []struct {
+		name         string
+		args         []string
+		stdin        string
+		env          map[string]string
+		mockBehavior func(c *mockclient.ClientInterface)
+		wantCode     int
+		wantKeyFile  string
+	}{
+		{
+			name:  "Put text from stdin",
+			args:  []string{"put"},
+			stdin: "text data",
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("Insert", mock.Anything, entity.TextManagement{TextData: "text data", Encryption: &unencrypted}).Return(entity.TextManagement{Uuid: uuid}, nil)
+			},
+			wantCode: exitOK,
+		},
+		{
+			name:  "Put encrypted text with password from env",
+			args:  []string{"put", "-encrypt", "-key-size", "1024", "-key-out", filepath.Join(dir, "key.pem")},
+			stdin: "text data",
+			env:   map[string]string{"ZCELERO_PASSWORD": "password123"},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("Insert", mock.Anything, entity.TextManagement{
+					TextData:           "text data",
+					Encryption:         &encrypted,
+					KeySize:            1024,
+					PrivateKeyPassword: "password123",
+				}).Return(entity.TextManagement{Uuid: uuid, PrivateKey: "private key"}, nil)
+			},
+			wantCode:    exitOK,
+			wantKeyFile: filepath.Join(dir, "key.pem"),
+		},
+		{
+			name:  "Put passphrase text",
+			args:  []string{"put", "-passphrase"},
+			stdin: "text data",
+			env:   map[string]string{"ZCELERO_PASSWORD": "password123"},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("Insert", mock.Anything, entity.TextManagement{
+					TextData:           "text data",
+					Encryption:         &encrypted,
+					EncryptionMode:     entity.EncryptionModePassphrase,
+					PrivateKeyPassword: "password123",
+				}).Return(entity.TextManagement{Uuid: uuid}, nil)
+			},
+			wantCode: exitOK,
+		},
+		{
+			name:     "Put with both encryptions",
+			args:     []string{"put", "-encrypt", "-passphrase"},
+			stdin:    "text data",
+			env:      map[string]string{"ZCELERO_PASSWORD": "password123"},
+			wantCode: exitError,
+		},
+		{
+			name:     "Put encrypted text without password",
+			args:     []string{"put", "-encrypt"},
+			stdin:    "text data",
+			wantCode: exitError,
+		},
+		{
+			name:     "Put encrypted text over an existing key file",
+			args:     []string{"put", "-encrypt", "-key-out", filepath.Join(dir, "key.pem")},
+			stdin:    "text data",
+			env:      map[string]string{"ZCELERO_PASSWORD": "password123"},
+			wantCode: exitError,
+		},
+		{
+			name:  "Put invalid text",
+			args:  []string{"put"},
+			stdin: " ",
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("Insert", mock.Anything, mock.Anything).Return(entity.TextManagement{}, apperror.NewValidation([]apperror.FieldError{
+					{Field: "text_data", Code: "notblank", Message: "must not be blank"},
+				}))
+			},
+			wantCode: exitError,
+		},
+		{
+			name:     "Put with too many files",
+			args:     []string{"put", "a", "b"},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCli(tt.stdin)
+			if tt.env != nil {
+				c.env = tt.env
+			}
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(c.client)
+			}
+
+			if got := c.run(tt.args); got != tt.wantCode {
+				t.Fatalf("cli.run() = %d, want %d, stderr %s", got, tt.wantCode, c.stderr.String())
+			}
+			if tt.wantCode == exitOK && strings.TrimSpace(c.stdout.String()) != uuid {
+				t.Errorf("cli.run() stdout = %q, want %q", c.stdout.String(), uuid)
+			}
+			if tt.wantKeyFile != "" {
+				info, err := os.Stat(tt.wantKeyFile)
+				if err != nil || info.Mode().Perm() != 0600 {
+					t.Errorf("private key file = %v, %v, want mode 0600", info, err)
+				}
+			}
+			c.client.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	passwordFile := filepath.Join(dir, "password")
+	os.WriteFile(keyFile, []byte("private key"), 0600)
+	os.WriteFile(passwordFile, []byte("password123\n"), 0600)
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		mockBehavior func(c *mockclient.ClientInterface)
+		wantCode     int
+		wantStdout   string
+	}{
+		{
+			name: "Get unencrypted text",
+			args: []string{"get", uuid},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid}, nil)
+				c.On("Get", mock.Anything, uuid, "", "").Return("text data", nil)
+			},
+			wantCode:   exitOK,
+			wantStdout: "text data",
+		},
+		{
+			name: "Get encrypted text with key and password files",
+			args: []string{"get", "-key", keyFile, "-password-file", passwordFile, uuid},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true}, nil)
+				c.On("Get", mock.Anything, uuid, "private key", "password123").Return("text data", nil)
+			},
+			wantCode:   exitOK,
+			wantStdout: "text data",
+		},
+		{
+			name: "Get encrypted text with key and password from env",
+			args: []string{"get", uuid},
+			env:  map[string]string{"ZCELERO_PRIVATE_KEY": "env key", "ZCELERO_PASSWORD": "env password"},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true}, nil)
+				c.On("Get", mock.Anything, uuid, "env key", "env password").Return("text data", nil)
+			},
+			wantCode:   exitOK,
+			wantStdout: "text data",
+		},
+		{
+			name: "Get passphrase text with password file",
+			args: []string{"get", "-password-file", passwordFile, uuid},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true, EncryptionMode: entity.EncryptionModePassphrase}, nil)
+				c.On("Get", mock.Anything, uuid, "", "password123").Return("text data", nil)
+			},
+			wantCode:   exitOK,
+			wantStdout: "text data",
+		},
+		{
+			name: "Get encrypted text without key",
+			args: []string{"get", uuid},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true}, nil)
+			},
+			wantCode: exitError,
+		},
+		{
+			name: "Get missing text",
+			args: []string{"get", uuid},
+			mockBehavior: func(c *mockclient.ClientInterface) {
+				c.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{}, apperror.New(apperror.NotFound, "text not found"))
+			},
+			wantCode: exitError,
+		},
+		{
+			name:     "Get without id",
+			args:     []string{"get"},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCli("")
+			if tt.env != nil {
+				c.env = tt.env
+			}
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(c.client)
+			}
+
+			if got := c.run(tt.args); got != tt.wantCode {
+				t.Fatalf("cli.run() = %d, want %d, stderr %s", got, tt.wantCode, c.stderr.String())
+			}
+			if c.stdout.String() != tt.wantStdout {
+				t.Errorf("cli.run() stdout = %q, want %q", c.stdout.String(), tt.wantStdout)
+			}
+			c.client.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMetaAndRm(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	os.WriteFile(keyFile, []byte("private key"), 0600)
+
+	c := newTestCli("")
+	c.env["ZCELERO_PASSWORD"] = "password123"
+	c.client.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true, KeySize: 2048}, nil)
+	c.client.On("Delete", mock.Anything, uuid, "private key", "password123").Return(nil)
+
+	if got := c.run([]string{"meta", uuid}); got != exitOK {
+		t.Fatalf("cli.run(meta) = %d, stderr %s", got, c.stderr.String())
+	}
+	if !strings.Contains(c.stdout.String(), `"key_size": 2048`) {
+		t.Errorf("cli.run(meta) stdout = %s", c.stdout.String())
+	}
+	if got := c.run([]string{"rm", "-key", keyFile, uuid}); got != exitOK {
+		t.Fatalf("cli.run(rm) = %d, stderr %s", got, c.stderr.String())
+	}
+	c.client.AssertExpectations(t)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	for _, args := range [][]string{{}, {"list"}, {"-unknown"}} {
+		c := newTestCli("")
+		if got := c.run(args); got != exitUsage {
+			t.Errorf("cli.run(%v) = %d, want %d", args, got, exitUsage)
+		}
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	publicKey, privateKey, _ := textcrypto.GeneratePairKey(rand.Reader, rsaKey, "password123")
+	ciphertext, _ := textcrypto.EncryptMessage(rand.Reader, publicKey, "encrypted text data")
+	writeFile := func(name string, fileData any) string {
+		path := filepath.Join(dir, name)
+		data, _ := json.Marshal(fileData)
+		os.WriteFile(path, data, 0600)
+		return path
+	}
+	encryptedFile := writeFile("encrypted.json", textcrypto.FileContent{Content: base64.StdEncoding.EncodeToString(ciphertext), Encrypted: true})
+	unencryptedFile := writeFile("unencrypted.json", textcrypto.FileContent{Content: "text data"})
+	content, kdf, _ := textcrypto.SealWithPassphrase(rand.Reader, "password123", "passphrase text data")
+	passphraseFile := writeFile("passphrase.json", textcrypto.FileContent{Content: content, Encrypted: true, Mode: textcrypto.ModePassphrase, KDF: kdf})
+	invalidFile := writeFile("invalid.json", "not a stored text")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(keyFile, []byte(privateKey), 0600)
+	masterKeys := "2024-01:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	keyring, _ := envelope.NewKeyring(map[string][]byte{"2024-01": bytes.Repeat([]byte{7}, 32)}, "2024-01")
+	plain, _ := json.Marshal(textcrypto.FileContent{Content: "sealed text data"})
+	sealed, _ := envelope.Seal(keyring, "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", plain)
+	sealedFile := filepath.Join(dir, "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec.json")
+	os.WriteFile(sealedFile, sealed, 0600)
+	masterKeysFile := filepath.Join(dir, "master-keys")
+	os.WriteFile(masterKeysFile, []byte(masterKeys+"\n"), 0600)
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantCode   int
+		wantStdout string
+	}{
+		{
+			name:       "Decrypt unencrypted file",
+			args:       []string{"decrypt", unencryptedFile},
+			wantCode:   exitOK,
+			wantStdout: "text data",
+		},
+		{
+			name:       "Decrypt encrypted file",
+			args:       []string{"decrypt", "-key", keyFile, encryptedFile},
+			env:        map[string]string{"ZCELERO_PASSWORD": "password123"},
+			wantCode:   exitOK,
+			wantStdout: "encrypted text data",
+		},
+		{
+			name:       "Decrypt passphrase file without private key",
+			args:       []string{"decrypt", passphraseFile},
+			env:        map[string]string{"ZCELERO_PASSWORD": "password123"},
+			wantCode:   exitOK,
+			wantStdout: "passphrase text data",
+		},
+		{
+			name:     "Decrypt with wrong password",
+			args:     []string{"decrypt", "-key", keyFile, encryptedFile},
+			env:      map[string]string{"ZCELERO_PASSWORD": "password456"},
+			wantCode: exitError,
+		},
+		{
+			name:     "Decrypt without private key",
+			args:     []string{"decrypt", encryptedFile},
+			env:      map[string]string{"ZCELERO_PASSWORD": "password123"},
+			wantCode: exitError,
+		},
+		{
+			name:       "Decrypt file encrypted at rest",
+			args:       []string{"decrypt", "-master-keys", masterKeysFile, sealedFile},
+			wantCode:   exitOK,
+			wantStdout: "sealed text data",
+		},
+		{
+			name:       "Decrypt file encrypted at rest with keys from environment",
+			args:       []string{"decrypt", sealedFile},
+			env:        map[string]string{"AT_REST_KEYS": masterKeys},
+			wantCode:   exitOK,
+			wantStdout: "sealed text data",
+		},
+		{
+			name:     "Decrypt file encrypted at rest without master keys",
+			args:     []string{"decrypt", sealedFile},
+			wantCode: exitError,
+		},
+		{
+			name:     "Decrypt invalid file",
+			args:     []string{"decrypt", invalidFile},
+			wantCode: exitError,
+		},
+		{
+			name:     "Decrypt missing file",
+			args:     []string{"decrypt", filepath.Join(dir, "missing.json")},
+			wantCode: exitError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCli("")
+			if tt.env != nil {
+				c.env = tt.env
+			}
+			c.newClient = func(server string, timeout time.Duration) (client.ClientInterface, error) {
+				t.Fatal("decrypt must not create a client")
+				return nil, nil
+			}
+
+			if got := c.run(tt.args); got != tt.wantCode {
+				t.Fatalf("cli.run() = %d, want %d, stderr %s", got, tt.wantCode, c.stderr.String())
+			}
+			if c.stdout.String() != tt.wantStdout {
+				t.Errorf("cli.run() stdout = %q, want %q", c.stdout.String(), tt.wantStdout)
+			}
+		})
+	}
+}
+
+var auditKey = bytes.Repeat([]byte{7}, 32)
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "audit.log")
+	edited := filepath.Join(dir, "edited.log")
+	lines := []string{}
+	previous := entity.AuditEntry{}
+	for _, action := range []string{entity.AuditActionInsert, entity.AuditActionRead} {
+		previous = audit.Chain(auditKey, previous, entity.AuditEntry{Action: action, Outcome: entity.AuditOutcomeSuccess, Principal: "CN=alice"})
+		line, _ := json.Marshal(previous)
+		lines = append(lines, string(line))
+	}
+	os.WriteFile(log, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	keyFile := filepath.Join(dir, "audit-key")
+	os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(auditKey)), 0600)
+	os.WriteFile(edited, []byte(strings.Replace(strings.Join(lines, "\n"), "CN=alice", "CN=mallory", 1)), 0600)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "Verify intact log",
+			args:       []string{"audit", "-key-file", keyFile, log},
+			wantCode:   exitOK,
+			wantStdout: "2 entries verified, last hash " + previous.Hash + "\n",
+		},
+		{
+			name:       "Verify edited log",
+			args:       []string{"audit", "-key-file", keyFile, edited},
+			wantCode:   exitError,
+			wantStderr: "entry 1 does not match its hash",
+		},
+		{
+			name:       "Verify without the key",
+			args:       []string{"audit", log},
+			wantCode:   exitError,
+			wantStderr: "entry 1 does not match its hash",
+		},
+		{
+			name:     "Verify missing log",
+			args:     []string{"audit", filepath.Join(dir, "missing.log")},
+			wantCode: exitError,
+		},
+		{
+			name:     "Verify without file",
+			args:     []string{"audit"},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCli("")
+			c.newClient = func(server string, timeout time.Duration) (client.ClientInterface, error) {
+				t.Fatal("audit must not create a client")
+				return nil, nil
+			}
+
+			if got := c.run(tt.args); got != tt.wantCode {
+				t.Fatalf("cli.run() = %d, want %d, stderr %s", got, tt.wantCode, c.stderr.String())
+			}
+			if c.stdout.String() != tt.wantStdout {
+				t.Errorf("cli.run() stdout = %q, want %q", c.stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(c.stderr.String(), tt.wantStderr) {
+				t.Errorf("cli.run() stderr = %q, want %q", c.stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
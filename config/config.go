@@ -0,0 +1,501 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the application, see Load for where each one comes from
+type Config struct {
+	Port        int         `yaml:"port"`
+	GrpcPort    int         `yaml:"grpc_port"`
+	LogLevel    string      `yaml:"log_level"`
+	GinMode     string      `yaml:"gin_mode"`
+	StoragePath string      `yaml:"storage_path"`
+	KeySizes    []uint64    `yaml:"key_sizes"`
+	KeyPool     KeyPool     `yaml:"key_pool"`
+	AsyncInsert AsyncInsert `yaml:"async_insert"`
+	Passphrase  Passphrase  `yaml:"passphrase"`
+	Timeouts    Timeouts    `yaml:"timeouts"`
+	TLS         TLS         `yaml:"tls"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Webhooks    Webhooks    `yaml:"webhooks"`
+	AtRest      AtRest      `yaml:"at_rest"`
+	Audit       Audit       `yaml:"audit"`
+	// AdminPrincipals are the client certificate subjects allowed to use the admin endpoints
+	AdminPrincipals []string `yaml:"admin_principals"`
+	// TrustedProxies are the addresses and CIDR ranges of the proxies whose X-Forwarded-For header gives the
+	// client address, with none the client address is always the one of the connection
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// KeyPool configures the pre-generation of RSA keys
+type KeyPool struct {
+	Size    int `yaml:"size"`
+	Workers int `yaml:"workers"`
+}
+
+// AsyncInsert configures the queue of POST /v1/text-management?async=true, zero workers disable it. Finished
+// jobs are removed once they haven't changed for JobTTL, along with a private key that was never read
+type AsyncInsert struct {
+	Workers   int           `yaml:"workers"`
+	QueueSize int           `yaml:"queue_size"`
+	JobTTL    time.Duration `yaml:"job_ttl"`
+}
+
+// Passphrase limits the passphrase texts encrypted or decrypted at the same time to KDFConcurrency, each
+// Argon2id derivation takes 64 MiB and 4 threads. The requests over the limit answer 503 instead of waiting
+type Passphrase struct {
+	KDFConcurrency int `yaml:"kdf_concurrency"`
+}
+
+// Timeouts limit the HTTP connections, zero disables the limit, and how long the shutdown waits for
+// requests and background jobs to finish
+type Timeouts struct {
+	Read       time.Duration `yaml:"read"`
+	ReadHeader time.Duration `yaml:"read_header"`
+	Write      time.Duration `yaml:"write"`
+	Idle       time.Duration `yaml:"idle"`
+	Shutdown   time.Duration `yaml:"shutdown"`
+}
+
+// TLS enables HTTPS and gRPC over TLS when the certificate and key are set, the files are reloaded when
+// they change. ClientAuth is none, optional or require, verifying client certificates against ClientCAFile.
+// RedirectPort serves plain HTTP redirecting to HTTPS, zero disables it
+type TLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	ClientAuth   string `yaml:"client_auth"`
+	RedirectPort int    `yaml:"redirect_port"`
+}
+
+// Tracing exports the OpenTelemetry spans to an OTLP gRPC collector, an empty Endpoint disables the export.
+// SampleRatio is the fraction of new traces recorded, requests carrying a sampled parent are always recorded
+type Tracing struct {
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Webhooks configures the delivery of the webhook events. A failed delivery is retried up to MaxAttempts
+// attempts, waiting Backoff after the first failure and doubling it after each one. Timeout limits each attempt
+type Webhooks struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// AtRest encrypts every stored text with its own data key, wrapped by the master key KeyId. The master keys
+// are read from KeysFile, or from $AT_REST_KEYS when it's empty, so they are never part of the configuration.
+// An empty KeyId disables the encryption. RotationRate limits the texts rewrapped per second when the master
+// key is rotated, zero removes the limit
+type AtRest struct {
+	KeysFile     string `yaml:"keys_file"`
+	KeyId        string `yaml:"key_id"`
+	RotationRate int    `yaml:"rotation_rate"`
+	PKCS11       PKCS11 `yaml:"pkcs11"`
+}
+
+// PKCS11 keeps the master keys in the token TokenLabel of a PKCS#11 module, like an HSM, instead of the keys
+// file: each key id is the label of an AES key in the token, and the PIN is read from $AT_REST_PKCS11_PIN. An
+// empty Module disables it. Without DataKeys only the master keys stay in the token, it unwraps the data key of
+// each text into the process memory, where the text is encrypted and decrypted. With DataKeys the data keys
+// are generated in the token and the texts are encrypted by it too, so they never leave it unwrapped
+type PKCS11 struct {
+	Module     string `yaml:"module"`
+	TokenLabel string `yaml:"token_label"`
+	DataKeys   bool   `yaml:"data_keys"`
+}
+
+// Audit keys the hash chain of the audit log with the HMAC key read from KeyFile, or from $AUDIT_KEY when it's
+// empty, so it's never part of the configuration. Without a key the chain is plain SHA-256, which anyone able
+// to write the log can recompute after editing it
+type Audit struct {
+	KeyFile string `yaml:"key_file"`
+}
+
+// Enabled tells whether the APIs are served over TLS
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+var logLevels = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+	"fatal": zerolog.FatalLevel,
+	"panic": zerolog.PanicLevel,
+}
+
+var ginModes = []string{"debug", "release", "test"}
+
+var clientAuths = []string{"none", "optional", "require"}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		Port:        8080,
+		GrpcPort:    9090,
+		LogLevel:    "info",
+		GinMode:     "release",
+		StoragePath: "storage",
+		KeySizes:    []uint64{1024, 2048, 4096},
+		KeyPool:     KeyPool{Size: 5, Workers: 2},
+		AsyncInsert: AsyncInsert{Workers: 2, QueueSize: 100, JobTTL: time.Hour},
+		Passphrase:  Passphrase{KDFConcurrency: 4},
+		Timeouts: Timeouts{
+			Read:       30 * time.Second,
+			ReadHeader: 10 * time.Second,
+			Write:      60 * time.Second,
+			Idle:       120 * time.Second,
+			Shutdown:   30 * time.Second,
+		},
+		TLS:      TLS{ClientAuth: "none"},
+		Tracing:  Tracing{SampleRatio: 1},
+		Webhooks: Webhooks{MaxAttempts: 8, Backoff: 30 * time.Second, Timeout: 10 * time.Second},
+		AtRest:   AtRest{RotationRate: 100},
+	}
+}
+
+// setting is a value that can be overridden by an environment variable and a command-line flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{flag: "port", env: "PORT", usage: "HTTP port", set: setInt(func(c *Config) *int { return &c.Port })},
+	{flag: "grpc-port", env: "GRPC_PORT", usage: "gRPC port", set: setInt(func(c *Config) *int { return &c.GrpcPort })},
+	{flag: "log-level", env: "INFO_LEVEL", usage: "log level: debug, info, warn, error, fatal or panic", set: setString(func(c *Config) *string { return &c.LogLevel })},
+	{flag: "gin-mode", env: "GIN_MODE", usage: "Gin mode: debug, release or test", set: setString(func(c *Config) *string { return &c.GinMode })},
+	{flag: "storage-path", env: "STORAGE_PATH", usage: "folder where texts and jobs are stored", set: setString(func(c *Config) *string { return &c.StoragePath })},
+	{flag: "key-sizes", env: "KEY_SIZES", usage: "comma separated RSA key sizes accepted by the API", set: setKeySizes},
+	{flag: "key-pool-size", env: "KEY_POOL_SIZE", usage: "keys kept ready for each key size, 0 disables the pool", set: setInt(func(c *Config) *int { return &c.KeyPool.Size })},
+	{flag: "key-pool-workers", env: "KEY_POOL_WORKERS", usage: "goroutines refilling the key pool", set: setInt(func(c *Config) *int { return &c.KeyPool.Workers })},
+	{flag: "async-insert-workers", env: "ASYNC_INSERT_WORKERS", usage: "asynchronous inserts running at the same time, 0 disables them", set: setInt(func(c *Config) *int { return &c.AsyncInsert.Workers })},
+	{flag: "async-insert-queue-size", env: "ASYNC_INSERT_QUEUE_SIZE", usage: "asynchronous inserts waiting in the queue", set: setInt(func(c *Config) *int { return &c.AsyncInsert.QueueSize })},
+	{flag: "async-insert-job-ttl", env: "ASYNC_INSERT_JOB_TTL", usage: "time finished asynchronous inserts are kept", set: setDuration(func(c *Config) *time.Duration { return &c.AsyncInsert.JobTTL })},
+	{flag: "passphrase-kdf-concurrency", env: "PASSPHRASE_KDF_CONCURRENCY", usage: "passphrase texts encrypted or decrypted at the same time", set: setInt(func(c *Config) *int { return &c.Passphrase.KDFConcurrency })},
+	{flag: "read-timeout", env: "HTTP_READ_TIMEOUT", usage: "time to read a whole HTTP request", set: setDuration(func(c *Config) *time.Duration { return &c.Timeouts.Read })},
+	{flag: "read-header-timeout", env: "HTTP_READ_HEADER_TIMEOUT", usage: "time to read the HTTP request headers", set: setDuration(func(c *Config) *time.Duration { return &c.Timeouts.ReadHeader })},
+	{flag: "write-timeout", env: "HTTP_WRITE_TIMEOUT", usage: "time to handle a request and write its response", set: setDuration(func(c *Config) *time.Duration { return &c.Timeouts.Write })},
+	{flag: "idle-timeout", env: "HTTP_IDLE_TIMEOUT", usage: "time an idle keep-alive connection is kept open", set: setDuration(func(c *Config) *time.Duration { return &c.Timeouts.Idle })},
+	{flag: "tls-cert-file", env: "TLS_CERT_FILE", usage: "PEM certificate served by both APIs, enables TLS", set: setString(func(c *Config) *string { return &c.TLS.CertFile })},
+	{flag: "tls-key-file", env: "TLS_KEY_FILE", usage: "PEM private key of the certificate", set: setString(func(c *Config) *string { return &c.TLS.KeyFile })},
+	{flag: "tls-client-ca-file", env: "TLS_CLIENT_CA_FILE", usage: "PEM certificates of the CAs signing client certificates", set: setString(func(c *Config) *string { return &c.TLS.ClientCAFile })},
+	{flag: "tls-client-auth", env: "TLS_CLIENT_AUTH", usage: "client certificates: none, optional or require", set: setString(func(c *Config) *string { return &c.TLS.ClientAuth })},
+	{flag: "tls-redirect-port", env: "TLS_REDIRECT_PORT", usage: "plain HTTP port redirecting to HTTPS, 0 disables it", set: setInt(func(c *Config) *int { return &c.TLS.RedirectPort })},
+	{flag: "tracing-endpoint", env: "TRACING_ENDPOINT", usage: "OTLP gRPC collector address receiving the traces, empty disables them", set: setString(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{flag: "tracing-insecure", env: "TRACING_INSECURE", usage: "send the traces without TLS", set: setBool(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{flag: "tracing-sample-ratio", env: "TRACING_SAMPLE_RATIO", usage: "fraction of new traces recorded, between 0 and 1", set: setFloat(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{flag: "webhook-max-attempts", env: "WEBHOOK_MAX_ATTEMPTS", usage: "delivery attempts of each webhook event", set: setInt(func(c *Config) *int { return &c.Webhooks.MaxAttempts })},
+	{flag: "webhook-backoff", env: "WEBHOOK_BACKOFF", usage: "wait after the first failed webhook delivery, doubled after each one", set: setDuration(func(c *Config) *time.Duration { return &c.Webhooks.Backoff })},
+	{flag: "webhook-timeout", env: "WEBHOOK_TIMEOUT", usage: "time limit of each webhook delivery attempt", set: setDuration(func(c *Config) *time.Duration { return &c.Webhooks.Timeout })},
+	{flag: "at-rest-keys-file", env: "AT_REST_KEYS_FILE", usage: "file with the master keys encrypting the stored texts, defaults to $AT_REST_KEYS", set: setString(func(c *Config) *string { return &c.AtRest.KeysFile })},
+	{flag: "at-rest-key-id", env: "AT_REST_KEY_ID", usage: "master key encrypting new texts, empty disables the at-rest encryption", set: setString(func(c *Config) *string { return &c.AtRest.KeyId })},
+	{flag: "at-rest-rotation-rate", env: "AT_REST_ROTATION_RATE", usage: "texts rewrapped per second by a master key rotation, 0 removes the limit", set: setInt(func(c *Config) *int { return &c.AtRest.RotationRate })},
+	{flag: "at-rest-pkcs11-module", env: "AT_REST_PKCS11_MODULE", usage: "PKCS#11 library holding the master keys instead of the keys file, e.g. libsofthsm2.so", set: setString(func(c *Config) *string { return &c.AtRest.PKCS11.Module })},
+	{flag: "at-rest-pkcs11-token-label", env: "AT_REST_PKCS11_TOKEN_LABEL", usage: "label of the PKCS#11 token holding the master keys", set: setString(func(c *Config) *string { return &c.AtRest.PKCS11.TokenLabel })},
+	{flag: "at-rest-pkcs11-data-keys", env: "AT_REST_PKCS11_DATA_KEYS", usage: "generate the data keys in the PKCS#11 token and encrypt the texts in it", set: setBool(func(c *Config) *bool { return &c.AtRest.PKCS11.DataKeys })},
+	{flag: "audit-key-file", env: "AUDIT_KEY_FILE", usage: "file with the base64 HMAC key of the audit log chain, defaults to $AUDIT_KEY", set: setString(func(c *Config) *string { return &c.Audit.KeyFile })},
+	{flag: "admin-principals", env: "ADMIN_PRINCIPALS", usage: "semicolon separated client certificate subjects allowed to use the admin endpoints", set: setAdminPrincipals},
+	{flag: "trusted-proxies", env: "TRUSTED_PROXIES", usage: "comma separated addresses and CIDR ranges of the proxies allowed to set X-Forwarded-For", set: setTrustedProxies},
+	{flag: "shutdown-timeout", env: "SHUTDOWN_TIMEOUT", usage: "time to drain requests and jobs when stopping", set: setDuration(func(c *Config) *time.Duration { return &c.Timeouts.Shutdown })},
+}
+
+// Load builds the configuration from the defaults, the YAML file given by -config or $ZCELERO_CONFIG,
+// the environment variables and the command-line flags, each one overriding the previous, and validates it
+func Load(args []string, getenv func(key string) string, output io.Writer) (Config, error) {
+	flags := flag.NewFlagSet("zcelero", flag.ContinueOnError)
+	flags.SetOutput(output)
+	path := flags.String("config", "", "YAML configuration file, defaults to $ZCELERO_CONFIG")
+	values := map[string]string{}
+	for _, s := range settings {
+		s := s
+		flags.Func(s.flag, fmt.Sprintf("%s, overrides $%s", s.usage, s.env), func(value string) error {
+			// parsed right away so a wrong value is reported as a flag error
+			if err := s.set(&Config{}, value); err != nil {
+				return err
+			}
+			values[s.flag] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if flags.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	config := Default()
+	if *path == "" {
+		*path = getenv("ZCELERO_CONFIG")
+	}
+	if *path != "" {
+		if err := config.loadFile(*path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := s.set(&config, value); err != nil {
+				return Config{}, fmt.Errorf("invalid %s environment variable: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := values[s.flag]; ok {
+			s.set(&config, value)
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// loadFile overrides the settings present in the YAML file, unknown keys are rejected to catch typos
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("configuration file could not be read: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("configuration file %s is not valid: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every setting with a value the application can't run with
+func (c Config) Validate() error {
+	problems := []string{}
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, "port must be between 1 and 65535")
+	}
+	if c.GrpcPort < 1 || c.GrpcPort > 65535 {
+		problems = append(problems, "grpc_port must be between 1 and 65535")
+	}
+	if c.Port == c.GrpcPort {
+		problems = append(problems, "port and grpc_port must be different")
+	}
+	if _, ok := logLevels[c.LogLevel]; !ok {
+		problems = append(problems, fmt.Sprintf("log_level %q is not supported", c.LogLevel))
+	}
+	if !contains(ginModes, c.GinMode) {
+		problems = append(problems, fmt.Sprintf("gin_mode must be one of %s", strings.Join(ginModes, ", ")))
+	}
+	if strings.TrimSpace(c.StoragePath) == "" {
+		problems = append(problems, "storage_path is required")
+	}
+	if len(c.KeySizes) == 0 {
+		problems = append(problems, "key_sizes must have at least one size")
+	}
+	for _, size := range c.KeySizes {
+		if size < 1024 || size > 16384 {
+			problems = append(problems, fmt.Sprintf("key size %d must be between 1024 and 16384", size))
+		}
+	}
+	if c.KeyPool.Size < 0 || c.KeyPool.Workers < 0 {
+		problems = append(problems, "key_pool size and workers must not be negative")
+	}
+	if c.AsyncInsert.Workers < 0 || c.AsyncInsert.QueueSize < 0 {
+		problems = append(problems, "async_insert workers and queue_size must not be negative")
+	}
+	if c.AsyncInsert.JobTTL <= 0 {
+		problems = append(problems, "async_insert job_ttl must be positive")
+	}
+	if c.Passphrase.KDFConcurrency < 1 {
+		problems = append(problems, "passphrase kdf_concurrency must be positive")
+	}
+
+	if c.Timeouts.Read < 0 || c.Timeouts.ReadHeader < 0 || c.Timeouts.Write < 0 || c.Timeouts.Idle < 0 {
+		problems = append(problems, "timeouts must not be negative")
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		problems = append(problems, "timeouts.shutdown must be positive")
+	}
+	problems = append(problems, c.TLS.validate(c.Port, c.GrpcPort)...)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing sample_ratio must be between 0 and 1")
+	}
+	if c.Webhooks.MaxAttempts < 1 || c.Webhooks.Backoff <= 0 || c.Webhooks.Timeout <= 0 {
+		problems = append(problems, "webhooks max_attempts, backoff and timeout must be positive")
+	}
+	if c.AtRest.KeysFile != "" && c.AtRest.KeyId == "" {
+		problems = append(problems, "at_rest keys_file needs key_id")
+	}
+	if c.AtRest.RotationRate < 0 {
+		problems = append(problems, "at_rest rotation_rate must not be negative")
+	}
+	if c.AtRest.PKCS11.Module != "" && (c.AtRest.KeyId == "" || c.AtRest.PKCS11.TokenLabel == "") {
+		problems = append(problems, "at_rest pkcs11 module needs key_id and token_label")
+	}
+	if c.AtRest.PKCS11.Module != "" && c.AtRest.KeysFile != "" {
+		problems = append(problems, "at_rest keys_file and pkcs11 module can't be used together")
+	}
+	if c.AtRest.PKCS11.DataKeys && c.AtRest.PKCS11.Module == "" {
+		problems = append(problems, "at_rest pkcs11 data_keys needs module")
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems = append(problems, fmt.Sprintf("trusted proxy %q is not an address or a CIDR range", proxy))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (t TLS) validate(port, grpcPort int) []string {
+	problems := []string{}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		problems = append(problems, "tls cert_file and key_file must be set together")
+	}
+	if !contains(clientAuths, t.ClientAuth) {
+		problems = append(problems, fmt.Sprintf("tls client_auth must be one of %s", strings.Join(clientAuths, ", ")))
+	}
+	if t.ClientAuth != "none" && (t.ClientCAFile == "" || !t.Enabled()) {
+		problems = append(problems, "tls client_auth needs client_ca_file and cert_file")
+	}
+	if t.RedirectPort != 0 {
+		if !t.Enabled() {
+			problems = append(problems, "tls redirect_port needs cert_file")
+		}
+		if t.RedirectPort < 0 || t.RedirectPort > 65535 || t.RedirectPort == port || t.RedirectPort == grpcPort {
+			problems = append(problems, "tls redirect_port must be a free port between 1 and 65535")
+		}
+	}
+
+	return problems
+}
+
+// ZerologLevel returns the level of the log_level setting
+func (c Config) ZerologLevel() zerolog.Level {
+	return logLevels[c.LogLevel]
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+
+		*field(c) = number
+		return nil
+	}
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+
+		*field(c) = enabled
+		return nil
+	}
+}
+
+func setFloat(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+
+		*field(c) = number
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 30s", value)
+		}
+
+		*field(c) = duration
+		return nil
+	}
+}
+
+func setKeySizes(c *Config, value string) error {
+	sizes := []uint64{}
+	for _, size := range strings.Split(value, ",") {
+		number, err := strconv.ParseUint(strings.TrimSpace(size), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a list of numbers", value)
+		}
+		sizes = append(sizes, number)
+	}
+
+	c.KeySizes = sizes
+	return nil
+}
+
+// setAdminPrincipals splits the subjects on semicolons, since the subjects themselves have commas
+func setAdminPrincipals(c *Config, value string) error {
+	principals := []string{}
+	for _, principal := range strings.Split(value, ";") {
+		if principal = strings.TrimSpace(principal); principal != "" {
+			principals = append(principals, principal)
+		}
+	}
+
+	c.AdminPrincipals = principals
+	return nil
+}
+
+func setTrustedProxies(c *Config, value string) error {
+	proxies := []string{}
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	c.TrustedProxies = proxies
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
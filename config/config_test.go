@@ -0,0 +1,330 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configFile, []byte("port: 8081\nstorage_path: /data\nkey_pool:\n  size: 10\ntimeouts:\n  write: 2m\n"), 0600)
+	emptyFile := filepath.Join(dir, "empty.yaml")
+	os.WriteFile(emptyFile, nil, 0600)
+	unknownFile := filepath.Join(dir, "unknown.yaml")
+	os.WriteFile(unknownFile, []byte("prot: 8081\n"), 0600)
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    func(c *Config)
+		wantErr string
+	}{
+		{
+			name: "Defaults",
+			want: func(c *Config) {},
+		},
+		{
+			name: "File overrides defaults",
+			args: []string{"-config", configFile},
+			want: func(c *Config) {
+				c.Port = 8081
+				c.StoragePath = "/data"
+				c.KeyPool.Size = 10
+				c.Timeouts.Write = 2 * time.Minute
+			},
+		},
+		{
+			name: "File from environment",
+			env:  map[string]string{"ZCELERO_CONFIG": configFile},
+			want: func(c *Config) {
+				c.Port = 8081
+				c.StoragePath = "/data"
+				c.KeyPool.Size = 10
+				c.Timeouts.Write = 2 * time.Minute
+			},
+		},
+		{
+			name: "Empty file",
+			args: []string{"-config", emptyFile},
+			want: func(c *Config) {},
+		},
+		{
+			name: "Environment overrides file",
+			args: []string{"-config", configFile},
+			env:  map[string]string{"PORT": "8082", "KEY_SIZES": "2048, 4096", "INFO_LEVEL": "debug"},
+			want: func(c *Config) {
+				c.Port = 8082
+				c.StoragePath = "/data"
+				c.KeyPool.Size = 10
+				c.Timeouts.Write = 2 * time.Minute
+				c.KeySizes = []uint64{2048, 4096}
+				c.LogLevel = "debug"
+			},
+		},
+		{
+			name: "Flags override environment",
+			args: []string{"-config", configFile, "-port", "8083", "-async-insert-workers", "0"},
+			env:  map[string]string{"PORT": "8082"},
+			want: func(c *Config) {
+				c.Port = 8083
+				c.StoragePath = "/data"
+				c.KeyPool.Size = 10
+				c.Timeouts.Write = 2 * time.Minute
+				c.AsyncInsert.Workers = 0
+			},
+		},
+		{
+			name: "Timeouts from environment and flags",
+			args: []string{"-shutdown-timeout", "5s"},
+			env:  map[string]string{"HTTP_IDLE_TIMEOUT": "1m"},
+			want: func(c *Config) {
+				c.Timeouts.Idle = time.Minute
+				c.Timeouts.Shutdown = 5 * time.Second
+			},
+		},
+		{
+			name: "Tracing from environment and flags",
+			args: []string{"-tracing-sample-ratio", "0.25"},
+			env:  map[string]string{"TRACING_ENDPOINT": "collector:4317", "TRACING_INSECURE": "true"},
+			want: func(c *Config) {
+				c.Tracing = Tracing{Endpoint: "collector:4317", Insecure: true, SampleRatio: 0.25}
+			},
+		},
+		{
+			name: "Webhooks from environment",
+			env:  map[string]string{"WEBHOOK_MAX_ATTEMPTS": "3", "WEBHOOK_BACKOFF": "1s"},
+			want: func(c *Config) {
+				c.Webhooks.MaxAttempts = 3
+				c.Webhooks.Backoff = time.Second
+			},
+		},
+		{
+			name: "At-rest encryption from environment and flags",
+			args: []string{"-at-rest-key-id", "2024-01"},
+			env:  map[string]string{"AT_REST_KEYS_FILE": "/run/secrets/master-keys", "AT_REST_ROTATION_RATE": "10"},
+			want: func(c *Config) {
+				c.AtRest = AtRest{KeysFile: "/run/secrets/master-keys", KeyId: "2024-01", RotationRate: 10}
+			},
+		},
+		{
+			name: "PKCS#11 master keys from environment",
+			env:  map[string]string{"AT_REST_KEY_ID": "zcelero-2024", "AT_REST_PKCS11_MODULE": "/usr/lib/softhsm/libsofthsm2.so", "AT_REST_PKCS11_TOKEN_LABEL": "zcelero", "AT_REST_PKCS11_DATA_KEYS": "true"},
+			want: func(c *Config) {
+				c.AtRest.KeyId = "zcelero-2024"
+				c.AtRest.PKCS11 = PKCS11{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "zcelero", DataKeys: true}
+			},
+		},
+		{
+			name: "Admin principals from environment",
+			env:  map[string]string{"ADMIN_PRINCIPALS": "CN=admin,O=Zcelero; CN=auditor,O=Zcelero;"},
+			want: func(c *Config) {
+				c.AdminPrincipals = []string{"CN=admin,O=Zcelero", "CN=auditor,O=Zcelero"}
+			},
+		},
+		{
+			name: "Trusted proxies and audit key from environment",
+			env:  map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8, 192.168.1.7,", "AUDIT_KEY_FILE": "/run/secrets/audit-key"},
+			want: func(c *Config) {
+				c.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.7"}
+				c.Audit.KeyFile = "/run/secrets/audit-key"
+			},
+		},
+		{
+			name:    "Invalid boolean",
+			env:     map[string]string{"TRACING_INSECURE": "yes please"},
+			wantErr: "invalid TRACING_INSECURE environment variable",
+		},
+		{
+			name:    "Invalid duration",
+			env:     map[string]string{"HTTP_READ_TIMEOUT": "30"},
+			wantErr: "invalid HTTP_READ_TIMEOUT environment variable",
+		},
+		{
+			name:    "Missing file",
+			args:    []string{"-config", filepath.Join(dir, "missing.yaml")},
+			wantErr: "configuration file could not be read",
+		},
+		{
+			name:    "Unknown key in file",
+			args:    []string{"-config", unknownFile},
+			wantErr: "field prot not found",
+		},
+		{
+			name:    "Invalid environment variable",
+			env:     map[string]string{"KEY_POOL_SIZE": "five"},
+			wantErr: "invalid KEY_POOL_SIZE environment variable",
+		},
+		{
+			name:    "Invalid flag",
+			args:    []string{"-key-sizes", "2048,big"},
+			wantErr: "invalid value",
+		},
+		{
+			name:    "Unexpected argument",
+			args:    []string{"serve"},
+			wantErr: "unexpected argument",
+		},
+		{
+			name:    "Invalid value",
+			args:    []string{"-log-level", "verbose"},
+			wantErr: "invalid configuration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+
+			got, err := Load(tt.args, getenv, &bytes.Buffer{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			want := Default()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	output := &bytes.Buffer{}
+
+	_, err := Load([]string{"-h"}, func(string) string { return "" }, output)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Load() error = %v, want %v", err, flag.ErrHelp)
+	}
+	if !strings.Contains(output.String(), "overrides $STORAGE_PATH") {
+		t.Errorf("Load() usage = %s", output.String())
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr []string
+	}{
+		{
+			name:   "Valid defaults",
+			change: func(c *Config) {},
+		},
+		{
+			name: "Every invalid setting is reported",
+			change: func(c *Config) {
+				c.Port = 0
+				c.GrpcPort = 70000
+				c.GinMode = "prod"
+				c.StoragePath = " "
+				c.KeySizes = []uint64{512}
+				c.KeyPool.Workers = -1
+				c.AsyncInsert.QueueSize = -1
+				c.Timeouts.Write = -time.Second
+				c.Timeouts.Shutdown = 0
+			},
+			wantErr: []string{"port must be", "grpc_port must be", "gin_mode", "storage_path", "key size 512", "key_pool", "async_insert", "timeouts must", "timeouts.shutdown"},
+		},
+		{
+			name:    "Same port for both APIs",
+			change:  func(c *Config) { c.GrpcPort = c.Port },
+			wantErr: []string{"port and grpc_port must be different"},
+		},
+		{
+			name:    "Jobs never expiring",
+			change:  func(c *Config) { c.AsyncInsert.JobTTL = 0 },
+			wantErr: []string{"async_insert job_ttl must be positive"},
+		},
+		{
+			name: "Mutual TLS",
+			change: func(c *Config) {
+				c.TLS = TLS{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem", ClientAuth: "require", RedirectPort: 8000}
+			},
+		},
+		{
+			name:    "TLS key without certificate",
+			change:  func(c *Config) { c.TLS.KeyFile = "key.pem" },
+			wantErr: []string{"cert_file and key_file must be set together"},
+		},
+		{
+			name: "Client certificates without CA",
+			change: func(c *Config) {
+				c.TLS = TLS{CertFile: "cert.pem", KeyFile: "key.pem", ClientAuth: "optional"}
+			},
+			wantErr: []string{"client_auth needs client_ca_file"},
+		},
+		{
+			name:    "Unknown client auth",
+			change:  func(c *Config) { c.TLS.ClientAuth = "always" },
+			wantErr: []string{"client_auth must be one of none, optional, require"},
+		},
+		{
+			name:    "Redirect without TLS on the API port",
+			change:  func(c *Config) { c.TLS.RedirectPort = c.Port },
+			wantErr: []string{"redirect_port needs cert_file", "redirect_port must be a free port"},
+		},
+		{
+			name:    "No key sizes",
+			change:  func(c *Config) { c.KeySizes = nil },
+			wantErr: []string{"key_sizes must have at least one size"},
+		},
+		{
+			name:    "Master keys without key id",
+			change:  func(c *Config) { c.AtRest = AtRest{KeysFile: "master-keys", RotationRate: -1} },
+			wantErr: []string{"at_rest keys_file needs key_id", "at_rest rotation_rate must not be negative"},
+		},
+		{
+			name: "PKCS#11 without token",
+			change: func(c *Config) {
+				c.AtRest = AtRest{KeysFile: "master-keys", KeyId: "2024-01", PKCS11: PKCS11{Module: "/usr/lib/softhsm/libsofthsm2.so"}}
+			},
+			wantErr: []string{"at_rest pkcs11 module needs key_id and token_label", "at_rest keys_file and pkcs11 module can't be used together"},
+		},
+		{
+			name:    "PKCS#11 data keys without module",
+			change:  func(c *Config) { c.AtRest = AtRest{KeyId: "2024-01", PKCS11: PKCS11{DataKeys: true}} },
+			wantErr: []string{"at_rest pkcs11 data_keys needs module"},
+		},
+		{
+			name:    "Trusted proxy host name",
+			change:  func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"} },
+			wantErr: []string{`trusted proxy "proxy.internal" is not an address or a CIDR range`},
+		},
+		{
+			name:    "Webhooks without attempts",
+			change:  func(c *Config) { c.Webhooks.MaxAttempts = 0 },
+			wantErr: []string{"webhooks max_attempts, backoff and timeout must be positive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.change(&c)
+
+			err := c.Validate()
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Config.Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Config.Validate() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
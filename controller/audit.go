@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/logging"
+	"zcelero/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func QueryAudit(auditService service.AuditServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/audit requested")
+
+		var filter entity.AuditFilter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			var appError *apperror.Error
+			if !errors.As(err, &appError) {
+				err = apperror.Wrap(apperror.ValidationFailed, "since and until must be RFC 3339 times and limit a number", err)
+			}
+			abortWithError(c, err)
+			return
+		}
+
+		entries, err := auditService.Query(c.Request.Context(), filter)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/audit finished")
+
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+	}
+}
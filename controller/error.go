@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"zcelero/apperror"
+	"zcelero/logging"
+	"zcelero/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotFound answers requests to unknown routes
+func NotFound(c *gin.Context) {
+	abortWithError(c, apperror.New(apperror.NotFound, "route not found"))
+}
+
+// MethodNotAllowed answers requests to known routes with an unsupported method
+func MethodNotAllowed(c *gin.Context) {
+	abortWithError(c, apperror.New(apperror.MethodNotAllowed, "method not allowed for this route"))
+}
+
+// Recovery answers requests that panicked, the panic value is only logged with the secrets removed
+func Recovery(c *gin.Context, recovered any) {
+	logging.FromContext(c.Request.Context()).Error().Bytes("stack", debug.Stack()).Msg(redact.String(fmt.Sprint(recovered)))
+	abortWithError(c, errors.New("panic recovered"))
+}
+
+// abortWithError writes the error as an RFC 7807 problem, hiding details of errors without a code
+func abortWithError(c *gin.Context, err error) {
+	problem := apperror.ToProblem(err, c.Request.URL.Path)
+
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// bindingError keeps the field list reported by the validator and hides JSON decoding details
+func bindingError(err error) error {
+	var appError *apperror.Error
+	if errors.As(err, &appError) {
+		return appError
+	}
+
+	return apperror.Wrap(apperror.ValidationFailed, "request body must be a valid JSON document", err)
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+	"zcelero/entity"
+	"zcelero/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Live answers while the process is running
+func Live(healthService service.HealthServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, healthService.Live())
+	}
+}
+
+// Ready answers 503 with the failed checks while a dependency is not ready, a degraded application still
+// answers 200
+func Ready(healthService service.HealthServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		health := healthService.Ready(c.Request.Context())
+		if health.Status == entity.HealthStatusUnavailable {
+			c.JSON(http.StatusServiceUnavailable, health)
+			return
+		}
+
+		c.JSON(http.StatusOK, health)
+	}
+}
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+	"zcelero/logging"
+	"zcelero/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetJob(jobService service.JobServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/jobs/:id requested")
+
+		job, err := jobService.Get(c.Param("id"))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/jobs/:id finished")
+
+		c.JSON(http.StatusOK, job)
+	}
+}
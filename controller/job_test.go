@@ -0,0 +1,125 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zcelero/api"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	serviceMock "zcelero/mocks/service"
+	"zcelero/service"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetJobRoute(t *testing.T) {
+	jobService := &serviceMock.JobServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, jobService, nil, nil, nil, nil, config.Default())
+
+	jobId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	jobService.On("Get", jobId).Return(entity.Job{Id: jobId, Status: entity.JobStatusDone, Uuid: "uuid", PrivateKey: "private_key"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/jobs/"+jobId, nil)
+	router.ServeHTTP(w, req)
+
+	response := entity.Job{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private_key", response.PrivateKey)
+}
+
+func TestGetJobRouteNotFound(t *testing.T) {
+	jobService := &serviceMock.JobServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, jobService, nil, nil, nil, nil, config.Default())
+
+	jobId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	jobService.On("Get", jobId).Return(entity.Job{}, apperror.New(apperror.NotFound, "job not found"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/jobs/"+jobId, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetJobRouteWithServiceError(t *testing.T) {
+	jobService := &serviceMock.JobServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, jobService, nil, nil, nil, nil, config.Default())
+
+	jobService.On("Get", "invalid").Return(entity.Job{}, apperror.New(apperror.InvalidId, "id must be a valid uuid"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/jobs/invalid", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPostAsyncRoute(t *testing.T) {
+	jobService := &serviceMock.JobServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, jobService, nil, nil, nil, nil, config.Default())
+
+	encryptation := true
+	args := entity.TextManagement{
+		TextData:           "text data",
+		Encryption:         &encryptation,
+		KeySize:            4096,
+		PrivateKeyPassword: "password123",
+	}
+	body, _ := json.Marshal(args)
+
+	jobId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	jobService.On("Enqueue", mock.Anything, args).Return(entity.Job{Id: jobId, Status: entity.JobStatusPending}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management?async=true", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/v1/jobs/"+jobId, w.Header().Get("Location"))
+}
+
+func TestPostAsyncRouteDisabled(t *testing.T) {
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, nil, config.Default())
+
+	encryptation := false
+	args := entity.TextManagement{
+		TextData:   "text data",
+		Encryption: &encryptation,
+	}
+	body, _ := json.Marshal(args)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management?async=true", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPostAsyncRouteQueueFull(t *testing.T) {
+	jobService := &serviceMock.JobServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, jobService, nil, nil, nil, nil, config.Default())
+
+	encryptation := false
+	args := entity.TextManagement{
+		TextData:   "text data",
+		Encryption: &encryptation,
+	}
+	body, _ := json.Marshal(args)
+
+	jobService.On("Enqueue", mock.Anything, args).Return(entity.Job{}, service.ErrJobQueueFull)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management?async=true", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"time"
+	"zcelero/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID takes the X-Request-ID header, or creates a new id, echoes it in the response and puts a
+// logger tagged with it, and with the trace id when the request is traced, in the request context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := logging.RequestID(c.GetHeader(logging.RequestIDHeader))
+		c.Header(logging.RequestIDHeader, requestId)
+
+		logger := logging.FromContext(c.Request.Context()).With().Str("request_id", requestId)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			logger = logger.Str("trace_id", span.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger.Logger()))
+
+		c.Next()
+	}
+}
+
+// AccessLog writes one structured line for every answered request, the query and body are left out
+// since they may carry secrets
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// the context is read after the handlers so the line includes the principal
+		logging.FromContext(c.Request.Context()).Info().
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("route", c.FullPath()).
+			Int("status", c.Writer.Status()).
+			Int("bytes", c.Writer.Size()).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent()).
+			Msg("request answered")
+	}
+}
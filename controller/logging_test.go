@@ -0,0 +1,144 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"zcelero/api"
+	"zcelero/config"
+	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/repository"
+	"zcelero/service"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// captureLogs sends every log line at debug level to the returned buffer until the end of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	output := &bytes.Buffer{}
+	logger, level := log.Logger, zerolog.GlobalLevel()
+	log.Logger = logging.NewLogger(output)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+	})
+
+	return output
+}
+
+func logLines(t *testing.T, output *bytes.Buffer) []map[string]any {
+	lines := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		fields := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+
+	return lines
+}
+
+func TestRequestLogging(t *testing.T) {
+	helper := helper.NewHelper()
+	textService := service.NewService(repository.NewRepository(helper, t.TempDir()), helper, nil, 0)
+	router := api.Start(textService, nil, nil, nil, nil, nil, config.Default())
+
+	tests := []struct {
+		name          string
+		requestId     string
+		wantRequestId func(id string) bool
+	}{
+		{
+			name:          "Client request id",
+			requestId:     "checkout-42",
+			wantRequestId: func(id string) bool { return id == "checkout-42" },
+		},
+		{
+			name:          "Generated request id",
+			requestId:     "",
+			wantRequestId: func(id string) bool { return len(id) == 36 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]any{"text_data": "plain text", "encryption": false})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Request-ID", tt.requestId)
+			output := captureLogs(t)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			requestId := w.Header().Get("X-Request-ID")
+			assert.Equal(t, true, tt.wantRequestId(requestId))
+			inserted := struct {
+				Uuid string `json:"uuid"`
+			}{}
+			json.Unmarshal(w.Body.Bytes(), &inserted)
+
+			lines := logLines(t, output)
+			textLines := 0
+			for _, line := range lines {
+				assert.Equal(t, requestId, line["request_id"])
+				if line["text_id"] != nil {
+					assert.Equal(t, inserted.Uuid, line["text_id"])
+					textLines++
+				}
+			}
+			if textLines == 0 {
+				t.Errorf("no log line has the text_id: %v", lines)
+			}
+
+			access := lines[len(lines)-1]
+			assert.Equal(t, "request answered", access["message"])
+			assert.Equal(t, "POST", access["method"])
+			assert.Equal(t, "/v1/text-management", access["route"])
+			assert.Equal(t, float64(http.StatusOK), access["status"])
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	behindProxy := config.Default()
+	behindProxy.TrustedProxies = []string{"10.0.0.0/8"}
+
+	tests := []struct {
+		name   string
+		config config.Config
+		want   string
+	}{
+		{
+			name:   "Forwarded header from any client is ignored",
+			config: config.Default(),
+			want:   "10.0.0.1",
+		},
+		{
+			name:   "Forwarded header from a trusted proxy",
+			config: behindProxy,
+			want:   "203.0.113.9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := api.Start(&service.TextManagementService{}, nil, nil, nil, nil, nil, tt.config)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/unknown", nil)
+			req.RemoteAddr = "10.0.0.1:40000"
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			output := captureLogs(t)
+			router.ServeHTTP(w, req)
+
+			lines := logLines(t, output)
+			assert.Equal(t, tt.want, lines[len(lines)-1]["client_ip"])
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+	"zcelero/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts every request and observes its latency by method, route template and status code,
+// requests matching no route are grouped under the unmatched route
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(metrics.Since(start))
+	}
+}
@@ -0,0 +1,68 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"zcelero/api"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/metrics"
+	serviceMock "zcelero/mocks/service"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetrics(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantRoute  string
+		wantStatus string
+	}{
+		{
+			name:       "Route template instead of the query",
+			method:     http.MethodGet,
+			target:     "/v1/text-management/metadata?id=" + uuid,
+			wantRoute:  "/v1/text-management/metadata",
+			wantStatus: "200",
+		},
+		{
+			name:       "Route with path parameter",
+			method:     http.MethodGet,
+			target:     "/v1/jobs/" + uuid,
+			wantRoute:  "/v1/jobs/:id",
+			wantStatus: "200",
+		},
+		{
+			name:       "Unknown route",
+			method:     http.MethodGet,
+			target:     "/v1/unknown/" + uuid,
+			wantRoute:  "unmatched",
+			wantStatus: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &serviceMock.TextManagementServiceInteface{}
+			service.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid}, nil)
+			jobService := &serviceMock.JobServiceInterface{}
+			jobService.On("Get", uuid).Return(entity.Job{Id: uuid, Status: entity.JobStatusPending}, nil)
+			router := api.Start(service, jobService, nil, nil, nil, nil, config.Default())
+			requests := metrics.HTTPRequests.WithLabelValues(tt.method, tt.wantRoute, tt.wantStatus)
+			before := testutil.ToFloat64(requests)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.target, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, strconv.Itoa(w.Code))
+			assert.Equal(t, before+1, testutil.ToFloat64(requests))
+		})
+	}
+}
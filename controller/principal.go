@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"zcelero/logging"
+	"zcelero/principal"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate puts the subject of the verified client certificate in the request context as the principal,
+// along with the client address
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := principal.NewClientIPContext(c.Request.Context(), c.ClientIP())
+		if name := principal.FromTLS(c.Request.TLS); name != "" {
+			ctx = logging.With(principal.NewContext(ctx, name), "principal", name)
+			logging.FromContext(ctx).Debug().Msg("client certificate verified")
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
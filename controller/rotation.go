@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+	"zcelero/logging"
+	"zcelero/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func StartRotation(rotationService service.RotationServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/key-rotation requested")
+
+		rotation, err := rotationService.Start(c.Request.Context())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/key-rotation finished")
+
+		c.JSON(http.StatusAccepted, rotation)
+	}
+}
+
+func GetRotation(rotationService service.RotationServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/key-rotation requested")
+
+		rotation, err := rotationService.Status(c.Request.Context())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/key-rotation finished")
+
+		c.JSON(http.StatusOK, rotation)
+	}
+}
@@ -0,0 +1,74 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zcelero/api"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	serviceMock "zcelero/mocks/service"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStartRotationRoute(t *testing.T) {
+	rotationService := &serviceMock.RotationServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, rotationService, config.Default())
+
+	rotationService.On("Start", mock.Anything).Return(entity.Rotation{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.RotationStatusRunning, KeyId: "2024-06"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/key-rotation", nil)
+	router.ServeHTTP(w, req)
+
+	response := entity.Rotation{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "2024-06", response.KeyId)
+}
+
+func TestStartRotationRouteForbidden(t *testing.T) {
+	rotationService := &serviceMock.RotationServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, rotationService, config.Default())
+
+	rotationService.On("Start", mock.Anything).Return(entity.Rotation{}, apperror.New(apperror.Forbidden, "an admin client certificate is required to rotate the master key"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/key-rotation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetRotationRoute(t *testing.T) {
+	rotationService := &serviceMock.RotationServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, rotationService, config.Default())
+
+	rotationService.On("Status", mock.Anything).Return(entity.Rotation{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.RotationStatusDone, Total: 2, Processed: 2}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/key-rotation", nil)
+	router.ServeHTTP(w, req)
+
+	response := entity.Rotation{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, entity.RotationStatusDone, response.Status)
+}
+
+func TestRotationRoutesDisabled(t *testing.T) {
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, nil, config.Default())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/key-rotation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
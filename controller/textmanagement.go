@@ -1,22 +1,27 @@
 package controller
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"zcelero/apperror"
 	"zcelero/entity"
+	"zcelero/logging"
 	"zcelero/service"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
 )
 
 func Get(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Debug().Msg("end-point GET /v1/text-management requested")
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/text-management requested")
 
-		textId, exists := c.GetQuery("id")
-		if !exists {
-			log.Info().Msg("id not sent")
-			c.JSON(http.StatusNotAcceptable, gin.H{"message": "id param is required"})
+		query := struct {
+			Id string `form:"id" json:"id" binding:"required,uuid"`
+		}{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
 			return
 		}
 
@@ -24,54 +29,193 @@ func Get(textManagementService service.TextManagementServiceInteface) gin.Handle
 			PrivateKey         string `json:"private_key"`
 			PrivateKeyPassword string `json:"private_key_password"`
 		}{}
-		if err := c.ShouldBindJSON(&json); err != nil {
-			log.Error().Msg(err.Error())
-			c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+		// the body is optional since unencrypted texts don't need a private key
+		if err := c.ShouldBindJSON(&json); err != nil && !isEmptyBody(c, err) {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
 			return
 		}
 
-		response, err := textManagementService.Get(textId, json.PrivateKey, json.PrivateKeyPassword)
+		response, err := textManagementService.Get(c.Request.Context(), query.Id, json.PrivateKey, json.PrivateKeyPassword)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, err)
 			return
 		}
 
-		log.Debug().Msg("end-point GET /v1/text-management finished")
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/text-management finished")
 
 		c.JSON(http.StatusOK, gin.H{"text": response})
 	}
 }
 
-func Insert(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
+func GetMetadata(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/text-management/metadata requested")
+
+		query := struct {
+			Id string `form:"id" json:"id" binding:"required,uuid"`
+		}{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		response, err := textManagementService.GetMetadata(c.Request.Context(), query.Id)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/text-management/metadata finished")
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func Delete(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Debug().Msg("end-point POST /v1/text-management requested")
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point DELETE /v1/text-management requested")
+
+		query := struct {
+			Id string `form:"id" json:"id" binding:"required,uuid"`
+		}{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		json := struct {
+			PrivateKey         string `json:"private_key"`
+			PrivateKeyPassword string `json:"private_key_password"`
+		}{}
+		// the owner proves it with its client certificate or with the private key and password of the text
+		if err := c.ShouldBindJSON(&json); err != nil && !isEmptyBody(c, err) {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		if err := textManagementService.Delete(c.Request.Context(), query.Id, json.PrivateKey, json.PrivateKeyPassword); err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point DELETE /v1/text-management finished")
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func Insert(textManagementService service.TextManagementServiceInteface, jobService service.JobServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/text-management requested")
 
 		var json entity.TextManagement
 		if err := c.ShouldBindJSON(&json); err != nil {
-			log.Error().Msg(err.Error())
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		if c.Query("async") == "true" {
+			insertAsync(c, jobService, json)
+			return
+		}
+
+		response, err := textManagementService.Insert(c.Request.Context(), json)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/text-management finished")
+
+		c.JSON(http.StatusOK, gin.H{"uuid": response.Uuid, "private_key": response.PrivateKey})
+	}
+}
+
+func Rekey(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/text-management/rekey requested")
+
+		query := struct {
+			Id string `form:"id" json:"id" binding:"required,uuid"`
+		}{}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		var json entity.Rekey
+		if err := c.ShouldBindJSON(&json); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
 			return
 		}
 
-		if *json.Encryption && json.PrivateKeyPassword == "" {
-			log.Info().Msg("private_key_password not sent when encryption is required")
-			c.JSON(http.StatusNotAcceptable, gin.H{"error": "private_key_password is required when encryption is true"})
+		privateKey, err := textManagementService.Rekey(c.Request.Context(), query.Id, json)
+		if err != nil {
+			abortWithError(c, err)
 			return
 		}
-		if *json.Encryption && (json.KeySize != 1024 && json.KeySize != 2048 && json.KeySize != 4096) {
-			log.Info().Msg("keysize must be 1024 or 2048 or 4096")
-			c.JSON(http.StatusNotAcceptable, gin.H{"error": "keysize must be 1024 or 2048 or 4096"})
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/text-management/rekey finished")
+
+		response := gin.H{"uuid": query.Id}
+		// the client keeps the private key of the public key it sent
+		if privateKey != "" {
+			response["private_key"] = privateKey
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func ChangePassword(textManagementService service.TextManagementServiceInteface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/private-key/password requested")
+
+		var json entity.PasswordChange
+		if err := c.ShouldBindJSON(&json); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
 			return
 		}
 
-		response, err := textManagementService.Insert(json)
+		privateKey, err := textManagementService.ChangePassword(c.Request.Context(), json)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, err)
 			return
 		}
 
-		log.Debug().Msg("end-point GET /v1/text-management finished")
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/private-key/password finished")
 
-		c.JSON(http.StatusOK, gin.H{"uuid": response.Uuid, "private_key": response.PrivateKey})
+		c.JSON(http.StatusOK, gin.H{"private_key": privateKey})
 	}
 }
+
+func insertAsync(c *gin.Context, jobService service.JobServiceInterface, text entity.TextManagement) {
+	if jobService == nil {
+		logging.FromContext(c.Request.Context()).Info().Msg("async insert requested but async mode is disabled")
+		abortWithError(c, apperror.New(apperror.ValidationFailed, "async mode is not enabled"))
+		return
+	}
+
+	job, err := jobService.Enqueue(c.Request.Context(), text)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/text-management queued")
+
+	c.Header("Location", "/v1/jobs/"+job.Id)
+	c.JSON(http.StatusAccepted, gin.H{"job_id": job.Id, "status": job.Status})
+}
+
+// isEmptyBody tells whether the binding failed only because the request has no body
+func isEmptyBody(c *gin.Context, err error) bool {
+	return c.Request.Body == nil || c.Request.Body == http.NoBody || errors.Is(err, io.EOF)
+}
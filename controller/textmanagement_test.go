@@ -4,20 +4,24 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"zcelero/api"
+	"zcelero/apperror"
+	"zcelero/config"
 	"zcelero/entity"
 	serviceMock "zcelero/mocks/service"
 
 	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestGetUserRoute(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
 	args := struct {
@@ -25,11 +29,11 @@ func TestGetUserRoute(t *testing.T) {
 		PrivateKeyPassword string `json:"private_key_password"`
 	}{
 		PrivateKey:         "private_key",
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
-	service.On("Get", uuid, args.PrivateKey, args.PrivateKeyPassword).Return("message", nil)
+	service.On("Get", mock.Anything, uuid, args.PrivateKey, args.PrivateKeyPassword).Return("message", nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management?id="+uuid, bytes.NewReader(body))
@@ -40,14 +44,14 @@ func TestGetUserRoute(t *testing.T) {
 
 func TestGetUserRouteWithoutTextID(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	args := struct {
 		PrivateKey         string `json:"private_key"`
 		PrivateKeyPassword string `json:"private_key_password"`
 	}{
 		PrivateKey:         "private_key",
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
@@ -55,12 +59,12 @@ func TestGetUserRouteWithoutTextID(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management", bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestGetUserRouteWithServiceError(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
 	args := struct {
@@ -68,22 +72,52 @@ func TestGetUserRouteWithServiceError(t *testing.T) {
 		PrivateKeyPassword string `json:"private_key_password"`
 	}{
 		PrivateKey:         "private_key",
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
-	service.On("Get", uuid, args.PrivateKey, args.PrivateKeyPassword).Return("", errors.New("some error"))
+	service.On("Get", mock.Anything, uuid, args.PrivateKey, args.PrivateKeyPassword).Return("", errors.New("some error"))
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management?id="+uuid, bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"/problems/internal","title":"Internal Server Error","status":500,"detail":"internal server error","instance":"/v1/text-management","code":"internal"}`, w.Body.String())
+}
+
+func TestGetUserRouteWithWrongPassword(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	args := struct {
+		PrivateKey         string `json:"private_key"`
+		PrivateKeyPassword string `json:"private_key_password"`
+	}{
+		PrivateKey:         "private_key",
+		PrivateKeyPassword: "bbb",
+	}
+	body, _ := json.Marshal(args)
+
+	service.On("Get", mock.Anything, uuid, args.PrivateKey, args.PrivateKeyPassword).Return("", apperror.New(apperror.WrongPassword, "private_key_password is incorrect"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management?id="+uuid, bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, apperror.WrongPassword, problem.Code)
+	assert.Equal(t, "private_key_password is incorrect", problem.Detail)
 }
 
 func TestGetUserRouteBidingError(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
 	args := struct {
@@ -99,12 +133,12 @@ func TestGetUserRouteBidingError(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management?id="+uuid, bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestPostUserRouteWithoutEncryptation(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := false
 	args := entity.TextManagement{
@@ -119,7 +153,7 @@ func TestPostUserRouteWithoutEncryptation(t *testing.T) {
 		Uuid:       "uuid",
 	}
 
-	service.On("Insert", args).Return(response, nil)
+	service.On("Insert", mock.Anything, args).Return(response, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
@@ -130,14 +164,14 @@ func TestPostUserRouteWithoutEncryptation(t *testing.T) {
 
 func TestPostUserRouteWithEncryptation(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := true
 	args := entity.TextManagement{
 		TextData:           "text data",
 		Encryption:         &encryptation,
 		KeySize:            1024,
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
@@ -150,7 +184,7 @@ func TestPostUserRouteWithEncryptation(t *testing.T) {
 		PrivateKey:         "private_key",
 	}
 
-	service.On("Insert", args).Return(response, nil)
+	service.On("Insert", mock.Anything, args).Return(response, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
@@ -161,13 +195,13 @@ func TestPostUserRouteWithEncryptation(t *testing.T) {
 
 func TestPostUserRouteWithBindingError(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := true
 	args := entity.TextManagement{
 		Encryption:         &encryptation,
 		KeySize:            1024,
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
@@ -180,7 +214,7 @@ func TestPostUserRouteWithBindingError(t *testing.T) {
 
 func TestPostUserRouteWithoutPassword(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := true
 	args := entity.TextManagement{
@@ -194,18 +228,18 @@ func TestPostUserRouteWithoutPassword(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestPostUserRouteWithWrongKeySize(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := true
 	args := entity.TextManagement{
 		TextData:           "text data",
 		Encryption:         &encryptation,
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 		KeySize:            12,
 	}
 	body, _ := json.Marshal(args)
@@ -214,27 +248,284 @@ func TestPostUserRouteWithWrongKeySize(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestPostUserRouteWithInsertError(t *testing.T) {
 	service := &serviceMock.TextManagementServiceInteface{}
-	router := api.Start(service)
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
 
 	encryptation := true
 	args := entity.TextManagement{
 		TextData:           "text data",
 		Encryption:         &encryptation,
 		KeySize:            1024,
-		PrivateKeyPassword: "aaa",
+		PrivateKeyPassword: "password123",
 	}
 	body, _ := json.Marshal(args)
 
-	service.On("Insert", args).Return(entity.TextManagement{}, errors.New("error"))
+	service.On("Insert", mock.Anything, args).Return(entity.TextManagement{}, errors.New("error"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestPostUserRouteWithSeveralValidationErrors(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	encryptation := false
+	args := entity.TextManagement{
+		TextData:           " ",
+		Encryption:         &encryptation,
+		KeySize:            2048,
+		PrivateKeyPassword: "password123",
+	}
+	body, _ := json.Marshal(args)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader(body))
 	router.ServeHTTP(w, req)
 
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apperror.ValidationFailed, problem.Code)
+	assert.Equal(t, []apperror.FieldError{
+		{Field: "text_data", Code: "notblank", Message: "must not be blank"},
+		{Field: "key_size", Code: "excluded_without_encryption", Message: "must not be sent when encryption is false"},
+		{Field: "private_key_password", Code: "excluded_without_encryption", Message: "must not be sent when encryption is false"},
+	}, problem.Errors)
+	service.AssertNotCalled(t, "Insert")
+}
+
+func TestUnknownRouteReturnsProblem(t *testing.T) {
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, nil, config.Default())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/unknown", nil)
+	router.ServeHTTP(w, req)
+
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, apperror.NotFound, problem.Code)
+}
+
+func TestUnsupportedMethodReturnsProblem(t *testing.T) {
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, nil, nil, config.Default())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/v1/text-management", nil)
+	router.ServeHTTP(w, req)
+
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, apperror.MethodNotAllowed, problem.Code)
+}
+
+func TestPanicReturnsProblem(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	service.On("Insert", mock.Anything, mock.AnythingOfType("entity.TextManagement")).Run(func(args mock.Arguments) { panic("secret detail") })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management", bytes.NewReader([]byte(`{"text_data":"text data","encryption":false}`)))
+	router.ServeHTTP(w, req)
+
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, apperror.Internal, problem.Code)
+	assert.Equal(t, "internal server error", problem.Detail)
+}
+
+func TestGetUserRouteWithoutBody(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{name: "Request without body", body: nil},
+		{name: "Request with empty body", body: bytes.NewReader(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &serviceMock.TextManagementServiceInteface{}
+			router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+			service.On("Get", mock.Anything, uuid, "", "").Return("message", nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/v1/text-management?id="+uuid, tt.body)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestGetMetadataRoute(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	service.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true, KeySize: 2048}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management/metadata?id="+uuid, nil)
+	router.ServeHTTP(w, req)
+
+	metadata := entity.TextMetadata{}
+	json.Unmarshal(w.Body.Bytes(), &metadata)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint64(2048), metadata.KeySize)
+}
+
+func TestGetMetadataRouteWithServiceError(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	service.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{}, apperror.New(apperror.NotFound, "text not found"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/text-management/metadata?id="+uuid, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteRoute(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	service.On("Delete", mock.Anything, uuid, "", "").Return(nil)
+	service.On("Delete", mock.Anything, uuid, "private_key", "password123").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/v1/text-management?id="+uuid, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/v1/text-management?id="+uuid, bytes.NewBufferString(`{"private_key":"private_key","private_key_password":"password123"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	service.AssertExpectations(t)
+}
+
+func TestDeleteRouteWithServiceError(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	service.On("Delete", mock.Anything, uuid, "", "").Return(apperror.New(apperror.Forbidden, "the private key and password of the text, or the client certificate that stored it, are required to remove it"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/v1/text-management?id="+uuid, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestChangePasswordRoute(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	change := entity.PasswordChange{PrivateKey: "private_key", PrivateKeyPassword: "password123", NewPrivateKeyPassword: "password456"}
+	body, _ := json.Marshal(change)
+	service.On("ChangePassword", mock.Anything, change).Return("new_private_key", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/private-key/password", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"private_key":"new_private_key"}`, w.Body.String())
+}
+
+func TestChangePasswordRouteWithWeakPassword(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	body, _ := json.Marshal(entity.PasswordChange{PrivateKey: "private_key", PrivateKeyPassword: "password123", NewPrivateKeyPassword: "short"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/private-key/password", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	service.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything)
+}
+
+func TestChangePasswordRouteWithWrongPassword(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	change := entity.PasswordChange{PrivateKey: "private_key", PrivateKeyPassword: "password789", NewPrivateKeyPassword: "password456"}
+	body, _ := json.Marshal(change)
+	service.On("ChangePassword", mock.Anything, change).Return("", apperror.New(apperror.WrongPassword, "private_key_password is incorrect"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/private-key/password", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRekeyRoute(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	rekey := entity.Rekey{PrivateKey: "private_key", PrivateKeyPassword: "password123", NewPrivateKeyPassword: "password456"}
+	body, _ := json.Marshal(rekey)
+	service.On("Rekey", mock.Anything, uuid, rekey).Return("new_private_key", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management/rekey?id="+uuid, bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"private_key":"new_private_key","uuid":"`+uuid+`"}`, w.Body.String())
+}
+
+func TestRekeyRouteWithoutNewPassword(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	body, _ := json.Marshal(entity.Rekey{PrivateKey: "private_key", PrivateKeyPassword: "password123"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management/rekey?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	service.AssertNotCalled(t, "Rekey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRekeyRouteWithoutTextID(t *testing.T) {
+	service := &serviceMock.TextManagementServiceInteface{}
+	router := api.Start(service, nil, nil, nil, nil, nil, config.Default())
+
+	body, _ := json.Marshal(entity.Rekey{PrivateKey: "private_key", PrivateKeyPassword: "password123", NewPrivateKeyPassword: "password456"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/text-management/rekey", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
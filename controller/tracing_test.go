@@ -0,0 +1,114 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zcelero/api"
+	"zcelero/config"
+	"zcelero/helper"
+	"zcelero/repository"
+	"zcelero/service"
+	"zcelero/tracing"
+
+	"github.com/go-playground/assert/v2"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider keeping the spans in memory until the end of the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	tracing.Setup(config.Tracing{})
+
+	return exporter
+}
+
+func TestTrace(t *testing.T) {
+	traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	storage := t.TempDir()
+	helper := helper.NewHelper()
+	textService := service.NewService(repository.NewRepository(helper, storage), helper, nil, 0)
+	router := api.Start(textService, nil, nil, nil, nil, nil, config.Default())
+	inserted := struct {
+		Uuid       string `json:"uuid"`
+		PrivateKey string `json:"private_key"`
+	}{}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     func() string
+		body       func() any
+		wantStatus int
+		wantSpan   string
+		wantSpans  []string
+	}{
+		{
+			name:   "Encrypted insert",
+			method: http.MethodPost,
+			target: func() string { return "/v1/text-management" },
+			body: func() any {
+				return map[string]any{"text_data": "secret", "encryption": true, "key_size": 1024, "private_key_password": "password123"}
+			},
+			wantStatus: http.StatusOK,
+			wantSpan:   "POST /v1/text-management",
+			wantSpans:  []string{"keys.Generate", "crypto.Encrypt", "repository.Save"},
+		},
+		{
+			name:   "Decrypted read",
+			method: http.MethodGet,
+			target: func() string { return "/v1/text-management?id=" + inserted.Uuid },
+			body: func() any {
+				return map[string]any{"private_key": inserted.PrivateKey, "private_key_password": "password123"}
+			},
+			wantStatus: http.StatusOK,
+			wantSpan:   "GET /v1/text-management",
+			wantSpans:  []string{"repository.Load", "crypto.Decrypt"},
+		},
+		{
+			name:   "Wrong password",
+			method: http.MethodGet,
+			target: func() string { return "/v1/text-management?id=" + inserted.Uuid },
+			body: func() any {
+				return map[string]any{"private_key": inserted.PrivateKey, "private_key_password": "password456"}
+			},
+			wantStatus: http.StatusForbidden,
+			wantSpan:   "GET /v1/text-management",
+			wantSpans:  []string{"repository.Load", "crypto.Decrypt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := recordSpans(t)
+
+			body, _ := json.Marshal(tt.body())
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.target(), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+			router.ServeHTTP(w, req)
+			json.Unmarshal(w.Body.Bytes(), &inserted)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			spans := exporter.GetSpans()
+			if len(spans) != len(tt.wantSpans)+1 {
+				t.Fatalf("exported %d spans, want %d: %v", len(spans), len(tt.wantSpans)+1, spans)
+			}
+			server := spans[len(spans)-1]
+			assert.Equal(t, tt.wantSpan, server.Name)
+			assert.Equal(t, traceId, server.SpanContext.TraceID().String())
+			assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+			for i, name := range tt.wantSpans {
+				assert.Equal(t, name, spans[i].Name)
+				assert.Equal(t, server.SpanContext.SpanID(), spans[i].Parent.SpanID())
+			}
+		})
+	}
+}
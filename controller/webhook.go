@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+	"zcelero/entity"
+	"zcelero/logging"
+	"zcelero/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Subscribe(webhookService service.WebhookServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/webhooks requested")
+
+		var subscription entity.WebhookSubscription
+		if err := c.ShouldBindJSON(&subscription); err != nil {
+			logging.FromContext(c.Request.Context()).Info().Msg(err.Error())
+			abortWithError(c, bindingError(err))
+			return
+		}
+
+		subscription, err := webhookService.Subscribe(c.Request.Context(), subscription)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point POST /v1/webhooks finished")
+
+		c.JSON(http.StatusCreated, subscription)
+	}
+}
+
+func ListWebhooks(webhookService service.WebhookServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/webhooks requested")
+
+		subscriptions, err := webhookService.List(c.Request.Context())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point GET /v1/webhooks finished")
+
+		c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+	}
+}
+
+func Unsubscribe(webhookService service.WebhookServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point DELETE /v1/webhooks/:id requested")
+
+		if err := webhookService.Unsubscribe(c.Request.Context(), c.Param("id")); err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Debug().Msg("end-point DELETE /v1/webhooks/:id finished")
+
+		c.Status(http.StatusNoContent)
+	}
+}
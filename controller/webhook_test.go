@@ -0,0 +1,76 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zcelero/api"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	serviceMock "zcelero/mocks/service"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSubscribeRoute(t *testing.T) {
+	webhookService := &serviceMock.WebhookServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, webhookService, nil, config.Default())
+
+	subscription := entity.WebhookSubscription{Url: "https://example.com/hook", Events: []string{entity.WebhookEventRead}}
+	webhookService.On("Subscribe", mock.Anything, subscription).Return(entity.WebhookSubscription{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Url: subscription.Url, Events: subscription.Events, Secret: "secret"}, nil)
+	body, _ := json.Marshal(subscription)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	response := entity.WebhookSubscription{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "secret", response.Secret)
+}
+
+func TestSubscribeRouteWithValidationErrors(t *testing.T) {
+	webhookService := &serviceMock.WebhookServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, webhookService, nil, config.Default())
+
+	body, _ := json.Marshal(map[string]any{"url": "/relative", "events": []string{"text.created"}, "text_id": "invalid"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	problem := apperror.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []apperror.FieldError{
+		{Field: "url", Code: "webhookurl", Message: "must be an absolute http or https URL"},
+		{Field: "events[0]", Code: "webhookevent", Message: "must be one of text.read, text.deleted, text.decryption_failed, text.rekeyed"},
+		{Field: "text_id", Code: "uuid", Message: "must be a valid uuid"},
+	}, problem.Errors)
+	webhookService.AssertNotCalled(t, "Subscribe")
+}
+
+func TestUnsubscribeRoute(t *testing.T) {
+	webhookService := &serviceMock.WebhookServiceInterface{}
+	router := api.Start(&serviceMock.TextManagementServiceInteface{}, nil, nil, nil, webhookService, nil, config.Default())
+
+	subscriptionId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	webhookService.On("Unsubscribe", mock.Anything, subscriptionId).Return(nil).Once()
+	webhookService.On("Unsubscribe", mock.Anything, subscriptionId).Return(apperror.New(apperror.NotFound, "webhook subscription not found"))
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/v1/webhooks/"+subscriptionId, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"testing"
 	"zcelero/api"
+	"zcelero/config"
 	"zcelero/entity"
 	"zcelero/helper"
 	"zcelero/repository"
@@ -20,16 +21,16 @@ import (
 func TestEndToEndEncrypted(t *testing.T) {
 	os.Mkdir("storage", 0777)
 	helper := helper.NewHelper()
-	textManagementRepository := repository.NewRepository(helper)
-	textManagementService := service.NewService(textManagementRepository, helper)
-	router := api.Start(textManagementService)
+	textManagementRepository := repository.NewRepository(helper, "storage")
+	textManagementService := service.NewService(textManagementRepository, helper, nil, 0)
+	router := api.Start(textManagementService, nil, nil, nil, nil, nil, config.Default())
 	encryptation := true
 
 	postArgs := entity.TextManagement{
 		TextData:           "encrypted text data",
 		Encryption:         &encryptation,
 		KeySize:            1024,
-		PrivateKeyPassword: "123456",
+		PrivateKeyPassword: "password123",
 	}
 	postBody, _ := json.Marshal(postArgs)
 
@@ -63,9 +64,9 @@ func TestEndToEndEncrypted(t *testing.T) {
 func TestEndToEndNonEncrypted(t *testing.T) {
 	os.Mkdir("storage", 0777)
 	helper := helper.NewHelper()
-	textManagementRepository := repository.NewRepository(helper)
-	textManagementService := service.NewService(textManagementRepository, helper)
-	router := api.Start(textManagementService)
+	textManagementRepository := repository.NewRepository(helper, "storage")
+	textManagementService := service.NewService(textManagementRepository, helper, nil, 0)
+	router := api.Start(textManagementService, nil, nil, nil, nil, nil, config.Default())
 	encryptation := false
 
 	postArgs := entity.TextManagement{
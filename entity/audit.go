@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+const (
+	AuditActionInsert         = "text.insert"
+	AuditActionRead           = "text.read"
+	AuditActionReadMetadata   = "text.read_metadata"
+	AuditActionDelete         = "text.delete"
+	AuditActionRekey          = "text.rekey"
+	AuditActionQuery          = "audit.query"
+	AuditActionKeyRotation    = "key_rotation.start"
+	AuditActionPasswordChange = "private_key.password_change"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEntry records an operation, Hash covers every other field including the hash of the previous entry
+type AuditEntry struct {
+	Sequence     uint64    `json:"sequence"`
+	Time         time.Time `json:"time"`
+	Action       string    `json:"action"`
+	Outcome      string    `json:"outcome"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+	Principal    string    `json:"principal,omitempty"`
+	TextId       string    `json:"text_id,omitempty"`
+	ClientIP     string    `json:"client_ip,omitempty"`
+	PreviousHash string    `json:"previous_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// AuditFilter selects audit entries, empty fields match every entry
+type AuditFilter struct {
+	Action    string    `form:"action"`
+	TextId    string    `form:"text_id"`
+	Principal string    `form:"principal"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
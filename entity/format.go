@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"zcelero/redact"
+)
+
+// Format prints the text with its secret fields redacted, so a text reaching a log or a panic through
+// fmt, e.g. in a mock assertion, doesn't expose them
+func (t TextManagement) Format(f fmt.State, verb rune) {
+	type plain TextManagement
+	redacted := plain(t)
+	redacted.TextData = redact.Value(t.TextData)
+	redacted.PrivateKeyPassword = redact.Value(t.PrivateKeyPassword)
+	redacted.PrivateKey = redact.Value(t.PrivateKey)
+
+	printRedacted(f, verb, redacted, "entity.TextManagement")
+}
+
+// Format prints the job with the private key redacted
+func (j Job) Format(f fmt.State, verb rune) {
+	type plain Job
+	redacted := plain(j)
+	redacted.PrivateKey = redact.Value(j.PrivateKey)
+
+	printRedacted(f, verb, redacted, "entity.Job")
+}
+
+// printRedacted prints the redacted copy with the verb and the flags changing how structs are printed,
+// the Go syntax keeps the name of the original type
+func printRedacted(f fmt.State, verb rune, redacted any, typeName string) {
+	format := "%"
+	for _, flag := range "+#" {
+		if f.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+
+	io.WriteString(f, strings.Replace(fmt.Sprintf(format+string(verb), redacted), "entity.plain", typeName, 1))
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+type Job struct {
+	Id           string    `json:"id"`
+	Status       string    `json:"status"`
+	Uuid         string    `json:"uuid,omitempty"`
+	PrivateKey   string    `json:"private_key,omitempty"`
+	KeyDelivered bool      `json:"key_delivered"`
+	Error        string    `json:"error,omitempty"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	RotationStatusRunning = "running"
+	RotationStatusDone    = "done"
+	RotationStatusFailed  = "failed"
+)
+
+// Rotation is the progress of the job wrapping every stored text with the primary master key. Cursor is
+// the last text processed, the texts are processed in order so an interrupted rotation resumes after it
+type Rotation struct {
+	Id         string     `json:"id"`
+	Status     string     `json:"status"`
+	KeyId      string     `json:"key_id"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Rewrapped  int        `json:"rewrapped"`
+	Failed     int        `json:"failed"`
+	Cursor     string     `json:"cursor,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
@@ -1,10 +1,47 @@
 package entity
 
+import "time"
+
+// EncryptionModeRSA encrypts the text with a new RSA key pair, EncryptionModePassphrase with a key derived
+// from the password so reading it only needs the password
+const (
+	EncryptionModeRSA        = "rsa"
+	EncryptionModePassphrase = "passphrase"
+)
+
 type TextManagement struct {
-	TextData           string `json:"text_data" binding:"required"`
+	TextData           string `json:"text_data" binding:"required,notblank,maxbytes"`
 	Encryption         *bool  `json:"encryption" binding:"required"`
-	KeySize            uint64 `json:"key_size"`
+	EncryptionMode     string `json:"encryption_mode" binding:"omitempty,oneof=rsa passphrase"`
+	KeySize            uint64 `json:"key_size" binding:"omitempty,keysize"`
 	Uuid               string `json:"uuid"`
-	PrivateKeyPassword string `json:"private_key_password"`
+	PrivateKeyPassword string `json:"private_key_password" binding:"omitempty,password"`
 	PrivateKey         string `json:"private_key"`
 }
+
+// PasswordChange carries an encrypted private key with its password and the password to encrypt it with instead
+type PasswordChange struct {
+	PrivateKey            string `json:"private_key" binding:"required"`
+	PrivateKeyPassword    string `json:"private_key_password" binding:"required"`
+	NewPrivateKeyPassword string `json:"new_private_key_password" binding:"required,password"`
+}
+
+// Rekey carries the private key of a stored text with its password, and either the password of the new key
+// pair to generate or the public key of a pair kept by the client
+type Rekey struct {
+	PrivateKey            string `json:"private_key" binding:"required"`
+	PrivateKeyPassword    string `json:"private_key_password" binding:"required"`
+	KeySize               uint64 `json:"key_size" binding:"omitempty,keysize"`
+	NewPrivateKeyPassword string `json:"new_private_key_password" binding:"omitempty,password"`
+	PublicKey             string `json:"public_key" binding:"omitempty,publickey"`
+}
+
+type TextMetadata struct {
+	Uuid           string     `json:"uuid"`
+	Encrypted      bool       `json:"encrypted"`
+	EncryptionMode string     `json:"encryption_mode,omitempty"`
+	KeySize        uint64     `json:"key_size,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	Owner          string     `json:"owner,omitempty"`
+	RekeyedAt      *time.Time `json:"rekeyed_at,omitempty"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+const (
+	WebhookEventRead             = "text.read"
+	WebhookEventDeleted          = "text.deleted"
+	WebhookEventDecryptionFailed = "text.decryption_failed"
+	WebhookEventRekeyed          = "text.rekeyed"
+)
+
+// WebhookEvents are the event types a subscription can ask for
+var WebhookEvents = []string{WebhookEventRead, WebhookEventDeleted, WebhookEventDecryptionFailed, WebhookEventRekeyed}
+
+// WebhookSubscription receives the events of a text when TextId is set, or of every text of its owner
+type WebhookSubscription struct {
+	Id        string    `json:"id"`
+	Url       string    `json:"url" binding:"required,webhookurl"`
+	Events    []string  `json:"events" binding:"required,min=1,dive,webhookevent"`
+	TextId    string    `json:"text_id,omitempty" binding:"omitempty,uuid"`
+	Owner     string    `json:"owner,omitempty"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEvent is the payload delivered to the subscriptions, Owner is only used to match them
+type WebhookEvent struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	TextId    string    `json:"text_id"`
+	Owner     string    `json:"-"`
+	Principal string    `json:"principal,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// WebhookDelivery is an event waiting to be delivered to a subscription
+type WebhookDelivery struct {
+	Id             string       `json:"id"`
+	SubscriptionId string       `json:"subscription_id"`
+	Event          WebhookEvent `json:"event"`
+	Attempts       int          `json:"attempts"`
+	NextAttempt    time.Time    `json:"next_attempt"`
+	LastError      string       `json:"last_error,omitempty"`
+}
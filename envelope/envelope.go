@@ -0,0 +1,350 @@
+// Package envelope encrypts the stored records at rest. Each record is sealed with its own random data
+// key, which is wrapped by a server master key and stored next to it with the id of that key, so the
+// master keys can be rotated without losing the records written with the previous ones.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"zcelero/apperror"
+	"zcelero/config"
+)
+
+// Version is the format of the records written by Seal
+const Version = 1
+
+// keySize is the size of the master and data keys, both are AES-256 keys
+const keySize = 32
+
+// KeysEnv is the environment variable holding the master keys when no keys file is configured
+const KeysEnv = "AT_REST_KEYS"
+
+// KeyWrapToken marks the records whose data key was generated in a token, it's only unwrapped inside it
+const KeyWrapToken = "token"
+
+var keyIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Record is the document stored for a sealed record
+type Record struct {
+	Envelope   int    `json:"envelope"`
+	KeyId      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Ciphertext []byte `json:"ciphertext"`
+	// KeyWrap is empty for the data keys wrapped by KeyringInterface.Wrap, or KeyWrapToken
+	KeyWrap string `json:"key_wrap,omitempty"`
+}
+
+type KeyringInterface interface {
+	PrimaryKeyId() string
+	Wrap(keyId string, dataKey []byte) ([]byte, error)
+	Unwrap(keyId string, wrappedKey []byte) ([]byte, error)
+}
+
+// TokenKeyringInterface is implemented by the keyrings backed by a token, like a PKCS#11 HSM, that can keep the
+// data keys inside it too. The records are then encrypted and decrypted by the token, and their data keys only
+// leave it wrapped by a master key
+type TokenKeyringInterface interface {
+	KeyringInterface
+	// TokenDataKeys tells whether the new records get their data key from the token
+	TokenDataKeys() bool
+	SealInToken(keyId string, plaintext, additionalData []byte) (wrappedKey []byte, ciphertext []byte, err error)
+	OpenInToken(keyId string, wrappedKey, ciphertext, additionalData []byte) ([]byte, error)
+	RewrapInToken(keyId string, wrappedKey []byte, newKeyId string) ([]byte, error)
+}
+
+type keyring struct {
+	keys    map[string]cipher.AEAD
+	primary string
+}
+
+// NewKeyring wraps the data keys with the master keys, new records use the primary one
+func NewKeyring(keys map[string][]byte, primaryKeyId string) (KeyringInterface, error) {
+	if _, ok := keys[primaryKeyId]; !ok {
+		return nil, fmt.Errorf("master key %q is not in the keyring", primaryKeyId)
+	}
+
+	k := &keyring{keys: map[string]cipher.AEAD{}, primary: primaryKeyId}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key %q must have %d bytes, it has %d", id, keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+
+	return k, nil
+}
+
+// PrimaryKeyId returns the id of the master key wrapping new data keys
+func (k *keyring) PrimaryKeyId() string {
+	return k.primary
+}
+
+// Wrap encrypts the data key with the master key, authenticating the key id with it
+func (k *keyring) Wrap(keyId string, dataKey []byte) ([]byte, error) {
+	aead, ok := k.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %q is not in the keyring", keyId)
+	}
+
+	return seal(aead, dataKey, []byte(keyId))
+}
+
+// Unwrap decrypts a data key wrapped by Wrap
+func (k *keyring) Unwrap(keyId string, wrappedKey []byte) ([]byte, error) {
+	aead, ok := k.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %q is not in the keyring", keyId)
+	}
+
+	return open(aead, wrappedKey, []byte(keyId))
+}
+
+// LoadKeyring reads the master keys from the keys file, or from $AT_REST_KEYS when there is none. It
+// returns nil when the at-rest encryption is disabled
+func LoadKeyring(atRest config.AtRest, getenv func(key string) string) (KeyringInterface, error) {
+	if atRest.KeyId == "" {
+		return nil, nil
+	}
+
+	data := getenv(KeysEnv)
+	if atRest.KeysFile != "" {
+		content, err := os.ReadFile(atRest.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("master keys could not be read: %w", err)
+		}
+		data = string(content)
+	}
+
+	keys, err := ParseKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyring(keys, atRest.KeyId)
+}
+
+// ParseKeys reads the master keys written as "<key id>:<base64 key>", separated by new lines or
+// semicolons. Empty lines and lines starting with # are skipped
+func ParseKeys(data string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, line := range strings.FieldsFunc(data, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(line, ":")
+		id = strings.TrimSpace(id)
+		if !found || !keyIdPattern.MatchString(id) {
+			return nil, fmt.Errorf("master keys must be written as <key id>:<base64 key>, with ids of letters, digits, dots, dashes and underscores")
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("master key %q is repeated", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("master key %q is not valid base64", id)
+		}
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+// Seal encrypts the content of the record with a new data key wrapped by the primary master key. The
+// record id is authenticated with the content, so a sealed record can't be moved to another id
+func Seal(keyring KeyringInterface, recordId string, content []byte) ([]byte, error) {
+	record := Record{Envelope: Version, KeyId: keyring.PrimaryKeyId()}
+	if token, ok := keyring.(TokenKeyringInterface); ok && token.TokenDataKeys() {
+		wrappedKey, ciphertext, err := token.SealInToken(record.KeyId, content, []byte(recordId))
+		if err != nil {
+			return nil, apperror.Wrap(apperror.Internal, "record could not be encrypted by the token", err)
+		}
+		record.KeyWrap, record.WrappedKey, record.Ciphertext = KeyWrapToken, wrappedKey, ciphertext
+
+		return encode(record)
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "data key could not be generated", err)
+	}
+
+	wrappedKey, err := keyring.Wrap(record.KeyId, dataKey)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "data key could not be wrapped", err)
+	}
+	record.WrappedKey = wrappedKey
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "record could not be encrypted", err)
+	}
+	record.Ciphertext, err = seal(aead, content, []byte(recordId))
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "record could not be encrypted", err)
+	}
+
+	return encode(record)
+}
+
+// Open decrypts a record sealed by Seal, data that isn't sealed, like the records written before the
+// encryption was enabled, is returned as it is
+func Open(keyring KeyringInterface, recordId string, data []byte) ([]byte, error) {
+	record, sealed := Parse(data)
+	if !sealed {
+		return data, nil
+	}
+	if err := check(record); err != nil {
+		return nil, err
+	}
+	if record.KeyWrap == KeyWrapToken {
+		token, err := tokenOf(keyring)
+		if err != nil {
+			return nil, err
+		}
+		content, err := token.OpenInToken(record.KeyId, record.WrappedKey, record.Ciphertext, []byte(recordId))
+		if err != nil {
+			return nil, apperror.Wrap(apperror.Internal, "stored record could not be decrypted by the token", err)
+		}
+
+		return content, nil
+	}
+
+	dataKey, err := keyring.Unwrap(record.KeyId, record.WrappedKey)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "data key of the stored record could not be unwrapped", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "stored record could not be decrypted", err)
+	}
+	content, err := open(aead, record.Ciphertext, []byte(recordId))
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "stored record could not be decrypted", err)
+	}
+
+	return content, nil
+}
+
+// Rewrap wraps the data key of a sealed record with the primary master key, keeping its ciphertext, and
+// seals the data that isn't sealed yet. It tells whether the record changed, records already under the
+// primary key are returned as they are
+func Rewrap(keyring KeyringInterface, recordId string, data []byte) ([]byte, bool, error) {
+	record, sealed := Parse(data)
+	if !sealed {
+		sealedData, err := Seal(keyring, recordId, data)
+		return sealedData, err == nil, err
+	}
+	if record.KeyId == keyring.PrimaryKeyId() {
+		return data, false, nil
+	}
+	if err := check(record); err != nil {
+		return nil, false, err
+	}
+
+	if record.KeyWrap == KeyWrapToken {
+		token, err := tokenOf(keyring)
+		if err != nil {
+			return nil, false, err
+		}
+		record.WrappedKey, err = token.RewrapInToken(record.KeyId, record.WrappedKey, keyring.PrimaryKeyId())
+		if err != nil {
+			return nil, false, apperror.Wrap(apperror.Internal, "data key of the stored record could not be rewrapped by the token", err)
+		}
+	} else {
+		dataKey, err := keyring.Unwrap(record.KeyId, record.WrappedKey)
+		if err != nil {
+			return nil, false, apperror.Wrap(apperror.Internal, "data key of the stored record could not be unwrapped", err)
+		}
+		record.WrappedKey, err = keyring.Wrap(keyring.PrimaryKeyId(), dataKey)
+		if err != nil {
+			return nil, false, apperror.Wrap(apperror.Internal, "data key could not be wrapped", err)
+		}
+	}
+	record.KeyId = keyring.PrimaryKeyId()
+
+	rewrapped, err := encode(record)
+	return rewrapped, err == nil, err
+}
+
+// Parse tells whether the data is a sealed record, returning it
+func Parse(data []byte) (Record, bool) {
+	record := Record{}
+	if err := json.Unmarshal(data, &record); err != nil || record.Envelope == 0 {
+		return Record{}, false
+	}
+
+	return record, true
+}
+
+// check rejects the records written by a newer version
+func check(record Record) error {
+	if record.Envelope != Version {
+		return apperror.New(apperror.Internal, fmt.Sprintf("stored record has the unknown envelope version %d", record.Envelope))
+	}
+	if record.KeyWrap != "" && record.KeyWrap != KeyWrapToken {
+		return apperror.New(apperror.Internal, fmt.Sprintf("stored record has the unknown key wrap %q", record.KeyWrap))
+	}
+
+	return nil
+}
+
+// tokenOf returns the token holding the data keys of the records with the KeyWrapToken key wrap
+func tokenOf(keyring KeyringInterface) (TokenKeyringInterface, error) {
+	token, ok := keyring.(TokenKeyringInterface)
+	if !ok {
+		return nil, apperror.New(apperror.Internal, "data key of the stored record is held by a token, a PKCS#11 keyring is needed to open it")
+	}
+
+	return token, nil
+}
+
+func encode(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "record could not be encoded", err)
+	}
+
+	return data, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, which is prepended to the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+}
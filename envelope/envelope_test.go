@@ -0,0 +1,275 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zcelero/apperror"
+	"zcelero/config"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestSealOpen(t *testing.T) {
+	recordId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	content := []byte(`{"Content":"text data","Encrypted":false}`)
+	old, _ := NewKeyring(map[string][]byte{"old": oldKey}, "old")
+	rotated, _ := NewKeyring(map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	withoutOld, _ := NewKeyring(map[string][]byte{"new": newKey}, "new")
+
+	sealed, err := Seal(old, recordId, content)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("text data")) {
+		t.Fatalf("Seal() = %s, the content is in the clear", sealed)
+	}
+	if record, ok := Parse(sealed); !ok || record.KeyId != "old" || record.Envelope != Version {
+		t.Errorf("Parse() = %v, %v", record, ok)
+	}
+
+	tests := []struct {
+		name     string
+		keyring  KeyringInterface
+		recordId string
+		data     []byte
+		want     []byte
+		wantErr  bool
+	}{
+		{name: "Open", keyring: old, recordId: recordId, data: sealed, want: content},
+		{name: "Open after a new primary key", keyring: rotated, recordId: recordId, data: sealed, want: content},
+		{name: "Open without its master key", keyring: withoutOld, recordId: recordId, data: sealed, wantErr: true},
+		{name: "Open under another record id", keyring: old, recordId: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", data: sealed, wantErr: true},
+		{name: "Open a record that isn't sealed", keyring: old, recordId: recordId, data: content, want: content},
+		{name: "Open a tampered record", keyring: old, recordId: recordId, data: bytes.Replace(sealed, []byte(`"ciphertext":"`), []byte(`"ciphertext":"AAAA`), 1), wantErr: true},
+		{name: "Open an unknown version", keyring: old, recordId: recordId, data: []byte(`{"envelope":2,"key_id":"old"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Open(tt.keyring, tt.recordId, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && apperror.CodeOf(err) != apperror.Internal {
+				t.Errorf("Open() error code = %v, want %v", apperror.CodeOf(err), apperror.Internal)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Open() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(oldKey)
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr string
+	}{
+		{name: "Lines with comments", data: "# rotated in 2024\nold:" + encoded + "\n\nnew: " + base64.StdEncoding.EncodeToString(newKey) + "\n", want: []string{"old", "new"}},
+		{name: "Semicolons", data: "old:" + encoded + ";new:" + encoded, want: []string{"old", "new"}},
+		{name: "Empty", data: "", want: []string{}},
+		{name: "Missing id", data: encoded, wantErr: "must be written as"},
+		{name: "Invalid id", data: "old key:" + encoded, wantErr: "must be written as"},
+		{name: "Repeated id", data: "old:" + encoded + ";old:" + encoded, wantErr: "repeated"},
+		{name: "Invalid base64", data: "old:not base64!", wantErr: "not valid base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeys(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseKeys() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeys() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseKeys() = %v, want %v", got, tt.want)
+			}
+			for _, id := range tt.want {
+				if _, ok := got[id]; !ok {
+					t.Errorf("ParseKeys() is missing key %q", id)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "master-keys")
+	os.WriteFile(keysFile, []byte("old:"+base64.StdEncoding.EncodeToString(oldKey)+"\n"), 0600)
+	env := map[string]string{KeysEnv: "new:" + base64.StdEncoding.EncodeToString(newKey) + ";short:" + base64.StdEncoding.EncodeToString([]byte("short"))}
+	tests := []struct {
+		name        string
+		atRest      config.AtRest
+		wantPrimary string
+		wantErr     string
+	}{
+		{name: "Disabled", atRest: config.AtRest{KeysFile: keysFile}},
+		{name: "Keys file", atRest: config.AtRest{KeysFile: keysFile, KeyId: "old"}, wantPrimary: "old"},
+		{name: "Primary key missing from the file", atRest: config.AtRest{KeysFile: keysFile, KeyId: "new"}, wantErr: "not in the keyring"},
+		{name: "Missing keys file", atRest: config.AtRest{KeysFile: filepath.Join(dir, "missing"), KeyId: "old"}, wantErr: "could not be read"},
+		{name: "Keys from environment with a short key", atRest: config.AtRest{KeyId: "new"}, wantErr: "must have 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadKeyring(tt.atRest, func(key string) string { return env[key] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadKeyring() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyring() error = %v", err)
+			}
+			if tt.wantPrimary == "" {
+				if got != nil {
+					t.Errorf("LoadKeyring() = %v, want nil", got)
+				}
+				return
+			}
+			if got.PrimaryKeyId() != tt.wantPrimary {
+				t.Errorf("LoadKeyring().PrimaryKeyId() = %v, want %v", got.PrimaryKeyId(), tt.wantPrimary)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	recordId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	content := []byte(`{"Content":"text data","Encrypted":false}`)
+	old, _ := NewKeyring(map[string][]byte{"old": oldKey}, "old")
+	rotated, _ := NewKeyring(map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	withoutOld, _ := NewKeyring(map[string][]byte{"new": newKey}, "new")
+	sealed, _ := Seal(old, recordId, content)
+	sealedRecord, _ := Parse(sealed)
+
+	tests := []struct {
+		name        string
+		keyring     KeyringInterface
+		data        []byte
+		wantChanged bool
+		wantErr     bool
+	}{
+		{name: "Rewrap to the new primary key", keyring: rotated, data: sealed, wantChanged: true},
+		{name: "Rewrap a record already under the primary key", keyring: old, data: sealed, wantChanged: false},
+		{name: "Rewrap a record that isn't sealed", keyring: rotated, data: content, wantChanged: true},
+		{name: "Rewrap without the old master key", keyring: withoutOld, data: sealed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := Rewrap(tt.keyring, recordId, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rewrap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Rewrap() changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			record, ok := Parse(got)
+			if !ok || record.KeyId != tt.keyring.PrimaryKeyId() {
+				t.Errorf("Rewrap() = %s, want a record under %s", got, tt.keyring.PrimaryKeyId())
+			}
+			if opened, err := Open(withoutOld, recordId, got); tt.keyring == rotated && (err != nil || !bytes.Equal(opened, content)) {
+				t.Errorf("Open() = %s, %v, want %s without the old key", opened, err, content)
+			}
+			if bytes.Equal(tt.data, sealed) && !bytes.Equal(record.Ciphertext, sealedRecord.Ciphertext) {
+				t.Errorf("Rewrap() changed the ciphertext of the record")
+			}
+		})
+	}
+}
+
+// fakeToken plays a token keyring holding the data keys with the software keyring
+type fakeToken struct {
+	KeyringInterface
+}
+
+func (f fakeToken) TokenDataKeys() bool {
+	return true
+}
+
+func (f fakeToken) SealInToken(keyId string, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	dataKey := bytes.Repeat([]byte{3}, keySize)
+	wrappedKey, err := f.Wrap(keyId, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, _ := newAEAD(dataKey)
+	ciphertext, err := seal(aead, plaintext, additionalData)
+
+	return wrappedKey, ciphertext, err
+}
+
+func (f fakeToken) OpenInToken(keyId string, wrappedKey, ciphertext, additionalData []byte) ([]byte, error) {
+	dataKey, err := f.Unwrap(keyId, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, _ := newAEAD(dataKey)
+
+	return open(aead, ciphertext, additionalData)
+}
+
+func (f fakeToken) RewrapInToken(keyId string, wrappedKey []byte, newKeyId string) ([]byte, error) {
+	dataKey, err := f.Unwrap(keyId, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Wrap(newKeyId, dataKey)
+}
+
+func TestTokenDataKeys(t *testing.T) {
+	recordId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	content := []byte(`{"Content":"text data","Encrypted":false}`)
+	old, _ := NewKeyring(map[string][]byte{"old": oldKey}, "old")
+	rotated, _ := NewKeyring(map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	withoutOld, _ := NewKeyring(map[string][]byte{"new": newKey}, "new")
+
+	sealed, err := Seal(fakeToken{old}, recordId, content)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if record, _ := Parse(sealed); record.KeyWrap != KeyWrapToken {
+		t.Errorf("Seal() key wrap = %q, want %q", record.KeyWrap, KeyWrapToken)
+	}
+	if got, err := Open(fakeToken{old}, recordId, sealed); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Open() = %s, %v, want %s", got, err, content)
+	}
+	if _, err := Open(old, recordId, sealed); err == nil || !strings.Contains(err.Error(), "held by a token") {
+		t.Errorf("Open() without the token error = %v, want the record held by a token", err)
+	}
+
+	rewrapped, changed, err := Rewrap(fakeToken{rotated}, recordId, sealed)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap() = %v, %v, want true, nil", changed, err)
+	}
+	if record, _ := Parse(rewrapped); record.KeyId != "new" || record.KeyWrap != KeyWrapToken {
+		t.Errorf("Rewrap() = %s, want a token record under new", rewrapped)
+	}
+	if got, err := Open(fakeToken{withoutOld}, recordId, rewrapped); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Open() = %s, %v, want %s without the old key", got, err, content)
+	}
+
+	unknown := bytes.Replace(sealed, []byte(`"key_wrap":"token"`), []byte(`"key_wrap":"kms"`), 1)
+	if _, err := Open(fakeToken{old}, recordId, unknown); err == nil {
+		t.Errorf("Open() of an unknown key wrap succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"zcelero/apperror"
+	"zcelero/logging"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies the service in the ErrorInfo detail
+const errorDomain = "zcelero"
+
+var grpcCodes = map[apperror.Code]codes.Code{
+	apperror.NotFound:           codes.NotFound,
+	apperror.MethodNotAllowed:   codes.Unimplemented,
+	apperror.InvalidId:          codes.InvalidArgument,
+	apperror.DecryptionFailed:   codes.InvalidArgument,
+	apperror.WrongPassword:      codes.PermissionDenied,
+	apperror.Forbidden:          codes.PermissionDenied,
+	apperror.ValidationFailed:   codes.InvalidArgument,
+	apperror.StorageUnavailable: codes.Unavailable,
+	apperror.ServiceUnavailable: codes.Unavailable,
+	apperror.Internal:           codes.Internal,
+}
+
+// toStatus converts the error into a gRPC status carrying the apperror code as ErrorInfo reason
+func toStatus(ctx context.Context, err error) error {
+	code := apperror.Internal
+	message := "internal server error"
+	var fields []apperror.FieldError
+
+	var appError *apperror.Error
+	if errors.As(err, &appError) {
+		code = appError.Code
+		message = appError.Message
+		fields = appError.Fields
+	} else {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+	}
+
+	grpcCode, found := grpcCodes[code]
+	if !found {
+		grpcCode = codes.Internal
+	}
+
+	st, detailErr := status.New(grpcCode, message).WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: errorDomain})
+	if detailErr != nil {
+		return status.Error(grpcCode, message)
+	}
+
+	if len(fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		if withFields, detailErr := st.WithDetails(badRequest); detailErr == nil {
+			st = withFields
+		}
+	}
+
+	return st.Err()
+}
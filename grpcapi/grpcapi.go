@@ -0,0 +1,268 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/logging"
+	"zcelero/principal"
+	"zcelero/redact"
+	"zcelero/service"
+	"zcelero/textmanagementpb"
+	"zcelero/tracing"
+	"zcelero/validation"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type TextManagementServer struct {
+	textmanagementpb.UnimplementedTextManagementServer
+	TextManagementService service.TextManagementServiceInteface
+	Validator             binding.StructValidator
+}
+
+// Start creates the gRPC server wrapping the same service used by the REST API, options add e.g. TLS credentials
+func Start(textManagementService service.TextManagementServiceInteface, config config.Config, options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(options, grpc.ChainUnaryInterceptor(recovery, trace, requestID, authenticate, accessLog))...)
+	textmanagementpb.RegisterTextManagementServer(server, NewServer(textManagementService, config.KeySizes))
+
+	return server
+}
+
+// NewServer creates the gRPC implementation of the text management service, accepting the keySizes in inserts
+func NewServer(textManagementService service.TextManagementServiceInteface, keySizes []uint64) textmanagementpb.TextManagementServer {
+	return &TextManagementServer{
+		TextManagementService: textManagementService,
+		Validator:             validation.NewValidator(keySizes),
+	}
+}
+
+// recovery answers the calls that panicked with an Internal status instead of stopping the process, the panic
+// value is only logged with the secrets removed. It comes first so the panics of the other interceptors are
+// recovered too
+func recovery(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.FromContext(ctx).Error().Str("method", info.FullMethod).Bytes("stack", debug.Stack()).Msg(redact.String(fmt.Sprint(recovered)))
+			err = toStatus(ctx, apperror.New(apperror.Internal, "internal server error"))
+		}
+	}()
+
+	return handler(ctx, request)
+}
+
+// trace starts a server span for every call, continuing the trace of the traceparent metadata
+func trace(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response any, err error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(info.FullMethod, "/"), oteltrace.WithSpanKind(oteltrace.SpanKindServer), oteltrace.WithAttributes(
+		semconv.RPCSystemGRPC,
+		semconv.RPCMethod(path.Base(info.FullMethod)),
+		semconv.RPCService(path.Dir(strings.TrimPrefix(info.FullMethod, "/"))),
+	))
+	defer func() {
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if err != nil {
+			span.RecordError(err)
+		}
+		// like in the REST API, only failures of the server mark the span as failed
+		switch code {
+		case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss:
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	return handler(ctx, request)
+}
+
+// metadataCarrier reads and writes the trace propagation keys in the gRPC metadata
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// requestID takes the x-request-id metadata, or creates a new id, sends it back in the response header and
+// puts a logger tagged with it, and with the trace id, in the context
+func requestID(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	candidate := ""
+	if values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(logging.RequestIDHeader)); len(values) > 0 {
+		candidate = values[0]
+	}
+	requestId := logging.RequestID(candidate)
+	grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDHeader, requestId))
+
+	logger := logging.FromContext(ctx).With().Str("request_id", requestId)
+	if span := oteltrace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.Str("trace_id", span.TraceID().String())
+	}
+
+	return handler(logging.NewContext(ctx, logger.Logger()), request)
+}
+
+// accessLog writes one structured line for every call, the request messages are left out since they
+// may carry secrets
+func accessLog(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	response, err := handler(ctx, request)
+
+	event := logging.FromContext(ctx).Info().
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("latency", time.Since(start))
+	if p, ok := peer.FromContext(ctx); ok {
+		event = event.Str("peer", p.Addr.String())
+	}
+	event.Msg("rpc answered")
+
+	return response, err
+}
+
+// authenticate puts the subject of the verified client certificate in the context as the principal, along
+// with the client address
+func authenticate(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		ctx = principal.NewClientIPContext(ctx, clientIP(p.Addr))
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if name := principal.FromTLS(&tlsInfo.State); name != "" {
+				ctx = logging.With(principal.NewContext(ctx, name), "principal", name)
+				logging.FromContext(ctx).Debug().Msg("client certificate verified")
+			}
+		}
+	}
+
+	return handler(ctx, request)
+}
+
+// clientIP drops the port from the peer address, other addresses, like the in-process listener, are kept
+func clientIP(addr net.Addr) string {
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+
+	return addr.String()
+}
+
+// Insert validates the request like the REST API does and stores the text
+func (s *TextManagementServer) Insert(ctx context.Context, request *textmanagementpb.InsertRequest) (*textmanagementpb.InsertResponse, error) {
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Insert requested")
+
+	encryption := request.GetEncryption()
+	text := entity.TextManagement{
+		TextData:           request.GetTextData(),
+		Encryption:         &encryption,
+		EncryptionMode:     request.GetEncryptionMode(),
+		KeySize:            request.GetKeySize(),
+		PrivateKeyPassword: request.GetPrivateKeyPassword(),
+	}
+	if err := s.Validator.ValidateStruct(&text); err != nil {
+		logging.FromContext(ctx).Info().Msg(err.Error())
+		return nil, toStatus(ctx, err)
+	}
+
+	response, err := s.TextManagementService.Insert(ctx, text)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Insert finished")
+
+	return &textmanagementpb.InsertResponse{Uuid: response.Uuid, PrivateKey: response.PrivateKey}, nil
+}
+
+// Get returns the stored text, decrypted with the private key when necessary
+func (s *TextManagementServer) Get(ctx context.Context, request *textmanagementpb.GetRequest) (*textmanagementpb.GetResponse, error) {
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Get requested")
+
+	text, err := s.TextManagementService.Get(ctx, request.GetId(), request.GetPrivateKey(), request.GetPrivateKeyPassword())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Get finished")
+
+	return &textmanagementpb.GetResponse{Text: text}, nil
+}
+
+// GetMetadata describes the stored text without decrypting it
+func (s *TextManagementServer) GetMetadata(ctx context.Context, request *textmanagementpb.GetMetadataRequest) (*textmanagementpb.Metadata, error) {
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/GetMetadata requested")
+
+	metadata, err := s.TextManagementService.GetMetadata(ctx, request.GetId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	response := &textmanagementpb.Metadata{
+		Uuid:           metadata.Uuid,
+		Encrypted:      metadata.Encrypted,
+		KeySize:        metadata.KeySize,
+		EncryptionMode: metadata.EncryptionMode,
+		Owner:          metadata.Owner,
+	}
+	if metadata.CreatedAt != nil {
+		response.CreatedAt = timestamppb.New(*metadata.CreatedAt)
+	}
+	if metadata.RekeyedAt != nil {
+		response.RekeyedAt = timestamppb.New(*metadata.RekeyedAt)
+	}
+
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/GetMetadata finished")
+
+	return response, nil
+}
+
+// Delete removes the stored text
+func (s *TextManagementServer) Delete(ctx context.Context, request *textmanagementpb.DeleteRequest) (*emptypb.Empty, error) {
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Delete requested")
+
+	if err := s.TextManagementService.Delete(ctx, request.GetId(), request.GetPrivateKey(), request.GetPrivateKeyPassword()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	logging.FromContext(ctx).Debug().Msg("rpc TextManagement/Delete finished")
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,382 @@
+package grpcapi_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/grpcapi"
+	"zcelero/logging"
+	serviceMock "zcelero/mocks/service"
+	"zcelero/textmanagementpb"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts the gRPC server on an in-process listener and returns a client connected to it
+func dial(t *testing.T, service *serviceMock.TextManagementServiceInteface) textmanagementpb.TextManagementClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpcapi.Start(service, config.Default())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.DialContext() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return textmanagementpb.NewTextManagementClient(conn)
+}
+
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+
+	return ""
+}
+
+func TestTextManagementServer_Insert(t *testing.T) {
+	encryption := true
+	tests := []struct {
+		name         string
+		request      *textmanagementpb.InsertRequest
+		mockBehavior func(s *serviceMock.TextManagementServiceInteface)
+		want         *textmanagementpb.InsertResponse
+		wantCode     codes.Code
+		wantReason   string
+	}{
+		{
+			name: "Insert encrypted text",
+			request: &textmanagementpb.InsertRequest{
+				TextData:           "text data",
+				Encryption:         true,
+				KeySize:            1024,
+				PrivateKeyPassword: "password123",
+			},
+			mockBehavior: func(s *serviceMock.TextManagementServiceInteface) {
+				s.On("Insert", mock.Anything, entity.TextManagement{
+					TextData:           "text data",
+					Encryption:         &encryption,
+					KeySize:            1024,
+					PrivateKeyPassword: "password123",
+				}).Return(entity.TextManagement{Uuid: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", PrivateKey: "private key"}, nil)
+			},
+			want:     &textmanagementpb.InsertResponse{Uuid: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", PrivateKey: "private key"},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Insert passphrase text",
+			request: &textmanagementpb.InsertRequest{
+				TextData:           "text data",
+				Encryption:         true,
+				EncryptionMode:     entity.EncryptionModePassphrase,
+				PrivateKeyPassword: "password123",
+			},
+			mockBehavior: func(s *serviceMock.TextManagementServiceInteface) {
+				s.On("Insert", mock.Anything, entity.TextManagement{
+					TextData:           "text data",
+					Encryption:         &encryption,
+					EncryptionMode:     entity.EncryptionModePassphrase,
+					PrivateKeyPassword: "password123",
+				}).Return(entity.TextManagement{Uuid: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"}, nil)
+			},
+			want:     &textmanagementpb.InsertResponse{Uuid: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Insert with unknown encryption mode",
+			request: &textmanagementpb.InsertRequest{
+				TextData:           "text data",
+				Encryption:         true,
+				EncryptionMode:     "rot13",
+				PrivateKeyPassword: "password123",
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: string(apperror.ValidationFailed),
+		},
+		{
+			name: "Insert invalid text",
+			request: &textmanagementpb.InsertRequest{
+				TextData:   " ",
+				Encryption: false,
+				KeySize:    1024,
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: string(apperror.ValidationFailed),
+		},
+		{
+			name: "Insert with service error",
+			request: &textmanagementpb.InsertRequest{
+				TextData:   "text data",
+				Encryption: false,
+			},
+			mockBehavior: func(s *serviceMock.TextManagementServiceInteface) {
+				s.On("Insert", mock.Anything, mock.AnythingOfType("entity.TextManagement")).Return(entity.TextManagement{}, errors.New("disk exploded"))
+			},
+			wantCode:   codes.Internal,
+			wantReason: string(apperror.Internal),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &serviceMock.TextManagementServiceInteface{}
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(service)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			got, err := dial(t, service).Insert(ctx, tt.request)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("TextManagementServer.Insert() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				if reason := errorReason(err); reason != tt.wantReason {
+					t.Errorf("TextManagementServer.Insert() reason = %v, want %v", reason, tt.wantReason)
+				}
+				if status.Convert(err).Message() == "disk exploded" {
+					t.Error("TextManagementServer.Insert() leaked an internal error message")
+				}
+				return
+			}
+			if got.GetUuid() != tt.want.GetUuid() || got.GetPrivateKey() != tt.want.GetPrivateKey() {
+				t.Errorf("TextManagementServer.Insert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextManagementServer_InsertFieldViolations(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := dial(t, &serviceMock.TextManagementServiceInteface{}).Insert(ctx, &textmanagementpb.InsertRequest{
+		TextData:   "text data",
+		Encryption: true,
+	})
+
+	fields := []string{}
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	if len(fields) != 2 || fields[0] != "key_size" || fields[1] != "private_key_password" {
+		t.Errorf("TextManagementServer.Insert() field violations = %v, want key_size and private_key_password", fields)
+	}
+}
+
+func TestTextManagementServer_Get(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	tests := []struct {
+		name       string
+		serviceErr error
+		want       string
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{
+			name:     "Get text",
+			want:     "message",
+			wantCode: codes.OK,
+		},
+		{
+			name:       "Get text with wrong password",
+			serviceErr: apperror.New(apperror.WrongPassword, "private_key_password is incorrect"),
+			wantCode:   codes.PermissionDenied,
+			wantReason: string(apperror.WrongPassword),
+		},
+		{
+			name:       "Get missing text",
+			serviceErr: apperror.New(apperror.NotFound, "text not found"),
+			wantCode:   codes.NotFound,
+			wantReason: string(apperror.NotFound),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &serviceMock.TextManagementServiceInteface{}
+			service.On("Get", mock.Anything, uuid, "private key", "password123").Return(tt.want, tt.serviceErr)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			got, err := dial(t, service).Get(ctx, &textmanagementpb.GetRequest{
+				Id:                 uuid,
+				PrivateKey:         "private key",
+				PrivateKeyPassword: "password123",
+			})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("TextManagementServer.Get() error = %v, want code %v", err, tt.wantCode)
+			}
+			if reason := errorReason(err); reason != tt.wantReason {
+				t.Errorf("TextManagementServer.Get() reason = %v, want %v", reason, tt.wantReason)
+			}
+			if got.GetText() != tt.want {
+				t.Errorf("TextManagementServer.Get() = %v, want %v", got.GetText(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTextManagementServer_GetMetadata(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	createdAt := time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+	rekeyedAt := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	passphraseUuid := "2f13ed58-afc9-477a-bf0d-c90eb1b7db90"
+
+	service := &serviceMock.TextManagementServiceInteface{}
+	service.On("GetMetadata", mock.Anything, uuid).Return(entity.TextMetadata{Uuid: uuid, Encrypted: true, EncryptionMode: entity.EncryptionModeRSA, KeySize: 2048, CreatedAt: &createdAt, Owner: "CN=alice", RekeyedAt: &rekeyedAt}, nil)
+	service.On("GetMetadata", mock.Anything, passphraseUuid).Return(entity.TextMetadata{Uuid: passphraseUuid, Encrypted: true, EncryptionMode: entity.EncryptionModePassphrase, CreatedAt: &createdAt}, nil)
+	service.On("GetMetadata", mock.Anything, "invalid").Return(entity.TextMetadata{}, apperror.New(apperror.InvalidId, "id must be a valid uuid"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := dial(t, service)
+
+	got, err := client.GetMetadata(ctx, &textmanagementpb.GetMetadataRequest{Id: uuid})
+	if err != nil {
+		t.Fatalf("TextManagementServer.GetMetadata() error = %v", err)
+	}
+	if got.GetUuid() != uuid || !got.GetEncrypted() || got.GetEncryptionMode() != entity.EncryptionModeRSA || got.GetKeySize() != 2048 || !got.GetCreatedAt().AsTime().Equal(createdAt) {
+		t.Errorf("TextManagementServer.GetMetadata() = %v", got)
+	}
+	if got.GetOwner() != "CN=alice" || !got.GetRekeyedAt().AsTime().Equal(rekeyedAt) {
+		t.Errorf("TextManagementServer.GetMetadata() = %v, want the owner and the rekey time", got)
+	}
+
+	got, err = client.GetMetadata(ctx, &textmanagementpb.GetMetadataRequest{Id: passphraseUuid})
+	if err != nil {
+		t.Fatalf("TextManagementServer.GetMetadata() error = %v", err)
+	}
+	if got.GetEncryptionMode() != entity.EncryptionModePassphrase || got.GetKeySize() != 0 || got.GetOwner() != "" || got.GetRekeyedAt() != nil {
+		t.Errorf("TextManagementServer.GetMetadata() = %v, want a passphrase text never rekeyed", got)
+	}
+
+	_, err = client.GetMetadata(ctx, &textmanagementpb.GetMetadataRequest{Id: "invalid"})
+	if status.Code(err) != codes.InvalidArgument || errorReason(err) != string(apperror.InvalidId) {
+		t.Errorf("TextManagementServer.GetMetadata() error = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestTextManagementServer_Delete(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+
+	service := &serviceMock.TextManagementServiceInteface{}
+	service.On("Delete", mock.Anything, uuid, "private key", "password123").Return(nil).Once()
+	service.On("Delete", mock.Anything, uuid, "", "").Return(apperror.New(apperror.Forbidden, "the private key and password of the text, or the client certificate that stored it, are required to remove it"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := dial(t, service)
+
+	if _, err := client.Delete(ctx, &textmanagementpb.DeleteRequest{Id: uuid, PrivateKey: "private key", PrivateKeyPassword: "password123"}); err != nil {
+		t.Fatalf("TextManagementServer.Delete() error = %v", err)
+	}
+
+	_, err := client.Delete(ctx, &textmanagementpb.DeleteRequest{Id: uuid})
+	if status.Code(err) != codes.PermissionDenied || errorReason(err) != string(apperror.Forbidden) {
+		t.Errorf("TextManagementServer.Delete() error = %v, want %v", err, codes.PermissionDenied)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	output := &bytes.Buffer{}
+	logger := log.Logger
+	log.Logger = zerolog.New(output)
+	t.Cleanup(func() { log.Logger = logger })
+
+	service := &serviceMock.TextManagementServiceInteface{}
+	service.On("GetMetadata", mock.Anything, uuid).Run(func(args mock.Arguments) {
+		panic("private_key_password=password123")
+	})
+	service.On("Delete", mock.Anything, uuid, "", "").Return(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := dial(t, service)
+
+	_, err := client.GetMetadata(ctx, &textmanagementpb.GetMetadataRequest{Id: uuid})
+	if status.Code(err) != codes.Internal || errorReason(err) != string(apperror.Internal) {
+		t.Errorf("TextManagementServer.GetMetadata() error = %v, want %v", err, codes.Internal)
+	}
+	if !strings.Contains(output.String(), "private_key_password=") || strings.Contains(output.String(), "password123") {
+		t.Errorf("panic log = %s, want the password redacted", output)
+	}
+
+	// the server keeps answering
+	if _, err := client.Delete(ctx, &textmanagementpb.DeleteRequest{Id: uuid}); err != nil {
+		t.Errorf("TextManagementServer.Delete() error = %v", err)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	uuid := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	tests := []struct {
+		name      string
+		requestId string
+		want      func(id string) bool
+	}{
+		{
+			name:      "Client request id",
+			requestId: "checkout-42",
+			want:      func(id string) bool { return id == "checkout-42" },
+		},
+		{
+			name: "Generated request id",
+			want: func(id string) bool { return len(id) == 36 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &serviceMock.TextManagementServiceInteface{}
+			service.On("Delete", mock.MatchedBy(func(ctx context.Context) bool {
+				// the service receives the logger tagged with the request id
+				return logging.FromContext(ctx) != &log.Logger
+			}), uuid, "", "").Return(nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if tt.requestId != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", tt.requestId)
+			}
+			header := metadata.MD{}
+
+			if _, err := dial(t, service).Delete(ctx, &textmanagementpb.DeleteRequest{Id: uuid}, grpc.Header(&header)); err != nil {
+				t.Fatalf("TextManagementServer.Delete() error = %v", err)
+			}
+			got := header.Get("x-request-id")
+			if len(got) != 1 || !tt.want(got[0]) {
+				t.Errorf("x-request-id header = %v", got)
+			}
+		})
+	}
+}
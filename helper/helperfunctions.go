@@ -2,6 +2,8 @@ package helper
 
 import (
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +13,12 @@ type HelperInterface interface {
 	CreateFile(filePath string) (*os.File, error)
 	ReadFile(filePath string) ([]byte, error)
 	WriteFile(file *os.File, content string) (n int, err error)
+	AppendFile(filePath string, content string) error
+	CreateDir(dirPath string) error
+	ReadDir(dirPath string) ([]os.DirEntry, error)
+	RemoveFile(filePath string) error
+	ReplaceFile(filePath string, content string) error
+	Now() time.Time
 }
 
 type helperStruct struct{}
@@ -24,9 +32,9 @@ func (h *helperStruct) GenerateUuid() string {
 	return uuid.New().String()
 }
 
-// CreateFile creates a file in the desired path with the desired name
+// CreateFile creates a file in the desired path with the desired name, readable only by its owner
 func (h *helperStruct) CreateFile(filePath string) (*os.File, error) {
-	return os.Create(filePath)
+	return os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 }
 
 // ReadFile reads the desired file
@@ -38,3 +46,70 @@ func (h *helperStruct) ReadFile(filePath string) ([]byte, error) {
 func (h *helperStruct) WriteFile(file *os.File, content string) (n int, err error) {
 	return file.WriteString(content)
 }
+
+// AppendFile writes the content at the end of the file, creating it when missing, and flushes it to disk
+func (h *helperStruct) AppendFile(filePath string, content string) error {
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// CreateDir creates the directory and any missing parent
+func (h *helperStruct) CreateDir(dirPath string) error {
+	return os.MkdirAll(dirPath, 0700)
+}
+
+// ReadDir lists the entries of the desired directory
+func (h *helperStruct) ReadDir(dirPath string) ([]os.DirEntry, error) {
+	return os.ReadDir(dirPath)
+}
+
+// RemoveFile deletes the desired file
+func (h *helperStruct) RemoveFile(filePath string) error {
+	return os.Remove(filePath)
+}
+
+// ReplaceFile writes the content into a temporary file next to the existing one and renames it over it, so
+// readers find either the old or the new content. It fails with fs.ErrNotExist when there is no file to replace
+func (h *helperStruct) ReplaceFile(filePath string, content string) error {
+	if _, err := os.Stat(filePath); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+// Now returns the current time in UTC
+func (h *helperStruct) Now() time.Time {
+	return time.Now().UTC()
+}
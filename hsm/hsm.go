@@ -0,0 +1,19 @@
+// Package hsm keeps the master keys of the at-rest encryption in a PKCS#11 token, like an HSM or SoftHSM, so
+// they never exist in the process memory: the data keys are wrapped and unwrapped by the token. Optionally the
+// data keys are generated in the token too, and the texts are encrypted and decrypted by it.
+//
+// PKCS#11 modules are C libraries, so the keyring is only available in the builds with cgo.
+package hsm
+
+import "zcelero/envelope"
+
+// PinEnv is the environment variable holding the PIN of the token user
+const PinEnv = "AT_REST_PKCS11_PIN"
+
+// keySize is the size of the AES data keys generated in the token
+const keySize = 32
+
+type KeyringInterface interface {
+	envelope.TokenKeyringInterface
+	Close() error
+}
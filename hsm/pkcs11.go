@@ -0,0 +1,338 @@
+//go:build cgo
+
+package hsm
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"zcelero/config"
+
+	"github.com/miekg/pkcs11"
+)
+
+// sessions is the number of PKCS#11 sessions kept open, each one runs one operation at a time
+const sessions = 4
+
+// nonceSize and tagBits match the AES-GCM of the envelope package, so records wrapped by a software key
+// imported into the token can still be opened
+const (
+	nonceSize = 12
+	tagBits   = 128
+)
+
+type pkcs11Keyring struct {
+	ctx      *pkcs11.Ctx
+	slot     uint
+	sessions chan pkcs11.SessionHandle
+	primary  string
+	dataKeys bool
+	// keys caches the handles of the master keys found in the token
+	keys  map[string]pkcs11.ObjectHandle
+	mutex sync.Mutex
+}
+
+// NewKeyring logs in the token labeled settings.TokenLabel of the PKCS#11 module as the user with the pin. The
+// master keys are the AES keys of the token, their key id being the label, and primaryKeyId must be one of them
+func NewKeyring(settings config.PKCS11, primaryKeyId string, pin string) (KeyringInterface, error) {
+	ctx := pkcs11.New(settings.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("PKCS#11 module %s could not be loaded", settings.Module)
+	}
+	if err := ctx.Initialize(); err != nil && !isError(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("PKCS#11 module %s could not be initialized: %w", settings.Module, err)
+	}
+
+	k := &pkcs11Keyring{
+		ctx:      ctx,
+		sessions: make(chan pkcs11.SessionHandle, sessions),
+		primary:  primaryKeyId,
+		dataKeys: settings.DataKeys,
+		keys:     map[string]pkcs11.ObjectHandle{},
+	}
+	if err := k.open(settings.TokenLabel, pin); err != nil {
+		k.Close()
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func (k *pkcs11Keyring) open(tokenLabel, pin string) error {
+	slots, err := k.ctx.GetSlotList(true)
+	if err != nil {
+		return fmt.Errorf("PKCS#11 slots could not be listed: %w", err)
+	}
+	found := false
+	for _, slot := range slots {
+		info, err := k.ctx.GetTokenInfo(slot)
+		if err == nil && info.Label == tokenLabel {
+			k.slot, found = slot, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("PKCS#11 token %q was not found", tokenLabel)
+	}
+
+	// the sessions are read-only, the data keys are session objects that can't change the token
+	for i := 0; i < sessions; i++ {
+		session, err := k.ctx.OpenSession(k.slot, pkcs11.CKF_SERIAL_SESSION)
+		if err != nil {
+			return fmt.Errorf("PKCS#11 session could not be opened: %w", err)
+		}
+		k.sessions <- session
+	}
+
+	// the login is shared by every session of the application
+	session := <-k.sessions
+	err = k.ctx.Login(session, pkcs11.CKU_USER, pin)
+	k.sessions <- session
+	if err != nil && !isError(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		return fmt.Errorf("PKCS#11 token %q login failed: %w", tokenLabel, err)
+	}
+
+	return k.withSession(func(session pkcs11.SessionHandle) error {
+		_, err := k.key(session, k.primary)
+		return err
+	})
+}
+
+// Close logs out of the token and unloads the module
+func (k *pkcs11Keyring) Close() error {
+	var err error
+	if len(k.sessions) > 0 {
+		err = k.ctx.CloseAllSessions(k.slot)
+	}
+	if finalizeErr := k.ctx.Finalize(); err == nil {
+		err = finalizeErr
+	}
+	k.ctx.Destroy()
+
+	return err
+}
+
+// PrimaryKeyId returns the label of the master key wrapping new data keys
+func (k *pkcs11Keyring) PrimaryKeyId() string {
+	return k.primary
+}
+
+// TokenDataKeys tells whether new data keys are generated in the token
+func (k *pkcs11Keyring) TokenDataKeys() bool {
+	return k.dataKeys
+}
+
+// Wrap encrypts the data key with the master key inside the token, authenticating the key id with it
+func (k *pkcs11Keyring) Wrap(keyId string, dataKey []byte) (wrappedKey []byte, err error) {
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		masterKey, err := k.key(session, keyId)
+		if err != nil {
+			return err
+		}
+		wrappedKey, err = k.encrypt(session, masterKey, dataKey, []byte(keyId))
+		return err
+	})
+
+	return wrappedKey, err
+}
+
+// Unwrap decrypts a data key wrapped by Wrap inside the token
+func (k *pkcs11Keyring) Unwrap(keyId string, wrappedKey []byte) (dataKey []byte, err error) {
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		masterKey, err := k.key(session, keyId)
+		if err != nil {
+			return err
+		}
+		dataKey, err = k.decrypt(session, masterKey, wrappedKey, []byte(keyId))
+		return err
+	})
+
+	return dataKey, err
+}
+
+// SealInToken generates a data key in the token and encrypts the plaintext with it, the data key only leaves
+// the token wrapped by the master key
+func (k *pkcs11Keyring) SealInToken(keyId string, plaintext, additionalData []byte) (wrappedKey []byte, ciphertext []byte, err error) {
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		masterKey, err := k.key(session, keyId)
+		if err != nil {
+			return err
+		}
+
+		mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)}
+		dataKey, err := k.ctx.GenerateKey(session, mechanism, dataKeyTemplate(true))
+		if err != nil {
+			return fmt.Errorf("data key could not be generated in the token: %w", err)
+		}
+		defer k.ctx.DestroyObject(session, dataKey)
+
+		wrappedKey, err = k.ctx.WrapKey(session, keyWrapMechanism(), masterKey, dataKey)
+		if err != nil {
+			return fmt.Errorf("data key could not be wrapped in the token: %w", err)
+		}
+		ciphertext, err = k.encrypt(session, dataKey, plaintext, additionalData)
+		return err
+	})
+
+	return wrappedKey, ciphertext, err
+}
+
+// OpenInToken unwraps the data key inside the token and decrypts the ciphertext with it
+func (k *pkcs11Keyring) OpenInToken(keyId string, wrappedKey, ciphertext, additionalData []byte) (plaintext []byte, err error) {
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		masterKey, err := k.key(session, keyId)
+		if err != nil {
+			return err
+		}
+
+		dataKey, err := k.ctx.UnwrapKey(session, keyWrapMechanism(), masterKey, wrappedKey, dataKeyTemplate(false))
+		if err != nil {
+			return fmt.Errorf("data key could not be unwrapped in the token: %w", err)
+		}
+		defer k.ctx.DestroyObject(session, dataKey)
+
+		plaintext, err = k.decrypt(session, dataKey, ciphertext, additionalData)
+		return err
+	})
+
+	return plaintext, err
+}
+
+// RewrapInToken unwraps the data key with the master key keyId and wraps it with newKeyId, inside the token
+func (k *pkcs11Keyring) RewrapInToken(keyId string, wrappedKey []byte, newKeyId string) (rewrappedKey []byte, err error) {
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		masterKey, err := k.key(session, keyId)
+		if err != nil {
+			return err
+		}
+		newMasterKey, err := k.key(session, newKeyId)
+		if err != nil {
+			return err
+		}
+
+		dataKey, err := k.ctx.UnwrapKey(session, keyWrapMechanism(), masterKey, wrappedKey, dataKeyTemplate(false))
+		if err != nil {
+			return fmt.Errorf("data key could not be unwrapped in the token: %w", err)
+		}
+		defer k.ctx.DestroyObject(session, dataKey)
+
+		rewrappedKey, err = k.ctx.WrapKey(session, keyWrapMechanism(), newMasterKey, dataKey)
+		if err != nil {
+			return fmt.Errorf("data key could not be wrapped in the token: %w", err)
+		}
+		return nil
+	})
+
+	return rewrappedKey, err
+}
+
+// withSession runs the operation in one of the sessions, waiting for a free one
+func (k *pkcs11Keyring) withSession(operation func(session pkcs11.SessionHandle) error) error {
+	session := <-k.sessions
+	defer func() { k.sessions <- session }()
+
+	return operation(session)
+}
+
+// key finds the AES master key labeled keyId
+func (k *pkcs11Keyring) key(session pkcs11.SessionHandle, keyId string) (pkcs11.ObjectHandle, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if key, ok := k.keys[keyId]; ok {
+		return key, nil
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyId),
+	}
+	if err := k.ctx.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("master key %q could not be searched in the token: %w", keyId, err)
+	}
+	keys, _, err := k.ctx.FindObjects(session, 2)
+	k.ctx.FindObjectsFinal(session)
+	if err != nil {
+		return 0, fmt.Errorf("master key %q could not be searched in the token: %w", keyId, err)
+	}
+	switch len(keys) {
+	case 0:
+		return 0, fmt.Errorf("master key %q is not in the token", keyId)
+	case 1:
+		k.keys[keyId] = keys[0]
+		return keys[0], nil
+	default:
+		return 0, fmt.Errorf("master key label %q is used by more than one key of the token", keyId)
+	}
+}
+
+// encrypt runs AES-GCM in the token with a random nonce, which is prepended to the ciphertext
+func (k *pkcs11Keyring) encrypt(session pkcs11.SessionHandle, key pkcs11.ObjectHandle, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	params := pkcs11.NewGCMParams(nonce, additionalData, tagBits)
+	defer params.Free()
+	if err := k.ctx.EncryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, key); err != nil {
+		return nil, fmt.Errorf("token encryption could not start: %w", err)
+	}
+	ciphertext, err := k.ctx.Encrypt(session, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("token encryption failed: %w", err)
+	}
+
+	return append(nonce, ciphertext...), nil
+}
+
+func (k *pkcs11Keyring) decrypt(session pkcs11.SessionHandle, key pkcs11.ObjectHandle, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	params := pkcs11.NewGCMParams(ciphertext[:nonceSize], additionalData, tagBits)
+	defer params.Free()
+	if err := k.ctx.DecryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, key); err != nil {
+		return nil, fmt.Errorf("token decryption could not start: %w", err)
+	}
+	plaintext, err := k.ctx.Decrypt(session, ciphertext[nonceSize:])
+	if err != nil {
+		return nil, fmt.Errorf("token decryption failed: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// keyWrapMechanism is the AES key wrap with padding of RFC 5649, supported by the tokens to export keys
+func keyWrapMechanism() []*pkcs11.Mechanism {
+	return []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP_PAD, nil)}
+}
+
+// dataKeyTemplate describes the data keys: session objects whose value can't be read, only exported wrapped.
+// The length is only given when the key is generated, an unwrapped key gets the length of the wrapped one
+func dataKeyTemplate(generate bool) []*pkcs11.Attribute {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+	}
+	if generate {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, keySize))
+	}
+
+	return template
+}
+
+func isError(err error, code uint) bool {
+	var pkcs11Err pkcs11.Error
+	return errors.As(err, &pkcs11Err) && uint(pkcs11Err) == code
+}
@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package hsm
+
+import (
+	"fmt"
+	"zcelero/config"
+)
+
+// NewKeyring fails in the builds without cgo, the PKCS#11 modules can't be loaded
+func NewKeyring(settings config.PKCS11, primaryKeyId string, pin string) (KeyringInterface, error) {
+	return nil, fmt.Errorf("PKCS#11 module %s can't be loaded, zcelero must be built with CGO_ENABLED=1 to use it", settings.Module)
+}
@@ -0,0 +1,177 @@
+//go:build cgo
+
+package hsm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"zcelero/config"
+	"zcelero/envelope"
+
+	"github.com/miekg/pkcs11"
+)
+
+const (
+	testTokenLabel = "zcelero-test"
+	testPin        = "1234"
+)
+
+// softHSMModules are the usual places of the SoftHSM library, $SOFTHSM2_MODULE takes precedence
+var softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+// newSoftHSM initializes a SoftHSM token in a temporary folder with an AES master key for each label
+func newSoftHSM(t *testing.T, labels ...string) string {
+	t.Helper()
+
+	module := os.Getenv("SOFTHSM2_MODULE")
+	for _, candidate := range softHSMModules {
+		if _, err := os.Stat(candidate); module == "" && err == nil {
+			module = candidate
+		}
+	}
+	if module == "" {
+		t.Skip("SoftHSM is not installed, set $SOFTHSM2_MODULE to run the PKCS#11 tests")
+	}
+
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "tokens"), 0700)
+	conf := filepath.Join(dir, "softhsm2.conf")
+	os.WriteFile(conf, []byte("directories.tokendir = "+filepath.Join(dir, "tokens")+"\nobjectstore.backend = file\n"), 0600)
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		t.Fatalf("loading %s failed", module)
+	}
+	defer ctx.Destroy()
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer ctx.Finalize()
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("GetSlotList() = %v, %v", slots, err)
+	}
+	if err := ctx.InitToken(slots[0], "so-pin", testTokenLabel); err != nil {
+		t.Fatalf("InitToken() error = %v", err)
+	}
+	// SoftHSM moves the initialized token to a new slot
+	slots, _ = ctx.GetSlotList(true)
+	slot := slots[0]
+	for _, candidate := range slots {
+		if info, err := ctx.GetTokenInfo(candidate); err == nil && info.Label == testTokenLabel {
+			slot = candidate
+		}
+	}
+
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("OpenSession() error = %v", err)
+	}
+	defer ctx.CloseSession(session)
+	ctx.Login(session, pkcs11.CKU_SO, "so-pin")
+	if err := ctx.InitPIN(session, testPin); err != nil {
+		t.Fatalf("InitPIN() error = %v", err)
+	}
+	ctx.Logout(session)
+	ctx.Login(session, pkcs11.CKU_USER, testPin)
+	defer ctx.Logout(session)
+
+	for _, label := range labels {
+		_, err := ctx.GenerateKey(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)}, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_WRAP, true),
+			pkcs11.NewAttribute(pkcs11.CKA_UNWRAP, true),
+		})
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) error = %v", label, err)
+		}
+	}
+
+	return module
+}
+
+func TestNewKeyring(t *testing.T) {
+	module := newSoftHSM(t, "2024-01")
+
+	tests := []struct {
+		name     string
+		settings config.PKCS11
+		keyId    string
+		pin      string
+		wantErr  bool
+	}{
+		{name: "Open the token", settings: config.PKCS11{Module: module, TokenLabel: testTokenLabel}, keyId: "2024-01", pin: testPin},
+		{name: "Wrong PIN", settings: config.PKCS11{Module: module, TokenLabel: testTokenLabel}, keyId: "2024-01", pin: "0000", wantErr: true},
+		{name: "Missing master key", settings: config.PKCS11{Module: module, TokenLabel: testTokenLabel}, keyId: "2024-06", pin: testPin, wantErr: true},
+		{name: "Missing token", settings: config.PKCS11{Module: module, TokenLabel: "other"}, keyId: "2024-01", pin: testPin, wantErr: true},
+		{name: "Missing module", settings: config.PKCS11{Module: "/missing/libpkcs11.so", TokenLabel: testTokenLabel}, keyId: "2024-01", pin: testPin, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tt.settings, tt.keyId, tt.pin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				keyring.Close()
+			}
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	module := newSoftHSM(t, "2024-01", "2024-06")
+	recordId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	content := []byte(`{"Content":"text data","Encrypted":false}`)
+
+	for _, dataKeys := range []bool{false, true} {
+		old, err := NewKeyring(config.PKCS11{Module: module, TokenLabel: testTokenLabel, DataKeys: dataKeys}, "2024-01", testPin)
+		if err != nil {
+			t.Fatalf("NewKeyring() error = %v", err)
+		}
+		sealed, err := envelope.Seal(old, recordId, content)
+		old.Close()
+		if err != nil {
+			t.Fatalf("Seal() error = %v", err)
+		}
+		if record, _ := envelope.Parse(sealed); (record.KeyWrap == envelope.KeyWrapToken) != dataKeys {
+			t.Errorf("Seal() key wrap = %q with data_keys %v", record.KeyWrap, dataKeys)
+		}
+
+		rotated, err := NewKeyring(config.PKCS11{Module: module, TokenLabel: testTokenLabel}, "2024-06", testPin)
+		if err != nil {
+			t.Fatalf("NewKeyring() error = %v", err)
+		}
+		rewrapped, changed, err := envelope.Rewrap(rotated, recordId, sealed)
+		if err != nil || !changed {
+			t.Fatalf("Rewrap() = %v, %v with data_keys %v", changed, err, dataKeys)
+		}
+		for _, data := range [][]byte{sealed, rewrapped} {
+			if got, err := envelope.Open(rotated, recordId, data); err != nil || !bytes.Equal(got, content) {
+				t.Errorf("Open() = %s, %v with data_keys %v, want %s", got, err, dataKeys, content)
+			}
+		}
+		if _, err := envelope.Open(rotated, "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", rewrapped); err == nil {
+			t.Errorf("Open() under another record id succeeded with data_keys %v", dataKeys)
+		}
+		rotated.Close()
+	}
+}
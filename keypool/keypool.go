@@ -0,0 +1,165 @@
+package keypool
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+	"zcelero/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+type KeyPoolInterface interface {
+	Get(keySize uint64) (*rsa.PrivateKey, error)
+	Depth(keySize uint64) int
+	Stop()
+}
+
+type keyPoolStruct struct {
+	keySizes   []uint64
+	keys       map[uint64]chan *rsa.PrivateKey
+	randReader io.Reader
+	wake       chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewKeyPool starts the workers that keep up to size keys ready for each of the key sizes,
+// negative sizes and worker counts disable the pool
+func NewKeyPool(keySizes []uint64, size int, workers int) KeyPoolInterface {
+	if size < 0 || workers < 0 {
+		log.Warn().Int("size", size).Int("workers", workers).Msg("Negative key pool settings, disabling the key pool")
+		size, workers = 0, 0
+	}
+
+	pool := &keyPoolStruct{
+		keySizes:   append([]uint64(nil), keySizes...),
+		keys:       make(map[uint64]chan *rsa.PrivateKey),
+		randReader: rand.Reader,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+
+	for _, keySize := range pool.keySizes {
+		pool.keys[keySize] = make(chan *rsa.PrivateKey, size)
+		pool.publishDepth(keySize)
+	}
+
+	if size <= 0 {
+		return pool
+	}
+
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go pool.refill()
+	}
+
+	return pool
+}
+
+// Get returns a pre-generated key, generating one synchronously if the pool is empty
+func (k *keyPoolStruct) Get(keySize uint64) (*rsa.PrivateKey, error) {
+	keys, supported := k.keys[keySize]
+	if supported {
+		select {
+		case key := <-keys:
+			k.publishDepth(keySize)
+			k.signalRefill()
+			return key, nil
+		default:
+			log.Debug().Uint64("key_size", keySize).Msg("Key pool empty, generating key synchronously")
+			k.signalRefill()
+		}
+	}
+
+	return k.generate(keySize)
+}
+
+// Depth returns how many keys of the desired size are ready
+func (k *keyPoolStruct) Depth(keySize uint64) int {
+	return len(k.keys[keySize])
+}
+
+// Stop terminates the refill workers and waits for them to finish
+func (k *keyPoolStruct) Stop() {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
+	k.wg.Wait()
+}
+
+func (k *keyPoolStruct) refill() {
+	defer k.wg.Done()
+
+	for {
+		keySize, found := k.nextKeySize()
+		if !found {
+			select {
+			case <-k.stop:
+				return
+			case <-k.wake:
+				continue
+			}
+		}
+
+		key, err := k.generate(keySize)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			continue
+		}
+
+		select {
+		case <-k.stop:
+			return
+		case k.keys[keySize] <- key:
+			k.publishDepth(keySize)
+		default:
+			// another worker filled the slot first
+		}
+	}
+}
+
+// nextKeySize picks the key size whose pool is proportionally the emptiest
+func (k *keyPoolStruct) nextKeySize() (uint64, bool) {
+	var selected uint64
+	found := false
+	lowest := 0
+
+	for _, keySize := range k.keySizes {
+		keys := k.keys[keySize]
+		if len(keys) >= cap(keys) {
+			continue
+		}
+		if !found || len(keys) < lowest {
+			selected = keySize
+			lowest = len(keys)
+			found = true
+		}
+	}
+
+	return selected, found
+}
+
+// generate creates a key, observing how long it took
+func (k *keyPoolStruct) generate(keySize uint64) (*rsa.PrivateKey, error) {
+	start := time.Now()
+	key, err := rsa.GenerateKey(k.randReader, int(keySize))
+	metrics.KeyGenerationDuration.WithLabelValues(strconv.FormatUint(keySize, 10)).Observe(metrics.Since(start))
+
+	return key, err
+}
+
+func (k *keyPoolStruct) signalRefill() {
+	select {
+	case k.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (k *keyPoolStruct) publishDepth(keySize uint64) {
+	metrics.KeyPoolDepth.WithLabelValues(strconv.FormatUint(keySize, 10)).Set(float64(len(k.keys[keySize])))
+}
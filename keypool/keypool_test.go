@@ -0,0 +1,121 @@
+package keypool
+
+import (
+	"testing"
+	"time"
+	"zcelero/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func waitForDepth(t *testing.T, pool KeyPoolInterface, keySize uint64, depth int) {
+	deadline := time.Now().Add(30 * time.Second)
+	for pool.Depth(keySize) < depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("key pool depth for %d = %d, want %d", keySize, pool.Depth(keySize), depth)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_keyPoolStruct_Get(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		workers int
+		keySize uint64
+		wantErr bool
+	}{
+		{
+			name:    "Get key from warm pool",
+			size:    2,
+			workers: 1,
+			keySize: 1024,
+			wantErr: false,
+		},
+		{
+			name:    "Get key from disabled pool",
+			size:    0,
+			workers: 1,
+			keySize: 1024,
+			wantErr: false,
+		},
+		{
+			name:    "Get key from pool with negative settings",
+			size:    -1,
+			workers: -1,
+			keySize: 1024,
+			wantErr: false,
+		},
+		{
+			name:    "Get key with unsupported size",
+			size:    1,
+			workers: 1,
+			keySize: 1536,
+			wantErr: false,
+		},
+		{
+			name:    "Get key with invalid size",
+			size:    1,
+			workers: 1,
+			keySize: 1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewKeyPool([]uint64{1024}, tt.size, tt.workers)
+			defer pool.Stop()
+
+			if tt.size > 0 {
+				waitForDepth(t, pool, 1024, tt.size)
+			}
+
+			got, err := pool.Get(tt.keySize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("keyPoolStruct.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.N.BitLen() != int(tt.keySize) {
+				t.Errorf("keyPoolStruct.Get() key size = %d, want %d", got.N.BitLen(), tt.keySize)
+			}
+		})
+	}
+}
+
+func Test_keyPoolStruct_Refill(t *testing.T) {
+	pool := NewKeyPool([]uint64{1024}, 2, 2)
+	defer pool.Stop()
+
+	waitForDepth(t, pool, 1024, 2)
+
+	pool.Get(1024)
+	pool.Get(1024)
+
+	waitForDepth(t, pool, 1024, 2)
+
+	if got := testutil.ToFloat64(metrics.KeyPoolDepth.WithLabelValues("1024")); got != 2 {
+		t.Errorf("zcelero_key_pool_depth metric = %v, want 2", got)
+	}
+}
+
+func Test_keyPoolStruct_Stop(t *testing.T) {
+	pool := NewKeyPool([]uint64{1024}, 1, 3)
+	waitForDepth(t, pool, 1024, 1)
+
+	done := make(chan struct{})
+	go func() {
+		pool.Stop()
+		pool.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("keyPoolStruct.Stop() did not return")
+	}
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+	"zcelero/redact"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader is the header, and gRPC metadata key, carrying the request id
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the ids accepted from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// NewLogger returns the application logger writing to out. Secrets are removed from every line, so a
+// request body or a private key reaching a message, e.g. through a wrapped error, is never written
+func NewLogger(out io.Writer) zerolog.Logger {
+	return zerolog.New(redact.NewWriter(out)).With().Timestamp().Logger()
+}
+
+// NewContext returns a copy of the context carrying the logger
+func NewContext(ctx context.Context, logger zerolog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &logger)
+}
+
+// FromContext returns the logger of the request, or the global logger outside of a request
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*zerolog.Logger); ok {
+		return logger
+	}
+
+	return &log.Logger
+}
+
+// With returns a copy of the context whose logger adds the field to every line
+func With(ctx context.Context, key, value string) context.Context {
+	return NewContext(ctx, FromContext(ctx).With().Str(key, value).Logger())
+}
+
+// RequestID returns the id sent by the client when it is safe to be logged, or a new one
+func RequestID(candidate string) string {
+	if candidate == "" || len(candidate) > maxRequestIDLength || strings.IndexFunc(candidate, notPrintable) >= 0 {
+		return uuid.New().String()
+	}
+
+	return candidate
+}
+
+func notPrintable(r rune) bool {
+	return r > unicode.MaxASCII || !unicode.IsPrint(r)
+}
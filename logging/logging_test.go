@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate string
+		wantKept  bool
+	}{
+		{
+			name:      "Client id",
+			candidate: "3f1c2a9e-req",
+			wantKept:  true,
+		},
+		{
+			name:      "Missing id",
+			candidate: "",
+			wantKept:  false,
+		},
+		{
+			name:      "Too long id",
+			candidate: strings.Repeat("a", 129),
+			wantKept:  false,
+		},
+		{
+			name:      "Id forging log lines",
+			candidate: "abc\n{\"level\":\"error\"}",
+			wantKept:  false,
+		},
+		{
+			name:      "Non ASCII id",
+			candidate: "pedido-çã",
+			wantKept:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RequestID(tt.candidate)
+			if tt.wantKept && got != tt.candidate {
+				t.Errorf("RequestID() = %q, want %q", got, tt.candidate)
+			}
+			if !tt.wantKept {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Errorf("RequestID() = %q, want a new uuid", got)
+				}
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	output := &bytes.Buffer{}
+	ctx := NewContext(context.Background(), zerolog.New(output).With().Str("request_id", "req-1").Logger())
+
+	FromContext(With(ctx, "text_id", "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec")).Info().Msg("Reading file")
+	FromContext(ctx).Info().Msg("request answered")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	want := []string{
+		`{"level":"info","request_id":"req-1","text_id":"154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec","message":"Reading file"}`,
+		`{"level":"info","request_id":"req-1","message":"request answered"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("log lines = %v, want %v", lines, want)
+	}
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	if FromContext(context.Background()) == nil {
+		t.Error("FromContext() = nil, want the global logger")
+	}
+}
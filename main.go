@@ -1,54 +1,305 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 	"zcelero/api"
+	"zcelero/audit"
+	"zcelero/config"
+	"zcelero/envelope"
+	"zcelero/grpcapi"
 	"zcelero/helper"
+	"zcelero/hsm"
+	"zcelero/keypool"
+	"zcelero/logging"
+	"zcelero/metrics"
+	"zcelero/redact"
 	"zcelero/repository"
 	"zcelero/service"
+	"zcelero/tlsconfig"
+	"zcelero/tracing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-func init() {
+func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = logging.NewLogger(os.Stderr)
+	gin.DefaultWriter = redact.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = redact.NewWriter(os.Stderr)
+
+	// the .env file is optional, its values are read as environment variables
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		exit(fmt.Errorf("error loading .env file: %w", err))
+	}
 
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		panic("Error loading .env file")
+		exit(err)
 	}
+	zerolog.SetGlobalLevel(cfg.ZerologLevel())
 
-	zerolog.SetGlobalLevel(zerologLogLevel(os.Getenv("INFO_LEVEL")))
-}
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		exit(err)
+	}
 
-func main() {
 	helper := helper.NewHelper()
-	textManagementRepository := repository.NewRepository(helper)
-	textManagementService := service.NewService(textManagementRepository, helper)
+	if err := helper.CreateDir(cfg.StoragePath); err != nil {
+		exit(fmt.Errorf("error creating storage: %w", err))
+	}
+	textManagementRepository := repository.NewRepository(helper, cfg.StoragePath)
+	var keyring envelope.KeyringInterface
+	if cfg.AtRest.PKCS11.Module != "" {
+		token, err := hsm.NewKeyring(cfg.AtRest.PKCS11, cfg.AtRest.KeyId, os.Getenv(hsm.PinEnv))
+		if err != nil {
+			exit(fmt.Errorf("error opening the PKCS#11 token: %w", err))
+		}
+		defer token.Close()
+		keyring = token
+	} else {
+		keyring, err = envelope.LoadKeyring(cfg.AtRest, os.Getenv)
+		if err != nil {
+			exit(fmt.Errorf("error loading master keys: %w", err))
+		}
+	}
+	var encryptedRepository repository.EncryptedInterface
+	if keyring != nil {
+		log.Info().Str("key_id", keyring.PrimaryKeyId()).Bool("pkcs11", cfg.AtRest.PKCS11.Module != "").Bool("token_data_keys", cfg.AtRest.PKCS11.DataKeys).Msg("At-rest encryption enabled")
+		encryptedRepository = repository.NewEncryptedRepository(textManagementRepository, keyring)
+		textManagementRepository = encryptedRepository
+	}
+	metrics.Registry.MustRegister(metrics.NewStorageCollector(textManagementRepository.Usage))
+	keyPool := keypool.NewKeyPool(cfg.KeySizes, cfg.KeyPool.Size, cfg.KeyPool.Workers)
+	auditKey, err := audit.LoadKey(cfg.Audit, os.Getenv)
+	if err != nil {
+		exit(fmt.Errorf("error loading audit key: %w", err))
+	}
+	if auditKey == nil {
+		log.Warn().Msg("Audit log is chained without a key, set AUDIT_KEY_FILE or AUDIT_KEY so it can't be rehashed after an edit")
+	}
+	auditRepository, err := repository.NewAuditRepository(helper, cfg.StoragePath, auditKey)
+	if err != nil {
+		exit(fmt.Errorf("error opening audit log: %w", err))
+	}
+	auditService := service.NewAuditService(auditRepository, helper, cfg.AdminPrincipals)
+	webhookRepository, err := repository.NewWebhookRepository(helper, cfg.StoragePath)
+	if err != nil {
+		exit(fmt.Errorf("error creating webhook storage: %w", err))
+	}
+	plainService := service.NewService(textManagementRepository, helper, keyPool, cfg.Passphrase.KDFConcurrency)
+	webhookService := service.NewWebhookService(webhookRepository, plainService, helper, cfg.Webhooks)
+	textManagementService := service.NewAuditedService(service.NewNotifyingService(plainService, webhookService), auditService)
+
+	var rotationService service.RotationServiceInterface
+	if encryptedRepository != nil {
+		rotationRepository, err := repository.NewRotationRepository(helper, cfg.StoragePath)
+		if err != nil {
+			exit(fmt.Errorf("error creating rotation storage: %w", err))
+		}
+		rotationService = service.NewRotationService(encryptedRepository, rotationRepository, auditService, helper, keyring.PrimaryKeyId(), cfg.AdminPrincipals, cfg.AtRest.RotationRate)
+	}
+
+	healthService := service.NewHealthService(textManagementRepository, helper, keyPool, cfg)
+
+	var jobService service.JobServiceInterface
+	if cfg.AsyncInsert.Workers > 0 {
+		jobRepository, err := repository.NewJobRepository(helper, cfg.StoragePath)
+		if err != nil {
+			exit(fmt.Errorf("error creating job storage: %w", err))
+		}
+		jobService = service.NewJobService(textManagementService, jobRepository, helper, cfg.AsyncInsert.Workers, cfg.AsyncInsert.QueueSize, cfg.AsyncInsert.JobTTL)
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GrpcPort))
+	if err != nil {
+		exit(fmt.Errorf("error listening for gRPC: %w", err))
+	}
+
+	var reloader tlsconfig.ReloaderInterface
+	grpcOptions := []grpc.ServerOption{}
+	if cfg.TLS.Enabled() {
+		reloader, err = tlsconfig.NewReloader(cfg.TLS)
+		if err != nil {
+			exit(err)
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(reloader.TLSConfig("h2"))))
+	}
+	grpcServer := grpcapi.Start(textManagementService, cfg, grpcOptions...)
+
+	httpServer := newHTTPServer(cfg.Port, api.Start(textManagementService, jobService, healthService, auditService, webhookService, rotationService, cfg), cfg.Timeouts)
+	httpServers := []*http.Server{httpServer}
+	if reloader != nil {
+		httpServer.TLSConfig = reloader.TLSConfig("h2", "http/1.1")
+	}
+	if cfg.TLS.RedirectPort != 0 {
+		httpServers = append(httpServers, newHTTPServer(cfg.TLS.RedirectPort, tlsconfig.RedirectHandler(cfg.Port), cfg.Timeouts))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErrors := make(chan error, len(httpServers)+1)
+	go func() {
+		log.Info().Str("address", listener.Addr().String()).Bool("tls", reloader != nil).Msg("gRPC API Started")
+		if err := grpcServer.Serve(listener); err != nil {
+			serveErrors <- fmt.Errorf("gRPC API stopped: %w", err)
+		}
+	}()
+	for _, server := range httpServers {
+		server := server
+		go func() {
+			log.Info().Str("address", server.Addr).Bool("tls", server.TLSConfig != nil).Msg("API Started")
+			if err := listenAndServe(server); !errors.Is(err, http.ErrServerClosed) {
+				serveErrors <- fmt.Errorf("API stopped: %w", err)
+			}
+		}()
+	}
 
-	router := api.Start(textManagementService)
-	log.Info().Msg("API Started")
-	router.Run()
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Info().Msg("Shutting down")
+	case serveErr = <-serveErrors:
+		log.Error().Msg(serveErr.Error())
+	}
+	// a second signal kills the application without waiting for the shutdown
+	stop()
+
+	if err := shutdown(cfg.Timeouts.Shutdown, httpServers, grpcServer, jobService, webhookService, rotationService, keyPool); err != nil {
+		exit(err)
+	}
+	if reloader != nil {
+		reloader.Stop()
+	}
+	if err := flushTraces(cfg.Timeouts.Shutdown, shutdownTracing); err != nil {
+		log.Error().Msg(err.Error())
+	}
+	if serveErr != nil {
+		exit(serveErr)
+	}
+	log.Info().Msg("Shutdown finished")
 }
 
-func zerologLogLevel(level string) zerolog.Level {
-	switch level {
-	case "debug":
-		return zerolog.DebugLevel
-	case "info":
-		return zerolog.InfoLevel
-	case "error":
-		return zerolog.ErrorLevel
-	case "warn":
-		return zerolog.WarnLevel
-	case "fatal":
-		return zerolog.FatalLevel
-	case "panic":
-		return zerolog.PanicLevel
-	default:
-		panic(fmt.Sprintf("the specified %s log level is not supported", level))
+// shutdown stops accepting requests and waits for the running ones, then stops the background workers.
+// Jobs still queued when the timeout expires are marked as failed in the next start, and webhook events
+// not yet delivered are sent in the next start, as is the rest of an interrupted master key rotation.
+// The repositories write each file when it is saved, so they have nothing left to flush.
+func shutdown(timeout time.Duration, httpServers []*http.Server, grpcServer *grpc.Server, jobService service.JobServiceInterface, webhookService service.WebhookServiceInterface, rotationService service.RotationServiceInterface, keyPool keypool.KeyPoolInterface) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	errs := make(chan error, len(httpServers)+1)
+	for _, server := range httpServers {
+		server := server
+		go func() {
+			errs <- server.Shutdown(ctx)
+		}()
 	}
+	go func() {
+		if err := wait(ctx, grpcServer.GracefulStop); err != nil {
+			grpcServer.Stop()
+			errs <- fmt.Errorf("gRPC requests were interrupted: %w", err)
+			return
+		}
+		errs <- nil
+	}()
+	for i := 0; i < len(httpServers)+1; i++ {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+
+	if jobService != nil {
+		if err := wait(ctx, jobService.Stop); err != nil {
+			return fmt.Errorf("queued jobs were interrupted: %w", err)
+		}
+	}
+
+	if webhookService != nil {
+		if err := wait(ctx, webhookService.Stop); err != nil {
+			return fmt.Errorf("webhook dispatcher was interrupted: %w", err)
+		}
+	}
+
+	if rotationService != nil {
+		if err := wait(ctx, rotationService.Stop); err != nil {
+			return fmt.Errorf("master key rotation was interrupted: %w", err)
+		}
+	}
+
+	return wait(ctx, keyPool.Stop)
+}
+
+// flushTraces exports the spans still buffered, the shutdown of the application is not failed by a
+// collector that can't be reached
+func flushTraces(timeout time.Duration, shutdownTracing func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := shutdownTracing(ctx); err != nil {
+		return fmt.Errorf("pending traces could not be exported: %w", err)
+	}
+
+	return nil
+}
+
+// wait runs stop and gives up waiting for it when the context is done
+func wait(ctx context.Context, stop func()) error {
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newHTTPServer(port int, handler http.Handler, timeouts config.Timeouts) *http.Server {
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(port),
+		Handler:           handler,
+		ReadTimeout:       timeouts.Read,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+	}
+}
+
+// listenAndServe serves HTTPS when the server has a TLS configuration, its certificates come from it
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+
+	return server.ListenAndServe()
+}
+
+// exit reports errors that prevent the application from starting
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "zcelero: %s\n", err.Error())
+	os.Exit(1)
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+	mockkeypool "zcelero/mocks/keypool"
+	mockservice "zcelero/mocks/service"
+
+	"google.golang.org/grpc"
+)
+
+// startServer serves the handler on a random port and returns its address
+func startServer(t *testing.T, handler http.Handler) (*http.Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return server, "http://" + listener.Addr().String()
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestTime  time.Duration
+		timeout      time.Duration
+		mockBehavior func(jobService *mockservice.JobServiceInterface, keyPool *mockkeypool.KeyPoolInterface)
+		wantBody     string
+		wantErr      bool
+	}{
+		{
+			name:        "Drain running request and stop workers",
+			requestTime: 100 * time.Millisecond,
+			timeout:     5 * time.Second,
+			mockBehavior: func(jobService *mockservice.JobServiceInterface, keyPool *mockkeypool.KeyPoolInterface) {
+				jobService.On("Stop").Return()
+				keyPool.On("Stop").Return()
+			},
+			wantBody: "done",
+			wantErr:  false,
+		},
+		{
+			name:        "Give up on request slower than the timeout",
+			requestTime: 2 * time.Second,
+			timeout:     50 * time.Millisecond,
+			wantErr:     true,
+		},
+		{
+			name:        "Give up on jobs slower than the timeout",
+			requestTime: 0,
+			timeout:     50 * time.Millisecond,
+			mockBehavior: func(jobService *mockservice.JobServiceInterface, keyPool *mockkeypool.KeyPoolInterface) {
+				jobService.On("Stop").After(2 * time.Second).Return()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			requestTime := tt.requestTime
+			httpServer, address := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				time.Sleep(requestTime)
+				io.WriteString(w, "done")
+			}))
+			jobService := &mockservice.JobServiceInterface{}
+			keyPool := &mockkeypool.KeyPoolInterface{}
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(jobService, keyPool)
+			}
+
+			body := make(chan string, 1)
+			go func() {
+				res, err := http.Get(address)
+				if err != nil {
+					body <- err.Error()
+					return
+				}
+				defer res.Body.Close()
+				data, _ := io.ReadAll(res.Body)
+				body <- string(data)
+			}()
+			<-started
+
+			err := shutdown(tt.timeout, []*http.Server{httpServer}, grpc.NewServer(), jobService, nil, nil, keyPool)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("shutdown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if tt.wantBody != "" {
+				if got := <-body; got != tt.wantBody {
+					t.Errorf("running request answered %q, want %q", got, tt.wantBody)
+				}
+				jobService.AssertExpectations(t)
+				keyPool.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestShutdownWithoutJobService(t *testing.T) {
+	httpServer, _ := startServer(t, http.NotFoundHandler())
+	keyPool := &mockkeypool.KeyPoolInterface{}
+	keyPool.On("Stop").Return()
+
+	if err := shutdown(time.Second, []*http.Server{httpServer}, grpc.NewServer(), nil, nil, nil, keyPool); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+	keyPool.AssertExpectations(t)
+}
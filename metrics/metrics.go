@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// Registry holds every zcelero collector together with the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts the answered requests by method, route template and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zcelero_http_requests_total",
+		Help: "HTTP requests answered, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes how long the requests took to be answered
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "zcelero_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Inserts counts the stored texts by encryption and key size, 0 for plain texts
+	Inserts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zcelero_inserts_total",
+		Help: "Texts stored, by encryption and key size.",
+	}, []string{"encrypted", "key_size"})
+
+	// KeyGenerationDuration observes the RSA key generation, both from the pool workers and synchronous
+	KeyGenerationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "zcelero_key_generation_duration_seconds",
+		Help:    "RSA key generation time, by key size.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"key_size"})
+
+	// KeyPoolDepth is the number of pre-generated keys ready for each key size
+	KeyPoolDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "zcelero_key_pool_depth",
+		Help: "Pre-generated RSA keys ready, by key size.",
+	}, []string{"key_size"})
+
+	// CryptoFailures counts the encryption and decryption failures by error code
+	CryptoFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zcelero_crypto_failures_total",
+		Help: "Encryption and decryption failures, by operation and error code.",
+	}, []string{"operation", "code"})
+
+	// RepositoryDuration observes the storage operations by repository, operation and result
+	RepositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "zcelero_repository_operation_duration_seconds",
+		Help:    "Storage operation latency, by repository, operation and result.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 9),
+	}, []string{"repository", "operation", "result"})
+
+	// WebhookDeliveries counts the webhook delivery attempts by outcome: delivered, retried or dropped
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zcelero_webhook_deliveries_total",
+		Help: "Webhook delivery attempts, by outcome.",
+	}, []string{"outcome"})
+)
+
+var (
+	storedTextsDesc  = prometheus.NewDesc("zcelero_stored_texts", "Texts in the storage folder.", nil, nil)
+	storageBytesDesc = prometheus.NewDesc("zcelero_storage_bytes", "Size of the texts in the storage folder.", nil, nil)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Inserts,
+		KeyGenerationDuration,
+		KeyPoolDepth,
+		CryptoFailures,
+		RepositoryDuration,
+		WebhookDeliveries,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Since returns the seconds elapsed since start, as observed by the histograms
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Result labels an operation outcome
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
+
+// storageCollector reads the storage usage on every scrape
+type storageCollector struct {
+	usage func() (int, int64, error)
+}
+
+// NewStorageCollector reports the number and size of the stored texts returned by usage
+func NewStorageCollector(usage func() (texts int, bytes int64, err error)) prometheus.Collector {
+	return &storageCollector{usage: usage}
+}
+
+func (s *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- storedTextsDesc
+	ch <- storageBytesDesc
+}
+
+func (s *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	texts, bytes, err := s.usage()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		ch <- prometheus.NewInvalidMetric(storedTextsDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(storedTextsDesc, prometheus.GaugeValue, float64(texts))
+	ch <- prometheus.MustNewConstMetric(storageBytesDesc, prometheus.GaugeValue, float64(bytes))
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStorageCollector(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   func() (int, int64, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Stored texts",
+			usage: func() (int, int64, error) { return 3, 1536, nil },
+			want: `
+# HELP zcelero_storage_bytes Size of the texts in the storage folder.
+# TYPE zcelero_storage_bytes gauge
+zcelero_storage_bytes 1536
+# HELP zcelero_stored_texts Texts in the storage folder.
+# TYPE zcelero_stored_texts gauge
+zcelero_stored_texts 3
+`,
+		},
+		{
+			name:    "Unreadable storage",
+			usage:   func() (int, int64, error) { return 0, 0, errors.New("permission denied") },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(NewStorageCollector(tt.usage), strings.NewReader(tt.want))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CollectAndCompare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	if got := Result(nil); got != "ok" {
+		t.Errorf("Result(nil) = %q, want %q", got, "ok")
+	}
+	if got := Result(errors.New("error")); got != "error" {
+		t.Errorf("Result(error) = %q, want %q", got, "error")
+	}
+}
@@ -0,0 +1,107 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package client
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ClientInterface is an autogenerated mock type for the ClientInterface type
+type ClientInterface struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, textId, privateKey, password
+func (_m *ClientInterface) Delete(ctx context.Context, textId string, privateKey string, password string) error {
+	ret := _m.Called(ctx, textId, privateKey, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, textId, privateKey, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, textId, privateKey, password
+func (_m *ClientInterface) Get(ctx context.Context, textId string, privateKey string, password string) (string, error) {
+	ret := _m.Called(ctx, textId, privateKey, password)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, textId, privateKey, password)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, textId, privateKey, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMetadata provides a mock function with given fields: ctx, textId
+func (_m *ClientInterface) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	ret := _m.Called(ctx, textId)
+
+	var r0 entity.TextMetadata
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.TextMetadata); ok {
+		r0 = rf(ctx, textId)
+	} else {
+		r0 = ret.Get(0).(entity.TextMetadata)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, textId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Insert provides a mock function with given fields: ctx, text
+func (_m *ClientInterface) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	ret := _m.Called(ctx, text)
+
+	var r0 entity.TextManagement
+	if rf, ok := ret.Get(0).(func(context.Context, entity.TextManagement) entity.TextManagement); ok {
+		r0 = rf(ctx, text)
+	} else {
+		r0 = ret.Get(0).(entity.TextManagement)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.TextManagement) error); ok {
+		r1 = rf(ctx, text)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewClientInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewClientInterface creates a new instance of ClientInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewClientInterface(t mockConstructorTestingTNewClientInterface) *ClientInterface {
+	mock := &ClientInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -3,7 +3,9 @@
 package helper
 
 import (
+	fs "io/fs"
 	os "os"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,6 +15,34 @@ type HelperInterface struct {
 	mock.Mock
 }
 
+// AppendFile provides a mock function with given fields: filePath, content
+func (_m *HelperInterface) AppendFile(filePath string, content string) error {
+	ret := _m.Called(filePath, content)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(filePath, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateDir provides a mock function with given fields: dirPath
+func (_m *HelperInterface) CreateDir(dirPath string) error {
+	ret := _m.Called(dirPath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(dirPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateFile provides a mock function with given fields: filePath
 func (_m *HelperInterface) CreateFile(filePath string) (*os.File, error) {
 	ret := _m.Called(filePath)
@@ -50,6 +80,43 @@ func (_m *HelperInterface) GenerateUuid() string {
 	return r0
 }
 
+// Now provides a mock function with given fields:
+func (_m *HelperInterface) Now() time.Time {
+	ret := _m.Called()
+
+	var r0 time.Time
+	if rf, ok := ret.Get(0).(func() time.Time); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	return r0
+}
+
+// ReadDir provides a mock function with given fields: dirPath
+func (_m *HelperInterface) ReadDir(dirPath string) ([]fs.DirEntry, error) {
+	ret := _m.Called(dirPath)
+
+	var r0 []fs.DirEntry
+	if rf, ok := ret.Get(0).(func(string) []fs.DirEntry); ok {
+		r0 = rf(dirPath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]fs.DirEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(dirPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReadFile provides a mock function with given fields: filePath
 func (_m *HelperInterface) ReadFile(filePath string) ([]byte, error) {
 	ret := _m.Called(filePath)
@@ -73,6 +140,34 @@ func (_m *HelperInterface) ReadFile(filePath string) ([]byte, error) {
 	return r0, r1
 }
 
+// RemoveFile provides a mock function with given fields: filePath
+func (_m *HelperInterface) RemoveFile(filePath string) error {
+	ret := _m.Called(filePath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(filePath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceFile provides a mock function with given fields: filePath, content
+func (_m *HelperInterface) ReplaceFile(filePath string, content string) error {
+	ret := _m.Called(filePath, content)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(filePath, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WriteFile provides a mock function with given fields: file, content
 func (_m *HelperInterface) WriteFile(file *os.File, content string) (int, error) {
 	ret := _m.Called(file, content)
@@ -0,0 +1,71 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package keypool
+
+import (
+	rsa "crypto/rsa"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// KeyPoolInterface is an autogenerated mock type for the KeyPoolInterface type
+type KeyPoolInterface struct {
+	mock.Mock
+}
+
+// Depth provides a mock function with given fields: keySize
+func (_m *KeyPoolInterface) Depth(keySize uint64) int {
+	ret := _m.Called(keySize)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(uint64) int); ok {
+		r0 = rf(keySize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: keySize
+func (_m *KeyPoolInterface) Get(keySize uint64) (*rsa.PrivateKey, error) {
+	ret := _m.Called(keySize)
+
+	var r0 *rsa.PrivateKey
+	if rf, ok := ret.Get(0).(func(uint64) *rsa.PrivateKey); ok {
+		r0 = rf(keySize)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rsa.PrivateKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(keySize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stop provides a mock function with given fields:
+func (_m *KeyPoolInterface) Stop() {
+	_m.Called()
+}
+
+type mockConstructorTestingTNewKeyPoolInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewKeyPoolInterface creates a new instance of KeyPoolInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewKeyPoolInterface(t mockConstructorTestingTNewKeyPoolInterface) *KeyPoolInterface {
+	mock := &KeyPoolInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,74 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package repository
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AuditInterface is an autogenerated mock type for the AuditInterface type
+type AuditInterface struct {
+	mock.Mock
+}
+
+// Append provides a mock function with given fields: ctx, entry
+func (_m *AuditInterface) Append(ctx context.Context, entry entity.AuditEntry) (entity.AuditEntry, error) {
+	ret := _m.Called(ctx, entry)
+
+	var r0 entity.AuditEntry
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditEntry) entity.AuditEntry); ok {
+		r0 = rf(ctx, entry)
+	} else {
+		r0 = ret.Get(0).(entity.AuditEntry)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AuditEntry) error); ok {
+		r1 = rf(ctx, entry)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, filter
+func (_m *AuditInterface) List(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []entity.AuditEntry
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) []entity.AuditEntry); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AuditFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewAuditInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAuditInterface creates a new instance of AuditInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAuditInterface(t mockConstructorTestingTNewAuditInterface) *AuditInterface {
+	mock := &AuditInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
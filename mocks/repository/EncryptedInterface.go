@@ -0,0 +1,166 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package repository
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// EncryptedInterface is an autogenerated mock type for the EncryptedInterface type
+type EncryptedInterface struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, fileName
+func (_m *EncryptedInterface) Delete(ctx context.Context, fileName string) error {
+	ret := _m.Called(ctx, fileName)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, fileName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *EncryptedInterface) List(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Load provides a mock function with given fields: ctx, fileName
+func (_m *EncryptedInterface) Load(ctx context.Context, fileName string) ([]byte, error) {
+	ret := _m.Called(ctx, fileName)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, fileName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fileName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Replace provides a mock function with given fields: ctx, fileName, content
+func (_m *EncryptedInterface) Replace(ctx context.Context, fileName string, content string) error {
+	ret := _m.Called(ctx, fileName, content)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, fileName, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rewrap provides a mock function with given fields: ctx, fileName
+func (_m *EncryptedInterface) Rewrap(ctx context.Context, fileName string) (bool, error) {
+	ret := _m.Called(ctx, fileName)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, fileName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fileName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: ctx, fileName, content
+func (_m *EncryptedInterface) Save(ctx context.Context, fileName string, content string) error {
+	ret := _m.Called(ctx, fileName, content)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, fileName, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Usage provides a mock function with given fields:
+func (_m *EncryptedInterface) Usage() (int, int64, error) {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 int64
+	if rf, ok := ret.Get(1).(func() int64); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func() error); ok {
+		r2 = rf()
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+type mockConstructorTestingTNewEncryptedInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewEncryptedInterface creates a new instance of EncryptedInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewEncryptedInterface(t mockConstructorTestingTNewEncryptedInterface) *EncryptedInterface {
+	mock := &EncryptedInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,101 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package repository
+
+import (
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// JobInterface is an autogenerated mock type for the JobInterface type
+type JobInterface struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: jobId
+func (_m *JobInterface) Delete(jobId string) error {
+	ret := _m.Called(jobId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(jobId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields:
+func (_m *JobInterface) List() ([]entity.Job, error) {
+	ret := _m.Called()
+
+	var r0 []entity.Job
+	if rf, ok := ret.Get(0).(func() []entity.Job); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Job)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Load provides a mock function with given fields: jobId
+func (_m *JobInterface) Load(jobId string) (entity.Job, error) {
+	ret := _m.Called(jobId)
+
+	var r0 entity.Job
+	if rf, ok := ret.Get(0).(func(string) entity.Job); ok {
+		r0 = rf(jobId)
+	} else {
+		r0 = ret.Get(0).(entity.Job)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(jobId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: job
+func (_m *JobInterface) Save(job entity.Job) error {
+	ret := _m.Called(job)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.Job) error); ok {
+		r0 = rf(job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewJobInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewJobInterface creates a new instance of JobInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewJobInterface(t mockConstructorTestingTNewJobInterface) *JobInterface {
+	mock := &JobInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
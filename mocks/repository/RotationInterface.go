@@ -0,0 +1,64 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package repository
+
+import (
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RotationInterface is an autogenerated mock type for the RotationInterface type
+type RotationInterface struct {
+	mock.Mock
+}
+
+// Load provides a mock function with given fields:
+func (_m *RotationInterface) Load() (entity.Rotation, error) {
+	ret := _m.Called()
+
+	var r0 entity.Rotation
+	if rf, ok := ret.Get(0).(func() entity.Rotation); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.Rotation)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: rotation
+func (_m *RotationInterface) Save(rotation entity.Rotation) error {
+	ret := _m.Called(rotation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.Rotation) error); ok {
+		r0 = rf(rotation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewRotationInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewRotationInterface creates a new instance of RotationInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewRotationInterface(t mockConstructorTestingTNewRotationInterface) *RotationInterface {
+	mock := &RotationInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
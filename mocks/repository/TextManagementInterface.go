@@ -2,20 +2,61 @@
 
 package repository
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // TextManagementInterface is an autogenerated mock type for the TextManagementInterface type
 type TextManagementInterface struct {
 	mock.Mock
 }
 
-// Load provides a mock function with given fields: fileName
-func (_m *TextManagementInterface) Load(fileName string) ([]byte, error) {
-	ret := _m.Called(fileName)
+// Delete provides a mock function with given fields: ctx, fileName
+func (_m *TextManagementInterface) Delete(ctx context.Context, fileName string) error {
+	ret := _m.Called(ctx, fileName)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, fileName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *TextManagementInterface) List(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Load provides a mock function with given fields: ctx, fileName
+func (_m *TextManagementInterface) Load(ctx context.Context, fileName string) ([]byte, error) {
+	ret := _m.Called(ctx, fileName)
 
 	var r0 []byte
-	if rf, ok := ret.Get(0).(func(string) []byte); ok {
-		r0 = rf(fileName)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, fileName)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
@@ -23,8 +64,8 @@ func (_m *TextManagementInterface) Load(fileName string) ([]byte, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(fileName)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fileName)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -32,13 +73,13 @@ func (_m *TextManagementInterface) Load(fileName string) ([]byte, error) {
 	return r0, r1
 }
 
-// Save provides a mock function with given fields: fileName, content
-func (_m *TextManagementInterface) Save(fileName string, content string) error {
-	ret := _m.Called(fileName, content)
+// Replace provides a mock function with given fields: ctx, fileName, content
+func (_m *TextManagementInterface) Replace(ctx context.Context, fileName string, content string) error {
+	ret := _m.Called(ctx, fileName, content)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(fileName, content)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, fileName, content)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -46,6 +87,48 @@ func (_m *TextManagementInterface) Save(fileName string, content string) error {
 	return r0
 }
 
+// Save provides a mock function with given fields: ctx, fileName, content
+func (_m *TextManagementInterface) Save(ctx context.Context, fileName string, content string) error {
+	ret := _m.Called(ctx, fileName, content)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, fileName, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Usage provides a mock function with given fields:
+func (_m *TextManagementInterface) Usage() (int, int64, error) {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 int64
+	if rf, ok := ret.Get(1).(func() int64); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func() error); ok {
+		r2 = rf()
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 type mockConstructorTestingTNewTextManagementInterface interface {
 	mock.TestingT
 	Cleanup(func())
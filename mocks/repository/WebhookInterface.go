@@ -0,0 +1,131 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package repository
+
+import (
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhookInterface is an autogenerated mock type for the WebhookInterface type
+type WebhookInterface struct {
+	mock.Mock
+}
+
+// DeleteDelivery provides a mock function with given fields: deliveryId
+func (_m *WebhookInterface) DeleteDelivery(deliveryId string) error {
+	ret := _m.Called(deliveryId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(deliveryId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSubscription provides a mock function with given fields: subscriptionId
+func (_m *WebhookInterface) DeleteSubscription(subscriptionId string) error {
+	ret := _m.Called(subscriptionId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(subscriptionId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListDeliveries provides a mock function with given fields:
+func (_m *WebhookInterface) ListDeliveries() ([]entity.WebhookDelivery, error) {
+	ret := _m.Called()
+
+	var r0 []entity.WebhookDelivery
+	if rf, ok := ret.Get(0).(func() []entity.WebhookDelivery); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.WebhookDelivery)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSubscriptions provides a mock function with given fields:
+func (_m *WebhookInterface) ListSubscriptions() ([]entity.WebhookSubscription, error) {
+	ret := _m.Called()
+
+	var r0 []entity.WebhookSubscription
+	if rf, ok := ret.Get(0).(func() []entity.WebhookSubscription); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.WebhookSubscription)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveDelivery provides a mock function with given fields: delivery
+func (_m *WebhookInterface) SaveDelivery(delivery entity.WebhookDelivery) error {
+	ret := _m.Called(delivery)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.WebhookDelivery) error); ok {
+		r0 = rf(delivery)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveSubscription provides a mock function with given fields: subscription
+func (_m *WebhookInterface) SaveSubscription(subscription entity.WebhookSubscription) error {
+	ret := _m.Called(subscription)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.WebhookSubscription) error); ok {
+		r0 = rf(subscription)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewWebhookInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewWebhookInterface creates a new instance of WebhookInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewWebhookInterface(t mockConstructorTestingTNewWebhookInterface) *WebhookInterface {
+	mock := &WebhookInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
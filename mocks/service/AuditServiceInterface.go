@@ -0,0 +1,58 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package service
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AuditServiceInterface is an autogenerated mock type for the AuditServiceInterface type
+type AuditServiceInterface struct {
+	mock.Mock
+}
+
+// Query provides a mock function with given fields: ctx, filter
+func (_m *AuditServiceInterface) Query(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []entity.AuditEntry
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) []entity.AuditEntry); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AuditFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Record provides a mock function with given fields: ctx, action, textId, err
+func (_m *AuditServiceInterface) Record(ctx context.Context, action string, textId string, err error) {
+	_m.Called(ctx, action, textId, err)
+}
+
+type mockConstructorTestingTNewAuditServiceInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAuditServiceInterface creates a new instance of AuditServiceInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAuditServiceInterface(t mockConstructorTestingTNewAuditServiceInterface) *AuditServiceInterface {
+	mock := &AuditServiceInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
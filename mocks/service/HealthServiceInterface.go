@@ -0,0 +1,58 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package service
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// HealthServiceInterface is an autogenerated mock type for the HealthServiceInterface type
+type HealthServiceInterface struct {
+	mock.Mock
+}
+
+// Live provides a mock function with given fields:
+func (_m *HealthServiceInterface) Live() entity.Health {
+	ret := _m.Called()
+
+	var r0 entity.Health
+	if rf, ok := ret.Get(0).(func() entity.Health); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.Health)
+	}
+
+	return r0
+}
+
+// Ready provides a mock function with given fields: ctx
+func (_m *HealthServiceInterface) Ready(ctx context.Context) entity.Health {
+	ret := _m.Called(ctx)
+
+	var r0 entity.Health
+	if rf, ok := ret.Get(0).(func(context.Context) entity.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(entity.Health)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewHealthServiceInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewHealthServiceInterface creates a new instance of HealthServiceInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewHealthServiceInterface(t mockConstructorTestingTNewHealthServiceInterface) *HealthServiceInterface {
+	mock := &HealthServiceInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,77 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package service
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// JobServiceInterface is an autogenerated mock type for the JobServiceInterface type
+type JobServiceInterface struct {
+	mock.Mock
+}
+
+// Enqueue provides a mock function with given fields: ctx, text
+func (_m *JobServiceInterface) Enqueue(ctx context.Context, text entity.TextManagement) (entity.Job, error) {
+	ret := _m.Called(ctx, text)
+
+	var r0 entity.Job
+	if rf, ok := ret.Get(0).(func(context.Context, entity.TextManagement) entity.Job); ok {
+		r0 = rf(ctx, text)
+	} else {
+		r0 = ret.Get(0).(entity.Job)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.TextManagement) error); ok {
+		r1 = rf(ctx, text)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: jobId
+func (_m *JobServiceInterface) Get(jobId string) (entity.Job, error) {
+	ret := _m.Called(jobId)
+
+	var r0 entity.Job
+	if rf, ok := ret.Get(0).(func(string) entity.Job); ok {
+		r0 = rf(jobId)
+	} else {
+		r0 = ret.Get(0).(entity.Job)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(jobId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stop provides a mock function with given fields:
+func (_m *JobServiceInterface) Stop() {
+	_m.Called()
+}
+
+type mockConstructorTestingTNewJobServiceInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewJobServiceInterface creates a new instance of JobServiceInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewJobServiceInterface(t mockConstructorTestingTNewJobServiceInterface) *JobServiceInterface {
+	mock := &JobServiceInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,77 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package service
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RotationServiceInterface is an autogenerated mock type for the RotationServiceInterface type
+type RotationServiceInterface struct {
+	mock.Mock
+}
+
+// Start provides a mock function with given fields: ctx
+func (_m *RotationServiceInterface) Start(ctx context.Context) (entity.Rotation, error) {
+	ret := _m.Called(ctx)
+
+	var r0 entity.Rotation
+	if rf, ok := ret.Get(0).(func(context.Context) entity.Rotation); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(entity.Rotation)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Status provides a mock function with given fields: ctx
+func (_m *RotationServiceInterface) Status(ctx context.Context) (entity.Rotation, error) {
+	ret := _m.Called(ctx)
+
+	var r0 entity.Rotation
+	if rf, ok := ret.Get(0).(func(context.Context) entity.Rotation); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(entity.Rotation)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stop provides a mock function with given fields:
+func (_m *RotationServiceInterface) Stop() {
+	_m.Called()
+}
+
+type mockConstructorTestingTNewRotationServiceInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewRotationServiceInterface creates a new instance of RotationServiceInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewRotationServiceInterface(t mockConstructorTestingTNewRotationServiceInterface) *RotationServiceInterface {
+	mock := &RotationServiceInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -3,6 +3,7 @@
 package service
 
 import (
+	context "context"
 	entity "zcelero/entity"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,20 +14,20 @@ type TextManagementServiceInteface struct {
 	mock.Mock
 }
 
-// Get provides a mock function with given fields: textId, privateKey, password
-func (_m *TextManagementServiceInteface) Get(textId string, privateKey string, password string) (string, error) {
-	ret := _m.Called(textId, privateKey, password)
+// ChangePassword provides a mock function with given fields: ctx, change
+func (_m *TextManagementServiceInteface) ChangePassword(ctx context.Context, change entity.PasswordChange) (string, error) {
+	ret := _m.Called(ctx, change)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string, string, string) string); ok {
-		r0 = rf(textId, privateKey, password)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.PasswordChange) string); ok {
+		r0 = rf(ctx, change)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(textId, privateKey, password)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.PasswordChange) error); ok {
+		r1 = rf(ctx, change)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -34,20 +35,97 @@ func (_m *TextManagementServiceInteface) Get(textId string, privateKey string, p
 	return r0, r1
 }
 
-// Insert provides a mock function with given fields: text
-func (_m *TextManagementServiceInteface) Insert(text entity.TextManagement) (entity.TextManagement, error) {
-	ret := _m.Called(text)
+// Delete provides a mock function with given fields: ctx, textId, privateKey, password
+func (_m *TextManagementServiceInteface) Delete(ctx context.Context, textId string, privateKey string, password string) error {
+	ret := _m.Called(ctx, textId, privateKey, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, textId, privateKey, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, textId, privateKey, password
+func (_m *TextManagementServiceInteface) Get(ctx context.Context, textId string, privateKey string, password string) (string, error) {
+	ret := _m.Called(ctx, textId, privateKey, password)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, textId, privateKey, password)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, textId, privateKey, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMetadata provides a mock function with given fields: ctx, textId
+func (_m *TextManagementServiceInteface) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	ret := _m.Called(ctx, textId)
+
+	var r0 entity.TextMetadata
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.TextMetadata); ok {
+		r0 = rf(ctx, textId)
+	} else {
+		r0 = ret.Get(0).(entity.TextMetadata)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, textId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Insert provides a mock function with given fields: ctx, text
+func (_m *TextManagementServiceInteface) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	ret := _m.Called(ctx, text)
 
 	var r0 entity.TextManagement
-	if rf, ok := ret.Get(0).(func(entity.TextManagement) entity.TextManagement); ok {
-		r0 = rf(text)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.TextManagement) entity.TextManagement); ok {
+		r0 = rf(ctx, text)
 	} else {
 		r0 = ret.Get(0).(entity.TextManagement)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(entity.TextManagement) error); ok {
-		r1 = rf(text)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.TextManagement) error); ok {
+		r1 = rf(ctx, text)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Rekey provides a mock function with given fields: ctx, textId, rekey
+func (_m *TextManagementServiceInteface) Rekey(ctx context.Context, textId string, rekey entity.Rekey) (string, error) {
+	ret := _m.Called(ctx, textId, rekey)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.Rekey) string); ok {
+		r0 = rf(ctx, textId, rekey)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, entity.Rekey) error); ok {
+		r1 = rf(ctx, textId, rekey)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,112 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package service
+
+import (
+	context "context"
+	entity "zcelero/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhookServiceInterface is an autogenerated mock type for the WebhookServiceInterface type
+type WebhookServiceInterface struct {
+	mock.Mock
+}
+
+// HasOwnerSubscriptions provides a mock function with given fields:
+func (_m *WebhookServiceInterface) HasOwnerSubscriptions() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *WebhookServiceInterface) List(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []entity.WebhookSubscription
+	if rf, ok := ret.Get(0).(func(context.Context) []entity.WebhookSubscription); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.WebhookSubscription)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Notify provides a mock function with given fields: ctx, event
+func (_m *WebhookServiceInterface) Notify(ctx context.Context, event entity.WebhookEvent) {
+	_m.Called(ctx, event)
+}
+
+// Stop provides a mock function with given fields:
+func (_m *WebhookServiceInterface) Stop() {
+	_m.Called()
+}
+
+// Subscribe provides a mock function with given fields: ctx, subscription
+func (_m *WebhookServiceInterface) Subscribe(ctx context.Context, subscription entity.WebhookSubscription) (entity.WebhookSubscription, error) {
+	ret := _m.Called(ctx, subscription)
+
+	var r0 entity.WebhookSubscription
+	if rf, ok := ret.Get(0).(func(context.Context, entity.WebhookSubscription) entity.WebhookSubscription); ok {
+		r0 = rf(ctx, subscription)
+	} else {
+		r0 = ret.Get(0).(entity.WebhookSubscription)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.WebhookSubscription) error); ok {
+		r1 = rf(ctx, subscription)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Unsubscribe provides a mock function with given fields: ctx, subscriptionId
+func (_m *WebhookServiceInterface) Unsubscribe(ctx context.Context, subscriptionId string) error {
+	ret := _m.Called(ctx, subscriptionId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, subscriptionId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewWebhookServiceInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewWebhookServiceInterface creates a new instance of WebhookServiceInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewWebhookServiceInterface(t mockConstructorTestingTNewWebhookServiceInterface) *WebhookServiceInterface {
+	mock := &WebhookServiceInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,56 @@
+// Code generated by mockery v2.14.1. DO NOT EDIT.
+
+package tlsconfig
+
+import (
+	tls "crypto/tls"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ReloaderInterface is an autogenerated mock type for the ReloaderInterface type
+type ReloaderInterface struct {
+	mock.Mock
+}
+
+// Stop provides a mock function with given fields:
+func (_m *ReloaderInterface) Stop() {
+	_m.Called()
+}
+
+// TLSConfig provides a mock function with given fields: nextProtos
+func (_m *ReloaderInterface) TLSConfig(nextProtos ...string) *tls.Config {
+	_va := make([]interface{}, len(nextProtos))
+	for _i := range nextProtos {
+		_va[_i] = nextProtos[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *tls.Config
+	if rf, ok := ret.Get(0).(func(...string) *tls.Config); ok {
+		r0 = rf(nextProtos...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tls.Config)
+		}
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewReloaderInterface interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewReloaderInterface creates a new instance of ReloaderInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewReloaderInterface(t mockConstructorTestingTNewReloaderInterface) *ReloaderInterface {
+	mock := &ReloaderInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,523 @@
+package openapi_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+	"zcelero/api"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/envelope"
+	"zcelero/helper"
+	mockkeypool "zcelero/mocks/keypool"
+	"zcelero/openapi"
+	"zcelero/repository"
+	"zcelero/service"
+	"zcelero/textcrypto"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+var adminPrincipal = pkix.Name{CommonName: "admin", Organization: []string{"Zcelero"}}
+
+type contract struct {
+	t      *testing.T
+	doc    *openapi3.T
+	router routers.Router
+	api    *gin.Engine
+	// tls is the connection state of the calls, it carries the client certificate of the principal
+	tls *tls.ConnectionState
+}
+
+func newContract(t *testing.T) *contract {
+	os.Mkdir("storage", 0777)
+	t.Cleanup(func() { os.RemoveAll("storage") })
+
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.RegisteredBodyDecoder("text/plain"))
+	openapi3filter.RegisterBodyDecoder("text/javascript", openapi3filter.RegisteredBodyDecoder("text/plain"))
+	openapi3filter.RegisterBodyDecoder("text/css", openapi3filter.RegisteredBodyDecoder("text/plain"))
+
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Document)
+	if err != nil {
+		t.Fatalf("loading OpenAPI document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("OpenAPI document is not valid: %v", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("creating OpenAPI router: %v", err)
+	}
+
+	helper := helper.NewHelper()
+	keyring, err := envelope.NewKeyring(map[string][]byte{"contract": bytes.Repeat([]byte{7}, 32)}, "contract")
+	if err != nil {
+		t.Fatalf("creating keyring: %v", err)
+	}
+	encryptedRepository := repository.NewEncryptedRepository(repository.NewRepository(helper, "storage"), keyring)
+	auditRepository, err := repository.NewAuditRepository(helper, "storage", bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatalf("creating audit repository: %v", err)
+	}
+	auditService := service.NewAuditService(auditRepository, helper, []string{adminPrincipal.String()})
+	webhookRepository, err := repository.NewWebhookRepository(helper, "storage")
+	if err != nil {
+		t.Fatalf("creating webhook repository: %v", err)
+	}
+	plainService := service.NewService(encryptedRepository, helper, nil, 0)
+	webhookService := service.NewWebhookService(webhookRepository, plainService, helper, config.Default().Webhooks)
+	t.Cleanup(webhookService.Stop)
+	textManagementService := service.NewAuditedService(service.NewNotifyingService(plainService, webhookService), auditService)
+	jobRepository, err := repository.NewJobRepository(helper, "storage")
+	if err != nil {
+		t.Fatalf("creating job repository: %v", err)
+	}
+	jobService := service.NewJobService(textManagementService, jobRepository, helper, 1, 10, 0)
+	t.Cleanup(jobService.Stop)
+	rotationRepository, err := repository.NewRotationRepository(helper, "storage")
+	if err != nil {
+		t.Fatalf("creating rotation repository: %v", err)
+	}
+	rotationService := service.NewRotationService(encryptedRepository, rotationRepository, auditService, helper, keyring.PrimaryKeyId(), []string{adminPrincipal.String()}, 0)
+	t.Cleanup(rotationService.Stop)
+
+	healthService := service.NewHealthService(encryptedRepository, helper, nil, config.Default())
+
+	return &contract{t: t, doc: doc, router: router, api: api.Start(textManagementService, jobService, healthService, auditService, webhookService, rotationService, config.Default())}
+}
+
+// call runs the request through the API and validates both request and response against the document
+func (c *contract) call(method, path string, body any, wantStatus int) []byte {
+	c.t.Helper()
+
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.TLS = c.tls
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	route, pathParams, err := c.router.FindRoute(req)
+	if err != nil {
+		c.t.Fatalf("%s %s is not described: %v", method, path, err)
+	}
+
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateRequest(context.Background(), requestInput); err != nil && wantStatus < http.StatusBadRequest {
+		c.t.Fatalf("%s %s request does not match the document: %v", method, path, err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+
+	res := httptest.NewRecorder()
+	c.api.ServeHTTP(res, req)
+
+	if res.Code != wantStatus {
+		c.t.Fatalf("%s %s status = %d, want %d: %s", method, path, res.Code, wantStatus, res.Body.String())
+	}
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 res.Code,
+		Header:                 res.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(res.Body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateResponse(context.Background(), responseInput); err != nil {
+		c.t.Errorf("%s %s response does not match the document: %v", method, path, err)
+	}
+
+	return res.Body.Bytes()
+}
+
+func TestContractEveryRouteIsDescribed(t *testing.T) {
+	c := newContract(t)
+	pathParam := regexp.MustCompile(`:(\w+)`)
+
+	for _, route := range c.api.Routes() {
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		item := c.doc.Paths.Find(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("route %s %s is not described in openapi.json", route.Method, route.Path)
+		}
+	}
+}
+
+func TestContractTextManagement(t *testing.T) {
+	c := newContract(t)
+
+	body := c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":            "encrypted text data",
+		"encryption":           true,
+		"key_size":             1024,
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	inserted := struct {
+		Uuid       string `json:"uuid"`
+		PrivateKey string `json:"private_key"`
+	}{}
+	json.Unmarshal(body, &inserted)
+
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          inserted.PrivateKey,
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          inserted.PrivateKey,
+		"private_key_password": "password456",
+	}, http.StatusForbidden)
+	c.call(http.MethodGet, "/v1/text-management/metadata?id="+inserted.Uuid, nil, http.StatusOK)
+	c.call(http.MethodGet, "/v1/text-management/metadata?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", nil, http.StatusNotFound)
+	c.call(http.MethodGet, "/v1/text-management/metadata?id=invalid", nil, http.StatusBadRequest)
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          "not a key",
+		"private_key_password": "password123",
+	}, http.StatusUnprocessableEntity)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, nil, http.StatusForbidden)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          inserted.PrivateKey,
+		"private_key_password": "password456",
+	}, http.StatusForbidden)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          inserted.PrivateKey,
+		"private_key_password": "password123",
+	}, http.StatusNoContent)
+
+	body = c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":  "text data",
+		"encryption": false,
+	}, http.StatusOK)
+	json.Unmarshal(body, &inserted)
+
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, nil, http.StatusOK)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, nil, http.StatusNoContent)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, nil, http.StatusNotFound)
+	c.call(http.MethodDelete, "/v1/text-management?id=invalid", nil, http.StatusBadRequest)
+	c.call(http.MethodGet, "/v1/text-management?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", nil, http.StatusNotFound)
+	c.call(http.MethodGet, "/v1/text-management?id=invalid", nil, http.StatusBadRequest)
+	c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":  " ",
+		"encryption": false,
+		"key_size":   1024,
+	}, http.StatusBadRequest)
+}
+
+func TestContractPrivateKeyPassword(t *testing.T) {
+	c := newContract(t)
+
+	body := c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":            "encrypted text data",
+		"encryption":           true,
+		"key_size":             1024,
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	inserted := struct {
+		Uuid       string `json:"uuid"`
+		PrivateKey string `json:"private_key"`
+	}{}
+	json.Unmarshal(body, &inserted)
+
+	body = c.call(http.MethodPost, "/v1/private-key/password", map[string]string{
+		"private_key":              inserted.PrivateKey,
+		"private_key_password":     "password123",
+		"new_private_key_password": "password456",
+	}, http.StatusOK)
+	changed := struct {
+		PrivateKey string `json:"private_key"`
+	}{}
+	json.Unmarshal(body, &changed)
+
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          changed.PrivateKey,
+		"private_key_password": "password456",
+	}, http.StatusOK)
+	c.call(http.MethodPost, "/v1/private-key/password", map[string]string{
+		"private_key":              changed.PrivateKey,
+		"private_key_password":     "password123",
+		"new_private_key_password": "password789",
+	}, http.StatusForbidden)
+	c.call(http.MethodPost, "/v1/private-key/password", map[string]string{
+		"private_key":              "not a key",
+		"private_key_password":     "password123",
+		"new_private_key_password": "password789",
+	}, http.StatusUnprocessableEntity)
+	c.call(http.MethodPost, "/v1/private-key/password", map[string]string{
+		"private_key":              changed.PrivateKey,
+		"private_key_password":     "password456",
+		"new_private_key_password": "short",
+	}, http.StatusBadRequest)
+}
+
+func TestContractRekey(t *testing.T) {
+	c := newContract(t)
+
+	body := c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":            "encrypted text data",
+		"encryption":           true,
+		"key_size":             1024,
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	inserted := struct {
+		Uuid       string `json:"uuid"`
+		PrivateKey string `json:"private_key"`
+	}{}
+	json.Unmarshal(body, &inserted)
+
+	body = c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":              inserted.PrivateKey,
+		"private_key_password":     "password123",
+		"new_private_key_password": "password456",
+	}, http.StatusOK)
+	rekeyed := struct {
+		PrivateKey string `json:"private_key"`
+	}{}
+	json.Unmarshal(body, &rekeyed)
+
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          rekeyed.PrivateKey,
+		"private_key_password": "password456",
+	}, http.StatusOK)
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          inserted.PrivateKey,
+		"private_key_password": "password123",
+	}, http.StatusUnprocessableEntity)
+	c.call(http.MethodGet, "/v1/text-management/metadata?id="+inserted.Uuid, nil, http.StatusOK)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	publicKey, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	body = c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":          rekeyed.PrivateKey,
+		"private_key_password": "password456",
+		"public_key":           string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+	}, http.StatusOK)
+	if bytes.Contains(body, []byte("private_key")) {
+		t.Errorf("rekey with a public key answered a private key, %s", body)
+	}
+	privateKey, _ := textcrypto.EncryptPrivateKey(rand.Reader, rsaKey, "password789")
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key":          privateKey,
+		"private_key_password": "password789",
+	}, http.StatusOK)
+
+	c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":          privateKey,
+		"private_key_password": "password000",
+		"public_key":           string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+	}, http.StatusForbidden)
+	c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":          privateKey,
+		"private_key_password": "password789",
+	}, http.StatusBadRequest)
+	c.call(http.MethodPost, "/v1/text-management/rekey?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", map[string]string{
+		"private_key":              privateKey,
+		"private_key_password":     "password789",
+		"new_private_key_password": "password456",
+	}, http.StatusNotFound)
+
+	body = c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":  "text data",
+		"encryption": false,
+	}, http.StatusOK)
+	json.Unmarshal(body, &inserted)
+	c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":              privateKey,
+		"private_key_password":     "password789",
+		"new_private_key_password": "password456",
+	}, http.StatusBadRequest)
+}
+
+func TestContractPassphrase(t *testing.T) {
+	c := newContract(t)
+
+	body := c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":            "passphrase text data",
+		"encryption":           true,
+		"encryption_mode":      "passphrase",
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	inserted := struct {
+		Uuid string `json:"uuid"`
+	}{}
+	json.Unmarshal(body, &inserted)
+
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key_password": "password123",
+	}, http.StatusOK)
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key_password": "password456",
+	}, http.StatusForbidden)
+	c.call(http.MethodGet, "/v1/text-management/metadata?id="+inserted.Uuid, nil, http.StatusOK)
+	c.call(http.MethodPost, "/v1/text-management/rekey?id="+inserted.Uuid, map[string]string{
+		"private_key":              "private key",
+		"private_key_password":     "password123",
+		"new_private_key_password": "password456",
+	}, http.StatusBadRequest)
+	c.call(http.MethodDelete, "/v1/text-management?id="+inserted.Uuid, map[string]string{
+		"private_key_password": "password123",
+	}, http.StatusNoContent)
+
+	c.call(http.MethodPost, "/v1/text-management", map[string]any{
+		"text_data":            "passphrase text data",
+		"encryption":           true,
+		"encryption_mode":      "passphrase",
+		"key_size":             1024,
+		"private_key_password": "password123",
+	}, http.StatusBadRequest)
+}
+
+func TestContractJobs(t *testing.T) {
+	c := newContract(t)
+
+	body := c.call(http.MethodPost, "/v1/text-management?async=true", map[string]any{
+		"text_data":            "encrypted text data",
+		"encryption":           true,
+		"key_size":             1024,
+		"private_key_password": "password123",
+	}, http.StatusAccepted)
+	queued := struct {
+		JobId string `json:"job_id"`
+	}{}
+	json.Unmarshal(body, &queued)
+
+	deadline := time.Now().Add(30 * time.Second)
+	job := entity.Job{}
+	for job.Status != entity.JobStatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish, status %s", queued.JobId, job.Status)
+		}
+		json.Unmarshal(c.call(http.MethodGet, "/v1/jobs/"+queued.JobId, nil, http.StatusOK), &job)
+	}
+
+	c.call(http.MethodGet, "/v1/jobs/154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", nil, http.StatusNotFound)
+	c.call(http.MethodGet, "/v1/jobs/invalid", nil, http.StatusBadRequest)
+}
+
+func TestContractDocumentation(t *testing.T) {
+	c := newContract(t)
+
+	c.call(http.MethodGet, "/openapi.json", nil, http.StatusOK)
+	page := c.call(http.MethodGet, "/docs", nil, http.StatusOK)
+	if bytes.Contains(page, []byte("https://")) {
+		t.Errorf("Swagger UI page loads external resources: %s", page)
+	}
+	c.call(http.MethodGet, "/docs/swagger-ui-bundle.js", nil, http.StatusOK)
+	c.call(http.MethodGet, "/docs/swagger-ui.css", nil, http.StatusOK)
+	c.call(http.MethodGet, "/docs/LICENSE", nil, http.StatusNotFound)
+	c.call(http.MethodGet, "/docs/index.html", nil, http.StatusNotFound)
+	c.call(http.MethodGet, "/metrics", nil, http.StatusOK)
+}
+
+func TestContractHealth(t *testing.T) {
+	c := newContract(t)
+
+	c.call(http.MethodGet, "/healthz", nil, http.StatusOK)
+	c.call(http.MethodGet, "/readyz", nil, http.StatusOK)
+
+	invalid := config.Default()
+	invalid.Port = 0
+	helper := helper.NewHelper()
+	healthService := service.NewHealthService(repository.NewRepository(helper, "storage"), helper, nil, invalid)
+	c.api = api.Start(&service.TextManagementService{}, nil, healthService, nil, nil, nil, invalid)
+	c.call(http.MethodGet, "/readyz", nil, http.StatusServiceUnavailable)
+
+	drained := &mockkeypool.KeyPoolInterface{}
+	drained.On("Depth", mock.Anything).Return(0)
+	healthService = service.NewHealthService(repository.NewRepository(helper, "storage"), helper, drained, config.Default())
+	c.api = api.Start(&service.TextManagementService{}, nil, healthService, nil, nil, nil, config.Default())
+	var health entity.Health
+	json.Unmarshal(c.call(http.MethodGet, "/readyz", nil, http.StatusOK), &health)
+	if health.Status != entity.HealthStatusDegraded {
+		t.Errorf("GET /readyz with a drained key pool status = %v, want %v", health.Status, entity.HealthStatusDegraded)
+	}
+}
+
+func TestContractAudit(t *testing.T) {
+	c := newContract(t)
+
+	inserted := entity.TextManagement{}
+	body := c.call(http.MethodPost, "/v1/text-management", map[string]any{"text_data": "text data", "encryption": false}, http.StatusOK)
+	json.Unmarshal(body, &inserted)
+	c.call(http.MethodGet, "/v1/text-management?id="+inserted.Uuid, nil, http.StatusOK)
+
+	c.call(http.MethodGet, "/v1/audit", nil, http.StatusForbidden)
+	c.tls = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: adminPrincipal}}}}
+	c.call(http.MethodGet, "/v1/audit?since=2022-11-10T00:00:00Z", nil, http.StatusOK)
+	c.call(http.MethodGet, "/v1/audit?since=yesterday", nil, http.StatusBadRequest)
+
+	entries := struct {
+		Entries []entity.AuditEntry `json:"entries"`
+	}{}
+	json.Unmarshal(c.call(http.MethodGet, "/v1/audit?text_id="+inserted.Uuid+"&limit=10", nil, http.StatusOK), &entries)
+	if len(entries.Entries) != 2 || entries.Entries[0].Action != entity.AuditActionRead || entries.Entries[1].Action != entity.AuditActionInsert {
+		t.Errorf("GET /v1/audit entries = %v, want the read and the insert", entries.Entries)
+	}
+}
+
+func TestContractWebhooks(t *testing.T) {
+	c := newContract(t)
+	subscription := map[string]any{"url": "https://example.com/zcelero-events", "events": []string{entity.WebhookEventRead}}
+
+	c.call(http.MethodPost, "/v1/webhooks", subscription, http.StatusForbidden)
+	c.tls = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: adminPrincipal}}}}
+
+	inserted := entity.TextManagement{}
+	json.Unmarshal(c.call(http.MethodPost, "/v1/text-management", map[string]any{"text_data": "text data", "encryption": false}, http.StatusOK), &inserted)
+	subscription["text_id"] = inserted.Uuid
+	created := entity.WebhookSubscription{}
+	json.Unmarshal(c.call(http.MethodPost, "/v1/webhooks", subscription, http.StatusCreated), &created)
+	if created.Secret == "" {
+		t.Errorf("POST /v1/webhooks secret is missing")
+	}
+	c.call(http.MethodPost, "/v1/webhooks", map[string]any{"url": "ftp://example.com", "events": []string{"text.created"}}, http.StatusBadRequest)
+
+	c.call(http.MethodGet, "/v1/webhooks", nil, http.StatusOK)
+	c.call(http.MethodDelete, "/v1/webhooks/"+created.Id, nil, http.StatusNoContent)
+	c.call(http.MethodDelete, "/v1/webhooks/"+created.Id, nil, http.StatusNotFound)
+	c.call(http.MethodDelete, "/v1/webhooks/invalid", nil, http.StatusBadRequest)
+}
+
+func TestContractKeyRotation(t *testing.T) {
+	c := newContract(t)
+	c.call(http.MethodPost, "/v1/text-management", map[string]any{"text_data": "text data", "encryption": false}, http.StatusOK)
+
+	c.call(http.MethodPost, "/v1/key-rotation", nil, http.StatusForbidden)
+	c.tls = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: adminPrincipal}}}}
+	c.call(http.MethodGet, "/v1/key-rotation", nil, http.StatusNotFound)
+	c.call(http.MethodPost, "/v1/key-rotation", nil, http.StatusAccepted)
+
+	rotation := entity.Rotation{}
+	for i := 0; i < 100 && rotation.Status != entity.RotationStatusDone; i++ {
+		time.Sleep(10 * time.Millisecond)
+		json.Unmarshal(c.call(http.MethodGet, "/v1/key-rotation", nil, http.StatusOK), &rotation)
+	}
+	if rotation.Status != entity.RotationStatusDone || rotation.Processed != 1 {
+		t.Errorf("GET /v1/key-rotation = %+v, want one text processed", rotation)
+	}
+}
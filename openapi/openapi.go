@@ -0,0 +1,55 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"zcelero/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var Document []byte
+
+// swaggerUI holds the Swagger UI page and the swagger-ui-dist 5.18.2 assets it loads,
+// so the documentation works offline without third-party scripts
+//
+//go:embed swagger-ui/index.html swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css swagger-ui/favicon-32x32.png
+var swaggerUI embed.FS
+
+// GetDocument serves the OpenAPI document
+func GetDocument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", Document)
+	}
+}
+
+// GetSwaggerUI serves the Swagger UI page pointing to the OpenAPI document
+func GetSwaggerUI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := fs.ReadFile(swaggerUI, "swagger-ui/index.html")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
+
+// GetSwaggerUIAsset serves the scripts, styles and images used by the Swagger UI page
+func GetSwaggerUIAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file := c.Param("file")
+		if file == "index.html" {
+			controller.NotFound(c)
+			return
+		}
+
+		asset, err := fs.ReadFile(swaggerUI, path.Join("swagger-ui", path.Base(file)))
+		if err != nil {
+			controller.NotFound(c)
+			return
+		}
+
+		c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(file)), asset)
+	}
+}
@@ -0,0 +1,41 @@
+package principal
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+type contextKey struct{}
+
+type clientIPKey struct{}
+
+// FromTLS returns the subject of the verified client certificate, empty when the client didn't send one
+func FromTLS(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return state.VerifiedChains[0][0].Subject.String()
+}
+
+// NewContext returns a copy of the context carrying the authenticated principal
+func NewContext(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the authenticated principal, empty for anonymous requests
+func FromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(contextKey{}).(string)
+	return principal
+}
+
+// NewClientIPContext returns a copy of the context carrying the address of the client
+func NewClientIPContext(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, clientIP)
+}
+
+// ClientIPFromContext returns the address of the client, empty outside of a request
+func ClientIPFromContext(ctx context.Context) string {
+	clientIP, _ := ctx.Value(clientIPKey{}).(string)
+	return clientIP
+}
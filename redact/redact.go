@@ -0,0 +1,72 @@
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces every secret value
+const Placeholder = "[REDACTED]"
+
+// Fields are the request and response fields holding secrets
+var Fields = []string{"private_key", "private_key_password", "new_private_key_password", "text_data", "secret"}
+
+var patterns = newPatterns(Fields)
+
+type pattern struct {
+	expression  *regexp.Regexp
+	replacement string
+}
+
+func newPatterns(fields []string) []pattern {
+	names := strings.Join(fields, "|")
+
+	return []pattern{
+		// PEM blocks, e.g. a private key inside an error message. A block cut short ends at the end of
+		// the JSON string holding it
+		{regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----(?:[^"]*?-----END [A-Z0-9 ]+-----|[^"]*)`), Placeholder},
+		// JSON values, "text_data": "value"
+		{regexp.MustCompile(`"(` + names + `)"(\s*:\s*)"(?:[^"\\]|\\.)*"`), `"$1"$2"` + Placeholder + `"`},
+		// JSON values inside a JSON string, as in a logged message, \"text_data\":\"value\"
+		{regexp.MustCompile(`\\"(` + names + `)\\"(\s*:\s*)\\"(?:[^\\]|\\[^"])*\\"`), `\"$1\"$2\"` + Placeholder + `\"`},
+		// form and query values, text_data=value
+		{regexp.MustCompile(`\b(` + names + `)=[^&\s"\\]*`), `$1=` + Placeholder},
+	}
+}
+
+// String replaces the values of the secret fields and any PEM block in s
+func String(s string) string {
+	for _, p := range patterns {
+		s = p.expression.ReplaceAllString(s, p.replacement)
+	}
+
+	return s
+}
+
+// Value hides a secret value, keeping empty values empty so missing fields can still be told apart
+func Value(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return Placeholder
+}
+
+type writer struct {
+	out io.Writer
+}
+
+// NewWriter redacts everything written to out, each write is expected to hold whole lines like the
+// zerolog events
+func NewWriter(out io.Writer) io.Writer {
+	return &writer{out: out}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, String(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+	"zcelero/apperror"
+	"zcelero/audit"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/logging"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultAuditLimit is the number of entries listed when the filter has no limit
+const defaultAuditLimit = 100
+
+type AuditInterface interface {
+	Append(ctx context.Context, entry entity.AuditEntry) (entity.AuditEntry, error)
+	List(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+type auditRepositoryStruct struct {
+	Helper helper.HelperInterface
+	file   string
+	key    []byte
+	// mutex keeps the appends in sequence, each one chained to the last entry written
+	mutex sync.Mutex
+	last  entity.AuditEntry
+}
+
+// NewAuditRepository appends the audit entries to the audit.log file inside the audit folder of storagePath,
+// chained with the HMAC key. The existing log is verified first, a broken chain stops the application until
+// it is investigated. A last line torn by a crash while it was appended doesn't, see removeTornLine
+func NewAuditRepository(helper helper.HelperInterface, storagePath string, key []byte) (AuditInterface, error) {
+	location := fmt.Sprintf("%s/audit", storagePath)
+	if err := helper.CreateDir(location); err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "audit storage could not be created", err)
+	}
+
+	repository := &auditRepositoryStruct{Helper: helper, file: fmt.Sprintf("%s/audit.log", location), key: key}
+	data, err := helper.ReadFile(repository.file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "audit log could not be read", err)
+	}
+	if data, err = repository.removeTornLine(data); err != nil {
+		return nil, err
+	}
+
+	repository.last, err = audit.Verify(key, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("audit log %s is not intact: %w", repository.file, err)
+	}
+
+	return repository, nil
+}
+
+// removeTornLine handles a log whose last line misses its new line, which is left by a crash in the middle of
+// an append. A complete entry is kept and its new line added, anything else is moved to audit.log.torn for
+// investigation and the log is rewritten without it
+func (a *auditRepositoryStruct) removeTornLine(data []byte) ([]byte, error) {
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if end == len(data) {
+		return data, nil
+	}
+
+	if _, err := audit.Verify(a.key, bytes.NewReader(data)); err == nil {
+		if err := a.Helper.AppendFile(a.file, "\n"); err != nil {
+			log.Error().Msg(err.Error())
+			return nil, apperror.Wrap(apperror.StorageUnavailable, "audit log could not be repaired", err)
+		}
+		log.Warn().Str("file", a.file).Msg("Audit log last entry was missing its new line, it was added")
+		return append(data, '\n'), nil
+	}
+
+	torn := a.file + ".torn"
+	if err := a.Helper.AppendFile(torn, string(data[end:])+"\n"); err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "torn audit log line could not be moved aside", err)
+	}
+	if err := a.Helper.ReplaceFile(a.file, string(data[:end])); err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "audit log could not be repaired", err)
+	}
+	log.Warn().Str("file", a.file).Str("torn_file", torn).Int("bytes", len(data)-end).Msg("Audit log last line was torn, it was moved aside")
+
+	return data[:end], nil
+}
+
+// Append chains the entry to the last one and writes it at the end of the log
+func (a *auditRepositoryStruct) Append(ctx context.Context, entry entity.AuditEntry) (chained entity.AuditEntry, err error) {
+	defer observe("audit", "append", time.Now(), &err)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	chained = audit.Chain(a.key, a.last, entry)
+	content, err := json.Marshal(chained)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return entity.AuditEntry{}, apperror.Wrap(apperror.Internal, "audit entry could not be encoded", err)
+	}
+
+	if err := a.Helper.AppendFile(a.file, string(content)+"\n"); err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return entity.AuditEntry{}, apperror.Wrap(apperror.StorageUnavailable, "audit entry could not be stored", err)
+	}
+	a.last = chained
+
+	return chained, nil
+}
+
+// List returns the newest entries matching the filter, the most recent first
+func (a *auditRepositoryStruct) List(ctx context.Context, filter entity.AuditFilter) (entries []entity.AuditEntry, err error) {
+	defer observe("audit", "list", time.Now(), &err)
+
+	data, err := a.Helper.ReadFile(a.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []entity.AuditEntry{}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "audit log could not be read", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	entries = []entity.AuditEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := entity.AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logging.FromContext(ctx).Error().Msg(err.Error())
+			return nil, apperror.Wrap(apperror.Internal, "audit log could not be decoded", err)
+		}
+		if matches(filter, entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	// newest first, keeping only the limit
+	newest := make([]entity.AuditEntry, 0, limit)
+	for i := len(entries) - 1; i >= 0 && len(newest) < limit; i-- {
+		newest = append(newest, entries[i])
+	}
+
+	return newest, nil
+}
+
+func matches(filter entity.AuditFilter, entry entity.AuditEntry) bool {
+	return (filter.Action == "" || filter.Action == entry.Action) &&
+		(filter.TextId == "" || filter.TextId == entry.TextId) &&
+		(filter.Principal == "" || filter.Principal == entry.Principal) &&
+		(filter.Since.IsZero() || !entry.Time.Before(filter.Since)) &&
+		(filter.Until.IsZero() || entry.Time.Before(filter.Until))
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	mockhelper "zcelero/mocks/helper"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var auditTime = time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+
+var auditKey = []byte("0123456789abcdef0123456789abcdef")
+
+func auditEntry(minute int, action, textId, principal string) entity.AuditEntry {
+	return entity.AuditEntry{
+		Time:      auditTime.Add(time.Duration(minute) * time.Minute),
+		Action:    action,
+		Outcome:   entity.AuditOutcomeSuccess,
+		TextId:    textId,
+		Principal: principal,
+	}
+}
+
+func Test_auditRepositoryStruct_Append(t *testing.T) {
+	storagePath := t.TempDir()
+	ctx := context.Background()
+
+	repository, err := NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+	if err != nil {
+		t.Fatalf("NewAuditRepository() error = %v", err)
+	}
+	first, _ := repository.Append(ctx, auditEntry(0, entity.AuditActionInsert, "a", ""))
+	second, _ := repository.Append(ctx, auditEntry(1, entity.AuditActionRead, "a", ""))
+	if first.Sequence != 1 || second.Sequence != 2 || second.PreviousHash != first.Hash || first.PreviousHash != "" {
+		t.Fatalf("Append() entries are not chained: %v, %v", first, second)
+	}
+
+	// a restart continues the chain
+	repository, err = NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+	if err != nil {
+		t.Fatalf("NewAuditRepository() error = %v", err)
+	}
+	third, _ := repository.Append(ctx, auditEntry(2, entity.AuditActionDelete, "a", ""))
+	if third.Sequence != 3 || third.PreviousHash != second.Hash {
+		t.Errorf("Append() after restart = %v, want chained to %v", third, second)
+	}
+
+	info, _ := os.Stat(storagePath + "/audit/audit.log")
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func Test_auditRepositoryStruct_AppendError(t *testing.T) {
+	storagePath := t.TempDir()
+	helper := &mockhelper.HelperInterface{}
+	helper.On("CreateDir", storagePath+"/audit").Return(nil)
+	helper.On("ReadFile", storagePath+"/audit/audit.log").Return(nil, os.ErrNotExist)
+	helper.On("AppendFile", storagePath+"/audit/audit.log", mock.AnythingOfType("string")).Return(errors.New("disk full")).Once()
+	helper.On("AppendFile", storagePath+"/audit/audit.log", mock.AnythingOfType("string")).Return(nil)
+
+	repository, err := NewAuditRepository(helper, storagePath, auditKey)
+	if err != nil {
+		t.Fatalf("NewAuditRepository() error = %v", err)
+	}
+	_, err = repository.Append(context.Background(), auditEntry(0, entity.AuditActionInsert, "a", ""))
+	if apperror.CodeOf(err) != apperror.StorageUnavailable {
+		t.Errorf("Append() error = %v, want %s", err, apperror.StorageUnavailable)
+	}
+
+	// the failed entry doesn't take a place in the chain
+	entry, _ := repository.Append(context.Background(), auditEntry(1, entity.AuditActionInsert, "a", ""))
+	if entry.Sequence != 1 {
+		t.Errorf("Append() sequence = %d, want 1", entry.Sequence)
+	}
+}
+
+func TestNewAuditRepository_TamperedLog(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, _ := NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+	repository.Append(context.Background(), auditEntry(0, entity.AuditActionInsert, "a", "CN=alice"))
+	repository.Append(context.Background(), auditEntry(1, entity.AuditActionRead, "a", "CN=alice"))
+
+	file := storagePath + "/audit/audit.log"
+	data, _ := os.ReadFile(file)
+	os.WriteFile(file, []byte(strings.Replace(string(data), "CN=alice", "CN=mallory", 1)), 0600)
+
+	if _, err := NewAuditRepository(helper.NewHelper(), storagePath, auditKey); err == nil || !strings.Contains(err.Error(), "entry 1 does not match its hash") {
+		t.Errorf("NewAuditRepository() error = %v, want the edited entry", err)
+	}
+}
+
+func TestNewAuditRepository_TornLastLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		tear     func(data []byte) []byte
+		wantTorn string
+	}{
+		{
+			name:     "Partial entry",
+			tear:     func(data []byte) []byte { return append(data, `{"sequence":3,"time":"2022-11`...) },
+			wantTorn: `{"sequence":3,"time":"2022-11` + "\n",
+		},
+		{
+			name: "Complete entry without its new line",
+			tear: func(data []byte) []byte { return data[:len(data)-1] },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagePath := t.TempDir()
+			ctx := context.Background()
+			repository, _ := NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+			repository.Append(ctx, auditEntry(0, entity.AuditActionInsert, "a", ""))
+			second, _ := repository.Append(ctx, auditEntry(1, entity.AuditActionRead, "a", ""))
+
+			file := storagePath + "/audit/audit.log"
+			data, _ := os.ReadFile(file)
+			os.WriteFile(file, tt.tear(data), 0600)
+
+			repository, err := NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+			if err != nil {
+				t.Fatalf("NewAuditRepository() error = %v", err)
+			}
+			if repaired, _ := os.ReadFile(file); string(repaired) != string(data) {
+				t.Errorf("audit log = %q, want %q", repaired, data)
+			}
+			torn, _ := os.ReadFile(file + ".torn")
+			if string(torn) != tt.wantTorn {
+				t.Errorf("torn audit lines = %q, want %q", torn, tt.wantTorn)
+			}
+			third, _ := repository.Append(ctx, auditEntry(2, entity.AuditActionDelete, "a", ""))
+			if third.Sequence != 3 || third.PreviousHash != second.Hash {
+				t.Errorf("Append() after repair = %v, want chained to %v", third, second)
+			}
+		})
+	}
+}
+
+func TestNewAuditRepository_OtherKey(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, _ := NewAuditRepository(helper.NewHelper(), storagePath, auditKey)
+	repository.Append(context.Background(), auditEntry(0, entity.AuditActionInsert, "a", "CN=alice"))
+
+	if _, err := NewAuditRepository(helper.NewHelper(), storagePath, []byte("another key of at least 32 bytes")); err == nil || !strings.Contains(err.Error(), "entry 1 does not match its hash") {
+		t.Errorf("NewAuditRepository() error = %v, want the entry not matching", err)
+	}
+}
+
+func Test_auditRepositoryStruct_List(t *testing.T) {
+	ctx := context.Background()
+	repository, _ := NewAuditRepository(helper.NewHelper(), t.TempDir(), auditKey)
+
+	got, err := repository.List(ctx, entity.AuditFilter{})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("List() of an empty log = %v, %v", got, err)
+	}
+
+	repository.Append(ctx, auditEntry(0, entity.AuditActionInsert, "a", "CN=alice"))
+	repository.Append(ctx, auditEntry(1, entity.AuditActionRead, "a", "CN=bob"))
+	repository.Append(ctx, auditEntry(2, entity.AuditActionInsert, "b", "CN=alice"))
+	repository.Append(ctx, auditEntry(3, entity.AuditActionDelete, "a", "CN=alice"))
+
+	tests := []struct {
+		name         string
+		filter       entity.AuditFilter
+		wantSequence []uint64
+	}{
+		{name: "Every entry, newest first", filter: entity.AuditFilter{}, wantSequence: []uint64{4, 3, 2, 1}},
+		{name: "By text", filter: entity.AuditFilter{TextId: "a"}, wantSequence: []uint64{4, 2, 1}},
+		{name: "By action", filter: entity.AuditFilter{Action: entity.AuditActionInsert}, wantSequence: []uint64{3, 1}},
+		{name: "By principal", filter: entity.AuditFilter{Principal: "CN=bob"}, wantSequence: []uint64{2}},
+		{name: "By time", filter: entity.AuditFilter{Since: auditTime.Add(time.Minute), Until: auditTime.Add(3 * time.Minute)}, wantSequence: []uint64{3, 2}},
+		{name: "Limited", filter: entity.AuditFilter{Limit: 2}, wantSequence: []uint64{4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repository.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			sequences := []uint64{}
+			for _, entry := range got {
+				sequences = append(sequences, entry.Sequence)
+			}
+			if len(sequences) != len(tt.wantSequence) {
+				t.Fatalf("List() = %v, want %v", sequences, tt.wantSequence)
+			}
+			for i := range sequences {
+				if sequences[i] != tt.wantSequence[i] {
+					t.Errorf("List() = %v, want %v", sequences, tt.wantSequence)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"zcelero/envelope"
+	"zcelero/logging"
+	"zcelero/tracing"
+)
+
+type EncryptedInterface interface {
+	TextManagementInterface
+	Rewrap(ctx context.Context, fileName string) (bool, error)
+}
+
+type encryptedRepositoryStruct struct {
+	TextManagementRepository TextManagementInterface
+	Keyring                  envelope.KeyringInterface
+	// mutex keeps Rewrap from writing back a text that Delete removed or Replace changed in the meantime
+	mutex sync.Mutex
+}
+
+// NewEncryptedRepository seals every text saved into the repository with a data key wrapped by the primary
+// master key of the keyring, and opens them when loaded. Texts saved before the encryption was enabled are
+// loaded as they are
+func NewEncryptedRepository(textManagementRepository TextManagementInterface, keyring envelope.KeyringInterface) EncryptedInterface {
+	return &encryptedRepositoryStruct{TextManagementRepository: textManagementRepository, Keyring: keyring}
+}
+
+// Save seals the content before saving it, the file name is bound to it so it can't be moved to another text
+func (e *encryptedRepositoryStruct) Save(ctx context.Context, fileName string, content string) error {
+	_, span := tracing.Start(ctx, "repository.Seal")
+	sealed, err := envelope.Seal(e.Keyring, fileName, []byte(content))
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return err
+	}
+
+	return e.TextManagementRepository.Save(ctx, fileName, string(sealed))
+}
+
+// Load opens the sealed content after loading it
+func (e *encryptedRepositoryStruct) Load(ctx context.Context, fileName string) ([]byte, error) {
+	data, err := e.TextManagementRepository.Load(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := tracing.Start(ctx, "repository.Open")
+	content, err := envelope.Open(e.Keyring, fileName, data)
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func (e *encryptedRepositoryStruct) Delete(ctx context.Context, fileName string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.TextManagementRepository.Delete(ctx, fileName)
+}
+
+// Replace seals the new content like Save before replacing the stored one
+func (e *encryptedRepositoryStruct) Replace(ctx context.Context, fileName string, content string) error {
+	_, span := tracing.Start(ctx, "repository.Seal")
+	sealed, err := envelope.Seal(e.Keyring, fileName, []byte(content))
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.TextManagementRepository.Replace(ctx, fileName, string(sealed))
+}
+
+func (e *encryptedRepositoryStruct) List(ctx context.Context) ([]string, error) {
+	return e.TextManagementRepository.List(ctx)
+}
+
+func (e *encryptedRepositoryStruct) Usage() (texts int, bytes int64, err error) {
+	return e.TextManagementRepository.Usage()
+}
+
+// Rewrap wraps the data key of the text again with the primary master key, sealing the texts saved before
+// the encryption was enabled. The content itself isn't decrypted. It tells whether the text was rewritten,
+// texts already under the primary key are left as they are. The file is replaced, so a crash leaves either
+// the old record or the new one
+func (e *encryptedRepositoryStruct) Rewrap(ctx context.Context, fileName string) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	data, err := e.TextManagementRepository.Load(ctx, fileName)
+	if err != nil {
+		return false, err
+	}
+
+	rewrapped, changed, err := envelope.Rewrap(e.Keyring, fileName, data)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	return true, e.TextManagementRepository.Replace(ctx, fileName, string(rewrapped))
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"zcelero/apperror"
+	"zcelero/envelope"
+	"zcelero/helper"
+)
+
+func Test_encryptedRepositoryStruct(t *testing.T) {
+	ctx := context.Background()
+	storagePath := t.TempDir()
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	content := `{"Content":"text data","Encrypted":false}`
+	keyring, _ := envelope.NewKeyring(map[string][]byte{"2024-01": bytes.Repeat([]byte{1}, 32)}, "2024-01")
+	plainRepository := NewRepository(helper.NewHelper(), storagePath)
+	repository := NewEncryptedRepository(plainRepository, keyring)
+
+	if err := repository.Save(ctx, textId, content); err != nil {
+		t.Fatalf("encryptedRepositoryStruct.Save() error = %v", err)
+	}
+	stored, _ := os.ReadFile(storagePath + "/" + textId + ".json")
+	if record, sealed := envelope.Parse(stored); !sealed || record.KeyId != "2024-01" || bytes.Contains(stored, []byte("text data")) {
+		t.Errorf("stored file = %s, want a record sealed with 2024-01", stored)
+	}
+
+	got, err := repository.Load(ctx, textId)
+	if err != nil {
+		t.Fatalf("encryptedRepositoryStruct.Load() error = %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("encryptedRepositoryStruct.Load() = %s, want %s", got, content)
+	}
+
+	// texts saved before the encryption was enabled are still read
+	legacyId := "2f13ed58-afc9-477a-bf0d-c90eb1b7db90"
+	plainRepository.Save(ctx, legacyId, content)
+	if got, err := repository.Load(ctx, legacyId); err != nil || string(got) != content {
+		t.Errorf("encryptedRepositoryStruct.Load() = %s, %v, want %s", got, err, content)
+	}
+
+	// a sealed file copied over another text is rejected
+	os.WriteFile(storagePath+"/"+legacyId+".json", stored, 0600)
+	if _, err := repository.Load(ctx, legacyId); apperror.CodeOf(err) != apperror.Internal {
+		t.Errorf("encryptedRepositoryStruct.Load() error = %v, want %v", err, apperror.Internal)
+	}
+
+	if _, err := repository.Load(ctx, "6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11"); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("encryptedRepositoryStruct.Load() error = %v, want %v", err, apperror.NotFound)
+	}
+
+	replaced := `{"Content":"new text data","Encrypted":false}`
+	if err := repository.Replace(ctx, textId, replaced); err != nil {
+		t.Fatalf("encryptedRepositoryStruct.Replace() error = %v", err)
+	}
+	if stored, _ := os.ReadFile(storagePath + "/" + textId + ".json"); bytes.Contains(stored, []byte("new text data")) {
+		t.Errorf("stored file = %s, want a sealed record", stored)
+	}
+	if got, err := repository.Load(ctx, textId); err != nil || string(got) != replaced {
+		t.Errorf("encryptedRepositoryStruct.Load() = %s, %v, want %s", got, err, replaced)
+	}
+
+	if err := repository.Delete(ctx, textId); err != nil {
+		t.Errorf("encryptedRepositoryStruct.Delete() error = %v", err)
+	}
+}
+
+func Test_encryptedRepositoryStruct_Rewrap(t *testing.T) {
+	ctx := context.Background()
+	storagePath := t.TempDir()
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	legacyId := "2f13ed58-afc9-477a-bf0d-c90eb1b7db90"
+	content := `{"Content":"text data","Encrypted":false}`
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	old, _ := envelope.NewKeyring(map[string][]byte{"2024-01": oldKey}, "2024-01")
+	rotated, _ := envelope.NewKeyring(map[string][]byte{"2024-01": oldKey, "2024-06": newKey}, "2024-06")
+	plainRepository := NewRepository(helper.NewHelper(), storagePath)
+	NewEncryptedRepository(plainRepository, old).Save(ctx, textId, content)
+	plainRepository.Save(ctx, legacyId, content)
+	repository := NewEncryptedRepository(plainRepository, rotated)
+
+	for _, fileName := range []string{textId, legacyId} {
+		// the file is replaced by a new one, a reader of the old one still gets the whole old record
+		filePath := fmt.Sprintf("%s/%s.json", storagePath, fileName)
+		before, _ := os.ReadFile(filePath)
+		reader, _ := os.Open(filePath)
+		defer reader.Close()
+		opened, _ := reader.Stat()
+		if changed, err := repository.Rewrap(ctx, fileName); err != nil || !changed {
+			t.Errorf("encryptedRepositoryStruct.Rewrap(%s) = %v, %v, want true, nil", fileName, changed, err)
+		}
+		if replaced, _ := os.Stat(filePath); os.SameFile(opened, replaced) {
+			t.Errorf("encryptedRepositoryStruct.Rewrap(%s) rewrote the file in place", fileName)
+		}
+		if read, _ := io.ReadAll(reader); !bytes.Equal(read, before) {
+			t.Errorf("old file of %s = %s, want %s", fileName, read, before)
+		}
+		if changed, err := repository.Rewrap(ctx, fileName); err != nil || changed {
+			t.Errorf("encryptedRepositoryStruct.Rewrap(%s) again = %v, %v, want false, nil", fileName, changed, err)
+		}
+		stored, _ := plainRepository.Load(ctx, fileName)
+		if record, sealed := envelope.Parse(stored); !sealed || record.KeyId != "2024-06" {
+			t.Errorf("stored file = %s, want a record sealed with 2024-06", stored)
+		}
+		if got, err := repository.Load(ctx, fileName); err != nil || string(got) != content {
+			t.Errorf("encryptedRepositoryStruct.Load(%s) = %s, %v, want %s", fileName, got, err, content)
+		}
+	}
+
+	repository.Delete(ctx, textId)
+	if _, err := repository.Rewrap(ctx, textId); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("encryptedRepositoryStruct.Rewrap() error = %v, want %v", err, apperror.NotFound)
+	}
+}
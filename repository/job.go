@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+
+	"github.com/rs/zerolog/log"
+)
+
+type JobInterface interface {
+	Save(job entity.Job) error
+	Load(jobId string) (entity.Job, error)
+	List() ([]entity.Job, error)
+	Delete(jobId string) error
+}
+
+type jobRepositoryStruct struct {
+	Helper   helper.HelperInterface
+	location string
+}
+
+// NewJobRepository stores the jobs in the jobs folder inside storagePath, creating it when missing
+func NewJobRepository(helper helper.HelperInterface, storagePath string) (JobInterface, error) {
+	jobLocation := fmt.Sprintf("%s/jobs", storagePath)
+	err := helper.CreateDir(jobLocation)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "job storage could not be created", err)
+	}
+
+	return &jobRepositoryStruct{Helper: helper, location: jobLocation}, nil
+}
+
+// Save writes the job state into its file
+func (j *jobRepositoryStruct) Save(job entity.Job) (err error) {
+	defer observe("job", "save", time.Now(), &err)
+
+	log.Debug().Str("job_id", job.Id).Msg("Saving job state")
+
+	content, err := json.Marshal(job)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.Internal, "job could not be encoded", err)
+	}
+
+	file, err := j.Helper.CreateFile(fmt.Sprintf("%s/%s.json", j.location, job.Id))
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "job could not be stored", err)
+	}
+	defer file.Close()
+
+	_, err = j.Helper.WriteFile(file, string(content))
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "job could not be stored", err)
+	}
+
+	return nil
+}
+
+// Load reads the job state from its file
+func (j *jobRepositoryStruct) Load(jobId string) (job entity.Job, err error) {
+	defer observe("job", "load", time.Now(), &err)
+
+	log.Debug().Str("job_id", jobId).Msg("Reading job state")
+
+	data, err := j.Helper.ReadFile(fmt.Sprintf("%s/%s.json", j.location, jobId))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Info().Msg(err.Error())
+		return entity.Job{}, apperror.Wrap(apperror.NotFound, "job not found", err)
+	}
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return entity.Job{}, apperror.Wrap(apperror.StorageUnavailable, "job could not be read", err)
+	}
+
+	err = json.Unmarshal(data, &job)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return entity.Job{}, apperror.Wrap(apperror.StorageUnavailable, "job could not be read", err)
+	}
+
+	return job, nil
+}
+
+// Delete removes the job file
+func (j *jobRepositoryStruct) Delete(jobId string) (err error) {
+	defer observe("job", "delete", time.Now(), &err)
+
+	log.Debug().Str("job_id", jobId).Msg("Removing job")
+
+	err = j.Helper.RemoveFile(fmt.Sprintf("%s/%s.json", j.location, jobId))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Info().Msg(err.Error())
+		return apperror.Wrap(apperror.NotFound, "job not found", err)
+	}
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "job could not be removed", err)
+	}
+
+	return nil
+}
+
+// List reads every stored job, skipping the files that cannot be read
+func (j *jobRepositoryStruct) List() (jobs []entity.Job, err error) {
+	defer observe("job", "list", time.Now(), &err)
+
+	entries, err := j.Helper.ReadDir(j.location)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "jobs could not be listed", err)
+	}
+
+	jobs = []entity.Job{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		job, err := j.Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			log.Error().Str("file", entry.Name()).Msg("skipping unreadable job file")
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
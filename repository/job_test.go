@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	mockhelper "zcelero/mocks/helper"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_jobRepositoryStruct_Save(t *testing.T) {
+	storagePath := t.TempDir()
+	jobLocation := storagePath + "/jobs"
+	job := entity.Job{
+		Id:     "47b416d1-c5f2-417e-929e-7b83667c6654",
+		Status: entity.JobStatusPending,
+	}
+	type fields struct {
+		Helper helper.HelperInterface
+	}
+	type args struct {
+		job entity.Job
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mockBehavior   func(f fields, a args)
+		assertBehavior func(t *testing.T, f fields)
+		wantErr        bool
+	}{
+		{
+			name:   "Save job",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{job: job},
+			mockBehavior: func(f fields, a args) {
+				filePath := fmt.Sprintf("%s/%s.json", jobLocation, a.job.Id)
+				file, _ := os.Create(filePath)
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("CreateFile", filePath).Return(file, nil)
+				f.Helper.(*mockhelper.HelperInterface).On("WriteFile", file, mock.AnythingOfType("string")).Return(1, nil)
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Save job with creation error",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{job: job},
+			mockBehavior: func(f fields, a args) {
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("CreateFile", fmt.Sprintf("%s/%s.json", jobLocation, a.job.Id)).Return(nil, errors.New("error"))
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Save job with write error",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{job: job},
+			mockBehavior: func(f fields, a args) {
+				filePath := fmt.Sprintf("%s/%s.json", jobLocation, a.job.Id)
+				file, _ := os.Create(filePath)
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("CreateFile", filePath).Return(file, nil)
+				f.Helper.(*mockhelper.HelperInterface).On("WriteFile", file, mock.AnythingOfType("string")).Return(0, errors.New("error"))
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(tt.fields, tt.args)
+			}
+
+			repository, _ := NewJobRepository(tt.fields.Helper, storagePath)
+			if err := repository.Save(tt.args.job); (err != nil) != tt.wantErr {
+				t.Errorf("jobRepositoryStruct.Save() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.assertBehavior != nil {
+				tt.assertBehavior(t, tt.fields)
+			}
+		})
+	}
+}
+
+func Test_jobRepositoryStruct_Load(t *testing.T) {
+	storagePath := t.TempDir()
+	jobLocation := storagePath + "/jobs"
+	createdAt := time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+	job := entity.Job{
+		Id:        "47b416d1-c5f2-417e-929e-7b83667c6654",
+		Status:    entity.JobStatusDone,
+		Uuid:      "2f13ed58-afc9-477a-bf0d-c90eb1b7db90",
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	content := `{"id":"47b416d1-c5f2-417e-929e-7b83667c6654","status":"done","uuid":"2f13ed58-afc9-477a-bf0d-c90eb1b7db90","key_delivered":false,"created_at":"2022-11-10T00:00:00Z","updated_at":"2022-11-10T00:00:00Z"}`
+	type fields struct {
+		Helper helper.HelperInterface
+	}
+	type args struct {
+		jobId string
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mockBehavior   func(f fields, a args)
+		assertBehavior func(t *testing.T, f fields)
+		want           entity.Job
+		wantErr        bool
+	}{
+		{
+			name:   "Load job",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{jobId: job.Id},
+			mockBehavior: func(f fields, a args) {
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("ReadFile", fmt.Sprintf("%s/%s.json", jobLocation, a.jobId)).Return([]byte(content), nil)
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			want:    job,
+			wantErr: false,
+		},
+		{
+			name:   "Load job with read error",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{jobId: job.Id},
+			mockBehavior: func(f fields, a args) {
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("ReadFile", fmt.Sprintf("%s/%s.json", jobLocation, a.jobId)).Return(nil, errors.New("error"))
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			want:    entity.Job{},
+			wantErr: true,
+		},
+		{
+			name:   "Load job with invalid content",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args:   args{jobId: job.Id},
+			mockBehavior: func(f fields, a args) {
+				f.Helper.(*mockhelper.HelperInterface).On("CreateDir", jobLocation).Return(nil)
+				f.Helper.(*mockhelper.HelperInterface).On("ReadFile", fmt.Sprintf("%s/%s.json", jobLocation, a.jobId)).Return([]byte("{"), nil)
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			want:    entity.Job{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(tt.fields, tt.args)
+			}
+
+			repository, _ := NewJobRepository(tt.fields.Helper, storagePath)
+			got, err := repository.Load(tt.args.jobId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("jobRepositoryStruct.Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jobRepositoryStruct.Load() = %v, want %v", got, tt.want)
+			}
+
+			if tt.assertBehavior != nil {
+				tt.assertBehavior(t, tt.fields)
+			}
+		})
+	}
+}
+
+func Test_jobRepositoryStruct_List(t *testing.T) {
+	storagePath := t.TempDir()
+	jobLocation := storagePath + "/jobs"
+	repository, err := NewJobRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewJobRepository() error = %v", err)
+	}
+
+	pending := entity.Job{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.JobStatusPending}
+	done := entity.Job{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Status: entity.JobStatusDone}
+	repository.Save(pending)
+	repository.Save(done)
+	os.WriteFile(fmt.Sprintf("%s/notes.txt", jobLocation), []byte("not a job"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11.json", jobLocation), []byte("{corrupt"), 0600)
+
+	got, err := repository.List()
+	if err != nil {
+		t.Fatalf("jobRepositoryStruct.List() error = %v", err)
+	}
+	want := []entity.Job{done, pending}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jobRepositoryStruct.List() = %v, want %v", got, want)
+	}
+}
+
+func Test_jobRepositoryStruct_Delete(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, err := NewJobRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewJobRepository() error = %v", err)
+	}
+
+	job := entity.Job{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.JobStatusDone, PrivateKey: "private key"}
+	repository.Save(job)
+	info, err := os.Stat(fmt.Sprintf("%s/jobs/%s.json", storagePath, job.Id))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("job file mode = %v, %v, want %v", info.Mode().Perm(), err, os.FileMode(0600))
+	}
+
+	if err := repository.Delete(job.Id); err != nil {
+		t.Errorf("jobRepositoryStruct.Delete() error = %v", err)
+	}
+	if _, err := repository.Load(job.Id); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("jobRepositoryStruct.Load() error = %v, want %v", err, apperror.NotFound)
+	}
+	if err := repository.Delete(job.Id); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("jobRepositoryStruct.Delete() error = %v, want %v", err, apperror.NotFound)
+	}
+}
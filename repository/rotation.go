@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+
+	"github.com/rs/zerolog/log"
+)
+
+type RotationInterface interface {
+	Save(rotation entity.Rotation) error
+	Load() (entity.Rotation, error)
+}
+
+type rotationRepositoryStruct struct {
+	Helper helper.HelperInterface
+	path   string
+}
+
+// NewRotationRepository stores the state of the last master key rotation in the rotation folder inside
+// storagePath, so an interrupted rotation can be resumed
+func NewRotationRepository(helper helper.HelperInterface, storagePath string) (RotationInterface, error) {
+	location := fmt.Sprintf("%s/rotation", storagePath)
+	err := helper.CreateDir(location)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "rotation storage could not be created", err)
+	}
+
+	return &rotationRepositoryStruct{Helper: helper, path: location + "/rotation.json"}, nil
+}
+
+// Save writes the rotation state, replacing the previous one
+func (r *rotationRepositoryStruct) Save(rotation entity.Rotation) (err error) {
+	defer observe("rotation", "save", time.Now(), &err)
+
+	content, err := json.Marshal(rotation)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.Internal, "rotation could not be encoded", err)
+	}
+
+	file, err := r.Helper.CreateFile(r.path)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "rotation could not be stored", err)
+	}
+	defer file.Close()
+
+	_, err = r.Helper.WriteFile(file, string(content))
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "rotation could not be stored", err)
+	}
+
+	return nil
+}
+
+// Load reads the state of the last rotation
+func (r *rotationRepositoryStruct) Load() (rotation entity.Rotation, err error) {
+	defer observe("rotation", "load", time.Now(), &err)
+
+	data, err := r.Helper.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entity.Rotation{}, apperror.Wrap(apperror.NotFound, "no master key rotation has run", err)
+	}
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return entity.Rotation{}, apperror.Wrap(apperror.StorageUnavailable, "rotation could not be read", err)
+	}
+
+	err = json.Unmarshal(data, &rotation)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return entity.Rotation{}, apperror.Wrap(apperror.StorageUnavailable, "rotation could not be read", err)
+	}
+
+	return rotation, nil
+}
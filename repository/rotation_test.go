@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+)
+
+func Test_rotationRepositoryStruct(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, err := NewRotationRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewRotationRepository() error = %v", err)
+	}
+
+	if _, err := repository.Load(); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("rotationRepositoryStruct.Load() error = %v, want %v", err, apperror.NotFound)
+	}
+
+	startedAt := time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+	rotation := entity.Rotation{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Status: entity.RotationStatusRunning, KeyId: "2024-06", Total: 3, Processed: 1, Cursor: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", StartedAt: startedAt, UpdatedAt: startedAt}
+	for _, processed := range []int{1, 2} {
+		rotation.Processed = processed
+		if err := repository.Save(rotation); err != nil {
+			t.Fatalf("rotationRepositoryStruct.Save() error = %v", err)
+		}
+	}
+
+	got, err := repository.Load()
+	if err != nil {
+		t.Fatalf("rotationRepositoryStruct.Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, rotation) {
+		t.Errorf("rotationRepositoryStruct.Load() = %v, want %v", got, rotation)
+	}
+
+	os.WriteFile(fmt.Sprintf("%s/rotation/rotation.json", storagePath), []byte("{corrupt"), 0600)
+	if _, err := repository.Load(); apperror.CodeOf(err) != apperror.StorageUnavailable {
+		t.Errorf("rotationRepositoryStruct.Load() error = %v, want %v", err, apperror.StorageUnavailable)
+	}
+}
@@ -1,56 +1,186 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"zcelero/apperror"
 	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/metrics"
+	"zcelero/tracing"
 
 	"github.com/rs/zerolog/log"
 )
 
 type TextManagementInterface interface {
-	Save(fileName string, content string) error
-	Load(fileName string) ([]byte, error)
+	Save(ctx context.Context, fileName string, content string) error
+	Load(ctx context.Context, fileName string) ([]byte, error)
+	Delete(ctx context.Context, fileName string) error
+	Replace(ctx context.Context, fileName string, content string) error
+	List(ctx context.Context) ([]string, error)
+	Usage() (texts int, bytes int64, err error)
 }
 
 type textManagementRepositoryStruct struct {
-	Helper helper.HelperInterface
+	Helper   helper.HelperInterface
+	location string
+	// mutex keeps Replace from writing back a text that Delete removed in the meantime
+	mutex sync.Mutex
 }
 
-func NewRepository(helper helper.HelperInterface) TextManagementInterface {
-	return &textManagementRepositoryStruct{Helper: helper}
+// NewRepository stores the texts as JSON files inside the storagePath folder
+func NewRepository(helper helper.HelperInterface, storagePath string) TextManagementInterface {
+	return &textManagementRepositoryStruct{Helper: helper, location: storagePath}
 }
 
-var fileLocation = "storage"
-
 // Save saves the file into folder
-func (t *textManagementRepositoryStruct) Save(fileName string, content string) error {
-	log.Debug().Msg("Creating file")
+func (t *textManagementRepositoryStruct) Save(ctx context.Context, fileName string, content string) (err error) {
+	_, span := tracing.Start(ctx, "repository.Save")
+	defer func() { tracing.End(span, err) }()
+	defer observe("text", "save", time.Now(), &err)
+
+	logging.FromContext(ctx).Debug().Msg("Creating file")
 
-	file, err := t.Helper.CreateFile(fmt.Sprintf("%s/%s.json", fileLocation, fileName))
+	file, err := t.Helper.CreateFile(fmt.Sprintf("%s/%s.json", t.location, fileName))
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return err
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "text could not be stored", err)
 	}
+	defer file.Close()
 
-	log.Debug().Msg("Writing data inside file")
+	logging.FromContext(ctx).Debug().Msg("Writing data inside file")
 
 	_, err = t.Helper.WriteFile(file, content)
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return err
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "text could not be stored", err)
 	}
 
 	return nil
 }
 
 // Load reads the file into memory
-func (t *textManagementRepositoryStruct) Load(fileName string) ([]byte, error) {
-	log.Debug().Msg("Reading file")
-	data, err := t.Helper.ReadFile(fmt.Sprintf("%s/%s.json", fileLocation, fileName))
+func (t *textManagementRepositoryStruct) Load(ctx context.Context, fileName string) (data []byte, err error) {
+	_, span := tracing.Start(ctx, "repository.Load")
+	defer func() { tracing.End(span, err) }()
+	defer observe("text", "load", time.Now(), &err)
+
+	logging.FromContext(ctx).Debug().Msg("Reading file")
+	data, err = t.Helper.ReadFile(fmt.Sprintf("%s/%s.json", t.location, fileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		logging.FromContext(ctx).Info().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.NotFound, "text not found", err)
+	}
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return nil, err
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "text could not be read", err)
 	}
 
 	return data, nil
 }
+
+// Delete removes the file from folder
+func (t *textManagementRepositoryStruct) Delete(ctx context.Context, fileName string) (err error) {
+	_, span := tracing.Start(ctx, "repository.Delete")
+	defer func() { tracing.End(span, err) }()
+	defer observe("text", "delete", time.Now(), &err)
+
+	logging.FromContext(ctx).Debug().Msg("Removing file")
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	err = t.Helper.RemoveFile(fmt.Sprintf("%s/%s.json", t.location, fileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		logging.FromContext(ctx).Info().Msg(err.Error())
+		return apperror.Wrap(apperror.NotFound, "text not found", err)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "text could not be removed", err)
+	}
+
+	return nil
+}
+
+// Replace writes the new content of an existing file at once, readers never see a partial file
+func (t *textManagementRepositoryStruct) Replace(ctx context.Context, fileName string, content string) (err error) {
+	_, span := tracing.Start(ctx, "repository.Replace")
+	defer func() { tracing.End(span, err) }()
+	defer observe("text", "replace", time.Now(), &err)
+
+	logging.FromContext(ctx).Debug().Msg("Replacing file")
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	err = t.Helper.ReplaceFile(fmt.Sprintf("%s/%s.json", t.location, fileName), content)
+	if errors.Is(err, fs.ErrNotExist) {
+		logging.FromContext(ctx).Info().Msg(err.Error())
+		return apperror.Wrap(apperror.NotFound, "text not found", err)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "text could not be stored", err)
+	}
+
+	return nil
+}
+
+// List returns the names of the stored texts, sorted, the subfolders of the other repositories are skipped
+func (t *textManagementRepositoryStruct) List(ctx context.Context) (fileNames []string, err error) {
+	_, span := tracing.Start(ctx, "repository.List")
+	defer func() { tracing.End(span, err) }()
+	defer observe("text", "list", time.Now(), &err)
+
+	entries, err := t.Helper.ReadDir(t.location)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return nil, apperror.Wrap(apperror.StorageUnavailable, "texts could not be listed", err)
+	}
+
+	fileNames = []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		fileNames = append(fileNames, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(fileNames)
+
+	return fileNames, nil
+}
+
+// Usage counts the stored texts and adds up their size, the jobs folder is not included
+func (t *textManagementRepositoryStruct) Usage() (texts int, bytes int64, err error) {
+	defer observe("text", "usage", time.Now(), &err)
+
+	entries, err := t.Helper.ReadDir(t.location)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 0, 0, apperror.Wrap(apperror.StorageUnavailable, "texts could not be listed", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// removed since the folder was listed
+			continue
+		}
+		texts++
+		bytes += info.Size()
+	}
+
+	return texts, bytes, nil
+}
+
+// observe records the duration and result of a storage operation, err points to the returned error
+func observe(repository, operation string, start time.Time, err *error) {
+	metrics.RepositoryDuration.WithLabelValues(repository, operation, metrics.Result(*err)).Observe(metrics.Since(start))
+}
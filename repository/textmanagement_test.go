@@ -1,17 +1,21 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"reflect"
 	"testing"
+	"zcelero/apperror"
 	"zcelero/helper"
 	mockhelper "zcelero/mocks/helper"
 )
 
 func Test_textManagementRepositoryStruct_Save(t *testing.T) {
-	fileLocation = t.TempDir()
+	fileLocation := t.TempDir()
+	var file *os.File
 	type fields struct {
 		Helper helper.HelperInterface
 	}
@@ -35,12 +39,15 @@ func Test_textManagementRepositoryStruct_Save(t *testing.T) {
 				content:  `{content:"base64",encrypted:true}`,
 			},
 			mockBehavior: func(f fields, a args) {
-				file := os.NewFile(uintptr(10), fmt.Sprintf("%s/%s.json", fileLocation, a.fileName))
+				file, _ = os.Create(fmt.Sprintf("%s/%s.json", fileLocation, a.fileName))
 				f.Helper.(*mockhelper.HelperInterface).On("CreateFile", fmt.Sprintf("%s/%s.json", fileLocation, a.fileName)).Return(file, nil)
 				f.Helper.(*mockhelper.HelperInterface).On("WriteFile", file, a.content).Return(len([]byte(a.content)), nil)
 			},
 			assertBehavior: func(t *testing.T, f fields) {
 				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+				if err := file.Close(); !errors.Is(err, os.ErrClosed) {
+					t.Errorf("textManagementRepositoryStruct.Save() left the file open")
+				}
 			},
 			wantErr: false,
 		},
@@ -67,12 +74,15 @@ func Test_textManagementRepositoryStruct_Save(t *testing.T) {
 				content:  `{content:"base64",encrypted:true}`,
 			},
 			mockBehavior: func(f fields, a args) {
-				file := os.NewFile(uintptr(10), fmt.Sprintf("%s/%s.json", fileLocation, a.fileName))
+				file, _ = os.Create(fmt.Sprintf("%s/%s.json", fileLocation, a.fileName))
 				f.Helper.(*mockhelper.HelperInterface).On("CreateFile", fmt.Sprintf("%s/%s.json", fileLocation, a.fileName)).Return(file, nil)
 				f.Helper.(*mockhelper.HelperInterface).On("WriteFile", file, a.content).Return(0, errors.New("error"))
 			},
 			assertBehavior: func(t *testing.T, f fields) {
 				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+				if err := file.Close(); !errors.Is(err, os.ErrClosed) {
+					t.Errorf("textManagementRepositoryStruct.Save() left the file open")
+				}
 			},
 			wantErr: true,
 		},
@@ -83,8 +93,8 @@ func Test_textManagementRepositoryStruct_Save(t *testing.T) {
 				tt.mockBehavior(tt.fields, tt.args)
 			}
 
-			repository := NewRepository(tt.fields.Helper)
-			if err := repository.Save(tt.args.fileName, tt.args.content); (err != nil) != tt.wantErr {
+			repository := NewRepository(tt.fields.Helper, fileLocation)
+			if err := repository.Save(context.Background(), tt.args.fileName, tt.args.content); (err != nil) != tt.wantErr {
 				t.Errorf("textManagementRepositoryStruct.Save() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -96,6 +106,7 @@ func Test_textManagementRepositoryStruct_Save(t *testing.T) {
 }
 
 func Test_textManagementRepositoryStruct_Load(t *testing.T) {
+	fileLocation := "storage"
 	content := `{content:"base64",encrypted:true}`
 	type fields struct {
 		Helper helper.HelperInterface
@@ -142,6 +153,21 @@ func Test_textManagementRepositoryStruct_Load(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name:   "Load file not found",
+			fields: fields{&mockhelper.HelperInterface{}},
+			args: args{
+				fileName: "47b416d1-c5f2-417e-929e-7b83667c6654",
+			},
+			mockBehavior: func(f fields, a args) {
+				f.Helper.(*mockhelper.HelperInterface).On("ReadFile", fmt.Sprintf("%s/%s.json", fileLocation, a.fileName)).Return(nil, fs.ErrNotExist)
+			},
+			assertBehavior: func(t *testing.T, f fields) {
+				f.Helper.(*mockhelper.HelperInterface).AssertExpectations(t)
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -149,8 +175,11 @@ func Test_textManagementRepositoryStruct_Load(t *testing.T) {
 				tt.mockBehavior(tt.fields, tt.args)
 			}
 
-			repository := NewRepository(tt.fields.Helper)
-			got, err := repository.Load(tt.args.fileName)
+			repository := NewRepository(tt.fields.Helper, fileLocation)
+			got, err := repository.Load(context.Background(), tt.args.fileName)
+			if errors.Is(err, fs.ErrNotExist) && apperror.CodeOf(err) != apperror.NotFound {
+				t.Errorf("textManagementRepositoryStruct.Load() error code = %v, want %v", apperror.CodeOf(err), apperror.NotFound)
+			}
 			if (err != nil) != tt.wantErr {
 				t.Errorf("textManagementRepositoryStruct.Load() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -165,3 +194,171 @@ func Test_textManagementRepositoryStruct_Load(t *testing.T) {
 		})
 	}
 }
+
+func Test_textManagementRepositoryStruct_Delete(t *testing.T) {
+	fileLocation := "storage"
+	tests := []struct {
+		name      string
+		removeErr error
+		wantCode  apperror.Code
+		wantErr   bool
+	}{
+		{
+			name:      "Delete file",
+			removeErr: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "Delete file not found",
+			removeErr: fs.ErrNotExist,
+			wantCode:  apperror.NotFound,
+			wantErr:   true,
+		},
+		{
+			name:      "Delete file with error",
+			removeErr: errors.New("error"),
+			wantCode:  apperror.StorageUnavailable,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := "47b416d1-c5f2-417e-929e-7b83667c6654"
+			helper := &mockhelper.HelperInterface{}
+			helper.On("RemoveFile", fmt.Sprintf("%s/%s.json", fileLocation, fileName)).Return(tt.removeErr)
+
+			err := NewRepository(helper, fileLocation).Delete(context.Background(), fileName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("textManagementRepositoryStruct.Delete() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && apperror.CodeOf(err) != tt.wantCode {
+				t.Errorf("textManagementRepositoryStruct.Delete() error code = %v, want %v", apperror.CodeOf(err), tt.wantCode)
+			}
+
+			helper.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_textManagementRepositoryStruct_Replace(t *testing.T) {
+	ctx := context.Background()
+	fileLocation := t.TempDir()
+	fileName := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	os.WriteFile(fmt.Sprintf("%s/%s.json", fileLocation, fileName), []byte(`{"Content":"old text"}`), 0600)
+	repository := NewRepository(helper.NewHelper(), fileLocation)
+
+	if err := repository.Replace(ctx, fileName, `{"Content":"new"}`); err != nil {
+		t.Fatalf("textManagementRepositoryStruct.Replace() error = %v", err)
+	}
+	if got, _ := os.ReadFile(fmt.Sprintf("%s/%s.json", fileLocation, fileName)); string(got) != `{"Content":"new"}` {
+		t.Errorf("stored file = %s, want the new content", got)
+	}
+	// the temporary file is renamed, nothing is left behind
+	if entries, _ := os.ReadDir(fileLocation); len(entries) != 1 {
+		t.Errorf("storage has %d files, want 1", len(entries))
+	}
+
+	err := repository.Replace(ctx, "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", `{"Content":"new"}`)
+	if apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("textManagementRepositoryStruct.Replace() error = %v, want %v", err, apperror.NotFound)
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s/154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec.json", fileLocation)); err == nil {
+		t.Error("textManagementRepositoryStruct.Replace() created a missing text")
+	}
+
+	helper := &mockhelper.HelperInterface{}
+	helper.On("ReplaceFile", fmt.Sprintf("storage/%s.json", fileName), "content").Return(errors.New("disk full"))
+	err = NewRepository(helper, "storage").Replace(ctx, fileName, "content")
+	if apperror.CodeOf(err) != apperror.StorageUnavailable {
+		t.Errorf("textManagementRepositoryStruct.Replace() error = %v, want %v", err, apperror.StorageUnavailable)
+	}
+}
+
+func Test_textManagementRepositoryStruct_Usage(t *testing.T) {
+	fileLocation := t.TempDir()
+	os.WriteFile(fmt.Sprintf("%s/47b416d1-c5f2-417e-929e-7b83667c6654.json", fileLocation), []byte("12345"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec.json", fileLocation), []byte("123"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/notes.txt", fileLocation), []byte("not a text"), 0600)
+	os.Mkdir(fmt.Sprintf("%s/jobs", fileLocation), 0700)
+
+	tests := []struct {
+		name      string
+		location  string
+		wantTexts int
+		wantBytes int64
+		wantCode  apperror.Code
+		wantErr   bool
+	}{
+		{
+			name:      "Count texts only",
+			location:  fileLocation,
+			wantTexts: 2,
+			wantBytes: 8,
+			wantErr:   false,
+		},
+		{
+			name:     "Missing storage",
+			location: fmt.Sprintf("%s/missing", fileLocation),
+			wantCode: apperror.StorageUnavailable,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts, bytes, err := NewRepository(helper.NewHelper(), tt.location).Usage()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("textManagementRepositoryStruct.Usage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && apperror.CodeOf(err) != tt.wantCode {
+				t.Errorf("textManagementRepositoryStruct.Usage() error code = %v, want %v", apperror.CodeOf(err), tt.wantCode)
+			}
+			if texts != tt.wantTexts || bytes != tt.wantBytes {
+				t.Errorf("textManagementRepositoryStruct.Usage() = %d, %d, want %d, %d", texts, bytes, tt.wantTexts, tt.wantBytes)
+			}
+		})
+	}
+}
+
+func Test_textManagementRepositoryStruct_List(t *testing.T) {
+	fileLocation := t.TempDir()
+	os.WriteFile(fmt.Sprintf("%s/47b416d1-c5f2-417e-929e-7b83667c6654.json", fileLocation), []byte("12345"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec.json", fileLocation), []byte("123"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/notes.txt", fileLocation), []byte("not a text"), 0600)
+	os.Mkdir(fmt.Sprintf("%s/jobs", fileLocation), 0700)
+
+	tests := []struct {
+		name     string
+		location string
+		want     []string
+		wantCode apperror.Code
+		wantErr  bool
+	}{
+		{
+			name:     "List texts in order",
+			location: fileLocation,
+			want:     []string{"154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", "47b416d1-c5f2-417e-929e-7b83667c6654"},
+			wantErr:  false,
+		},
+		{
+			name:     "Missing storage",
+			location: fmt.Sprintf("%s/missing", fileLocation),
+			wantCode: apperror.StorageUnavailable,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRepository(helper.NewHelper(), tt.location).List(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("textManagementRepositoryStruct.List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && apperror.CodeOf(err) != tt.wantCode {
+				t.Errorf("textManagementRepositoryStruct.List() error code = %v, want %v", apperror.CodeOf(err), tt.wantCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("textManagementRepositoryStruct.List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
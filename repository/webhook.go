@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+
+	"github.com/rs/zerolog/log"
+)
+
+type WebhookInterface interface {
+	SaveSubscription(subscription entity.WebhookSubscription) error
+	DeleteSubscription(subscriptionId string) error
+	ListSubscriptions() ([]entity.WebhookSubscription, error)
+	SaveDelivery(delivery entity.WebhookDelivery) error
+	DeleteDelivery(deliveryId string) error
+	ListDeliveries() ([]entity.WebhookDelivery, error)
+}
+
+type webhookRepositoryStruct struct {
+	Helper        helper.HelperInterface
+	subscriptions string
+	deliveries    string
+}
+
+// NewWebhookRepository stores the subscriptions and the pending deliveries in the webhooks folder inside
+// storagePath, one file each, so the deliveries survive a restart
+func NewWebhookRepository(helper helper.HelperInterface, storagePath string) (WebhookInterface, error) {
+	repository := &webhookRepositoryStruct{
+		Helper:        helper,
+		subscriptions: fmt.Sprintf("%s/webhooks/subscriptions", storagePath),
+		deliveries:    fmt.Sprintf("%s/webhooks/deliveries", storagePath),
+	}
+
+	for _, location := range []string{repository.subscriptions, repository.deliveries} {
+		if err := helper.CreateDir(location); err != nil {
+			log.Error().Msg(err.Error())
+			return nil, apperror.Wrap(apperror.StorageUnavailable, "webhook storage could not be created", err)
+		}
+	}
+
+	return repository, nil
+}
+
+// SaveSubscription writes the subscription into its file
+func (w *webhookRepositoryStruct) SaveSubscription(subscription entity.WebhookSubscription) (err error) {
+	defer observe("webhook", "save_subscription", time.Now(), &err)
+
+	return w.save(w.subscriptions, subscription.Id, subscription)
+}
+
+// DeleteSubscription removes the subscription file
+func (w *webhookRepositoryStruct) DeleteSubscription(subscriptionId string) (err error) {
+	defer observe("webhook", "delete_subscription", time.Now(), &err)
+
+	return w.remove(w.subscriptions, subscriptionId, "webhook subscription")
+}
+
+// ListSubscriptions reads every subscription, skipping the files that cannot be read
+func (w *webhookRepositoryStruct) ListSubscriptions() (subscriptions []entity.WebhookSubscription, err error) {
+	defer observe("webhook", "list_subscriptions", time.Now(), &err)
+
+	subscriptions = []entity.WebhookSubscription{}
+	err = w.list(w.subscriptions, func(data []byte) error {
+		subscription := entity.WebhookSubscription{}
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			return err
+		}
+		subscriptions = append(subscriptions, subscription)
+		return nil
+	})
+
+	return subscriptions, err
+}
+
+// SaveDelivery writes the delivery state into its file
+func (w *webhookRepositoryStruct) SaveDelivery(delivery entity.WebhookDelivery) (err error) {
+	defer observe("webhook", "save_delivery", time.Now(), &err)
+
+	return w.save(w.deliveries, delivery.Id, delivery)
+}
+
+// DeleteDelivery removes a delivery that succeeded or was given up
+func (w *webhookRepositoryStruct) DeleteDelivery(deliveryId string) (err error) {
+	defer observe("webhook", "delete_delivery", time.Now(), &err)
+
+	return w.remove(w.deliveries, deliveryId, "webhook delivery")
+}
+
+// ListDeliveries reads every pending delivery, skipping the files that cannot be read
+func (w *webhookRepositoryStruct) ListDeliveries() (deliveries []entity.WebhookDelivery, err error) {
+	defer observe("webhook", "list_deliveries", time.Now(), &err)
+
+	deliveries = []entity.WebhookDelivery{}
+	err = w.list(w.deliveries, func(data []byte) error {
+		delivery := entity.WebhookDelivery{}
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+		return nil
+	})
+
+	return deliveries, err
+}
+
+func (w *webhookRepositoryStruct) save(location, id string, value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.Internal, "webhook could not be encoded", err)
+	}
+
+	file, err := w.Helper.CreateFile(fmt.Sprintf("%s/%s.json", location, id))
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "webhook could not be stored", err)
+	}
+	defer file.Close()
+
+	if _, err = w.Helper.WriteFile(file, string(content)); err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "webhook could not be stored", err)
+	}
+
+	return nil
+}
+
+func (w *webhookRepositoryStruct) remove(location, id, name string) error {
+	err := w.Helper.RemoveFile(fmt.Sprintf("%s/%s.json", location, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Info().Msg(err.Error())
+		return apperror.Wrap(apperror.NotFound, name+" not found", err)
+	}
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, name+" could not be removed", err)
+	}
+
+	return nil
+}
+
+func (w *webhookRepositoryStruct) list(location string, decode func(data []byte) error) error {
+	entries, err := w.Helper.ReadDir(location)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return apperror.Wrap(apperror.StorageUnavailable, "webhooks could not be listed", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := w.Helper.ReadFile(fmt.Sprintf("%s/%s", location, entry.Name()))
+		if err == nil {
+			err = decode(data)
+		}
+		if err != nil {
+			log.Error().Str("file", entry.Name()).Msg("skipping unreadable webhook file")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	mockhelper "zcelero/mocks/helper"
+)
+
+func Test_webhookRepositoryStruct_Subscriptions(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, err := NewWebhookRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewWebhookRepository() error = %v", err)
+	}
+
+	createdAt := time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+	perText := entity.WebhookSubscription{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Url: "https://example.com/hook", Events: []string{entity.WebhookEventRead}, TextId: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", Owner: "CN=alice", Secret: "secret", CreatedAt: createdAt}
+	perOwner := entity.WebhookSubscription{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Url: "https://example.com/hook", Events: entity.WebhookEvents, Owner: "CN=alice", Secret: "secret", CreatedAt: createdAt}
+	for _, subscription := range []entity.WebhookSubscription{perText, perOwner} {
+		if err := repository.SaveSubscription(subscription); err != nil {
+			t.Fatalf("webhookRepositoryStruct.SaveSubscription() error = %v", err)
+		}
+	}
+	os.WriteFile(fmt.Sprintf("%s/webhooks/subscriptions/notes.txt", storagePath), []byte("not a subscription"), 0600)
+	os.WriteFile(fmt.Sprintf("%s/webhooks/subscriptions/6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11.json", storagePath), []byte("{corrupt"), 0600)
+
+	got, err := repository.ListSubscriptions()
+	if err != nil {
+		t.Fatalf("webhookRepositoryStruct.ListSubscriptions() error = %v", err)
+	}
+	if want := []entity.WebhookSubscription{perText, perOwner}; !reflect.DeepEqual(got, want) {
+		t.Errorf("webhookRepositoryStruct.ListSubscriptions() = %v, want %v", got, want)
+	}
+
+	if err := repository.DeleteSubscription(perText.Id); err != nil {
+		t.Fatalf("webhookRepositoryStruct.DeleteSubscription() error = %v", err)
+	}
+	if err := repository.DeleteSubscription(perText.Id); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("webhookRepositoryStruct.DeleteSubscription() error = %v, want %v", err, apperror.NotFound)
+	}
+	if got, _ := repository.ListSubscriptions(); len(got) != 1 || got[0].Id != perOwner.Id {
+		t.Errorf("webhookRepositoryStruct.ListSubscriptions() = %v, want %v", got, perOwner)
+	}
+}
+
+func Test_webhookRepositoryStruct_Deliveries(t *testing.T) {
+	storagePath := t.TempDir()
+	repository, err := NewWebhookRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewWebhookRepository() error = %v", err)
+	}
+
+	delivery := entity.WebhookDelivery{
+		Id:             "47b416d1-c5f2-417e-929e-7b83667c6654",
+		SubscriptionId: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90",
+		Event:          entity.WebhookEvent{Id: "6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11", Type: entity.WebhookEventRead, TextId: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", Time: time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)},
+		NextAttempt:    time.Date(2022, 11, 10, 0, 0, 30, 0, time.UTC),
+	}
+	repository.SaveDelivery(delivery)
+	retried := delivery
+	retried.Attempts = 1
+	retried.LastError = "endpoint answered 500 Internal Server Error"
+	if err := repository.SaveDelivery(retried); err != nil {
+		t.Fatalf("webhookRepositoryStruct.SaveDelivery() error = %v", err)
+	}
+
+	got, err := repository.ListDeliveries()
+	if err != nil {
+		t.Fatalf("webhookRepositoryStruct.ListDeliveries() error = %v", err)
+	}
+	if want := []entity.WebhookDelivery{retried}; !reflect.DeepEqual(got, want) {
+		t.Errorf("webhookRepositoryStruct.ListDeliveries() = %v, want %v", got, want)
+	}
+
+	if err := repository.DeleteDelivery(delivery.Id); err != nil {
+		t.Fatalf("webhookRepositoryStruct.DeleteDelivery() error = %v", err)
+	}
+	if got, _ := repository.ListDeliveries(); len(got) != 0 {
+		t.Errorf("webhookRepositoryStruct.ListDeliveries() = %v, want none", got)
+	}
+}
+
+func TestNewWebhookRepository_StorageError(t *testing.T) {
+	helper := &mockhelper.HelperInterface{}
+	helper.On("CreateDir", "storage/webhooks/subscriptions").Return(errors.New("permission denied"))
+
+	_, err := NewWebhookRepository(helper, "storage")
+	if apperror.CodeOf(err) != apperror.StorageUnavailable {
+		t.Errorf("NewWebhookRepository() error = %v, want %v", err, apperror.StorageUnavailable)
+	}
+}
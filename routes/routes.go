@@ -2,12 +2,41 @@ package routes
 
 import (
 	"zcelero/controller"
+	"zcelero/metrics"
+	"zcelero/openapi"
 	"zcelero/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetRoutes(router *gin.Engine, textManagementService service.TextManagementServiceInteface) {
-	router.POST("/v1/text-management", controller.Insert(textManagementService))
+func GetRoutes(router *gin.Engine, textManagementService service.TextManagementServiceInteface, jobService service.JobServiceInterface, healthService service.HealthServiceInterface, auditService service.AuditServiceInterface, webhookService service.WebhookServiceInterface, rotationService service.RotationServiceInterface) {
+	router.POST("/v1/text-management", controller.Insert(textManagementService, jobService))
 	router.GET("/v1/text-management", controller.Get(textManagementService))
+	router.DELETE("/v1/text-management", controller.Delete(textManagementService))
+	router.GET("/v1/text-management/metadata", controller.GetMetadata(textManagementService))
+	router.POST("/v1/text-management/rekey", controller.Rekey(textManagementService))
+	router.POST("/v1/private-key/password", controller.ChangePassword(textManagementService))
+	if jobService != nil {
+		router.GET("/v1/jobs/:id", controller.GetJob(jobService))
+	}
+	if healthService != nil {
+		router.GET("/healthz", controller.Live(healthService))
+		router.GET("/readyz", controller.Ready(healthService))
+	}
+	if auditService != nil {
+		router.GET("/v1/audit", controller.QueryAudit(auditService))
+	}
+	if webhookService != nil {
+		router.POST("/v1/webhooks", controller.Subscribe(webhookService))
+		router.GET("/v1/webhooks", controller.ListWebhooks(webhookService))
+		router.DELETE("/v1/webhooks/:id", controller.Unsubscribe(webhookService))
+	}
+	if rotationService != nil {
+		router.POST("/v1/key-rotation", controller.StartRotation(rotationService))
+		router.GET("/v1/key-rotation", controller.GetRotation(rotationService))
+	}
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/openapi.json", openapi.GetDocument())
+	router.GET("/docs", openapi.GetSwaggerUI())
+	router.GET("/docs/:file", openapi.GetSwaggerUIAsset())
 }
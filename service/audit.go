@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/principal"
+	"zcelero/repository"
+)
+
+type AuditServiceInterface interface {
+	Record(ctx context.Context, action, textId string, err error)
+	Query(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+type AuditService struct {
+	AuditRepository repository.AuditInterface
+	Helper          helper.HelperInterface
+	adminPrincipals []string
+}
+
+// NewAuditService records the operations in the audit log, which only the admin principals can query
+func NewAuditService(auditRepository repository.AuditInterface, helper helper.HelperInterface, adminPrincipals []string) AuditServiceInterface {
+	return &AuditService{
+		AuditRepository: auditRepository,
+		Helper:          helper,
+		adminPrincipals: adminPrincipals,
+	}
+}
+
+// Record appends the operation, done by the principal and client of the context, to the audit log. An
+// entry that can't be written is logged but doesn't fail the operation, which already happened
+func (a *AuditService) Record(ctx context.Context, action, textId string, err error) {
+	entry := entity.AuditEntry{
+		Time:      a.Helper.Now(),
+		Action:    action,
+		Outcome:   entity.AuditOutcomeSuccess,
+		Principal: principal.FromContext(ctx),
+		TextId:    textId,
+		ClientIP:  principal.ClientIPFromContext(ctx),
+	}
+	if err != nil {
+		entry.Outcome = entity.AuditOutcomeFailure
+		entry.ErrorCode = string(apperror.CodeOf(err))
+	}
+
+	if _, err := a.AuditRepository.Append(ctx, entry); err != nil {
+		logging.FromContext(ctx).Error().Str("action", action).Str("outcome", entry.Outcome).Msg("audit entry could not be written")
+	}
+}
+
+// Query lists the audit entries matching the filter for admin principals, the query itself is audited
+func (a *AuditService) Query(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	entries, err := a.query(ctx, filter)
+	a.Record(ctx, entity.AuditActionQuery, filter.TextId, err)
+
+	return entries, err
+}
+
+func (a *AuditService) query(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	if !isAdmin(ctx, a.adminPrincipals) {
+		logging.FromContext(ctx).Info().Msg("audit log query denied")
+		return nil, apperror.New(apperror.Forbidden, "an admin client certificate is required to read the audit log")
+	}
+
+	return a.AuditRepository.List(ctx, filter)
+}
+
+// isAdmin tells whether the principal of the context is one of the admin principals
+func isAdmin(ctx context.Context, adminPrincipals []string) bool {
+	name := principal.FromContext(ctx)
+	for _, admin := range adminPrincipals {
+		if name != "" && name == admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+type auditedService struct {
+	TextManagementService TextManagementServiceInteface
+	AuditService          AuditServiceInterface
+}
+
+// NewAuditedService records every operation of the text service in the audit log, failed ones included
+func NewAuditedService(textManagementService TextManagementServiceInteface, auditService AuditServiceInterface) TextManagementServiceInteface {
+	return &auditedService{TextManagementService: textManagementService, AuditService: auditService}
+}
+
+func (a *auditedService) Get(ctx context.Context, textId, privateKey, password string) (string, error) {
+	text, err := a.TextManagementService.Get(ctx, textId, privateKey, password)
+	a.AuditService.Record(ctx, entity.AuditActionRead, textId, err)
+
+	return text, err
+}
+
+func (a *auditedService) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	metadata, err := a.TextManagementService.GetMetadata(ctx, textId)
+	a.AuditService.Record(ctx, entity.AuditActionReadMetadata, textId, err)
+
+	return metadata, err
+}
+
+func (a *auditedService) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	inserted, err := a.TextManagementService.Insert(ctx, text)
+	a.AuditService.Record(ctx, entity.AuditActionInsert, inserted.Uuid, err)
+
+	return inserted, err
+}
+
+func (a *auditedService) Delete(ctx context.Context, textId, privateKey, password string) error {
+	err := a.TextManagementService.Delete(ctx, textId, privateKey, password)
+	a.AuditService.Record(ctx, entity.AuditActionDelete, textId, err)
+
+	return err
+}
+
+func (a *auditedService) Rekey(ctx context.Context, textId string, rekey entity.Rekey) (string, error) {
+	privateKey, err := a.TextManagementService.Rekey(ctx, textId, rekey)
+	a.AuditService.Record(ctx, entity.AuditActionRekey, textId, err)
+
+	return privateKey, err
+}
+
+func (a *auditedService) ChangePassword(ctx context.Context, change entity.PasswordChange) (string, error) {
+	privateKey, err := a.TextManagementService.ChangePassword(ctx, change)
+	a.AuditService.Record(ctx, entity.AuditActionPasswordChange, "", err)
+
+	return privateKey, err
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	mockhelper "zcelero/mocks/helper"
+	mockrepository "zcelero/mocks/repository"
+	mockservice "zcelero/mocks/service"
+	"zcelero/principal"
+	"zcelero/service"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var auditTime = time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+
+func TestAuditService_Record(t *testing.T) {
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	ctx := principal.NewClientIPContext(principal.NewContext(context.Background(), "CN=alice"), "10.0.0.7")
+	tests := []struct {
+		name      string
+		err       error
+		appendErr error
+		want      entity.AuditEntry
+	}{
+		{
+			name: "Record success",
+			want: entity.AuditEntry{Time: auditTime, Action: entity.AuditActionRead, Outcome: entity.AuditOutcomeSuccess, Principal: "CN=alice", TextId: textId, ClientIP: "10.0.0.7"},
+		},
+		{
+			name: "Record failure",
+			err:  apperror.New(apperror.WrongPassword, "private_key_password is incorrect"),
+			want: entity.AuditEntry{Time: auditTime, Action: entity.AuditActionRead, Outcome: entity.AuditOutcomeFailure, ErrorCode: string(apperror.WrongPassword), Principal: "CN=alice", TextId: textId, ClientIP: "10.0.0.7"},
+		},
+		{
+			name:      "Record with storage error",
+			appendErr: apperror.New(apperror.StorageUnavailable, "audit entry could not be stored"),
+			want:      entity.AuditEntry{Time: auditTime, Action: entity.AuditActionRead, Outcome: entity.AuditOutcomeSuccess, Principal: "CN=alice", TextId: textId, ClientIP: "10.0.0.7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockrepository.AuditInterface{}
+			repository.On("Append", mock.Anything, tt.want).Return(tt.want, tt.appendErr)
+			helper := &mockhelper.HelperInterface{}
+			helper.On("Now").Return(auditTime)
+
+			service.NewAuditService(repository, helper, nil).Record(ctx, entity.AuditActionRead, textId, tt.err)
+			repository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuditService_Query(t *testing.T) {
+	admin := "CN=admin,O=Zcelero"
+	entries := []entity.AuditEntry{{Sequence: 1, Action: entity.AuditActionInsert}}
+	tests := []struct {
+		name        string
+		principal   string
+		wantErr     apperror.Code
+		wantOutcome string
+	}{
+		{name: "Query as admin", principal: admin, wantOutcome: entity.AuditOutcomeSuccess},
+		{name: "Query as another principal", principal: "CN=alice", wantErr: apperror.Forbidden, wantOutcome: entity.AuditOutcomeFailure},
+		{name: "Query without principal", wantErr: apperror.Forbidden, wantOutcome: entity.AuditOutcomeFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockrepository.AuditInterface{}
+			if tt.wantErr == "" {
+				repository.On("List", mock.Anything, entity.AuditFilter{Limit: 10}).Return(entries, nil)
+			}
+			repository.On("Append", mock.Anything, mock.MatchedBy(func(entry entity.AuditEntry) bool {
+				return entry.Action == entity.AuditActionQuery && entry.Outcome == tt.wantOutcome && entry.Principal == tt.principal
+			})).Return(entity.AuditEntry{}, nil).Once()
+			helper := &mockhelper.HelperInterface{}
+			helper.On("Now").Return(auditTime)
+			ctx := principal.NewContext(context.Background(), tt.principal)
+
+			got, err := service.NewAuditService(repository, helper, []string{admin}).Query(ctx, entity.AuditFilter{Limit: 10})
+			if tt.wantErr != "" {
+				if apperror.CodeOf(err) != tt.wantErr {
+					t.Errorf("AuditService.Query() error = %v, want %s", err, tt.wantErr)
+				}
+			} else if err != nil || len(got) != 1 {
+				t.Errorf("AuditService.Query() = %v, %v", got, err)
+			}
+			repository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuditedService(t *testing.T) {
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	wrongPassword := apperror.New(apperror.WrongPassword, "private_key_password is incorrect")
+	ctx := context.Background()
+
+	textService := &mockservice.TextManagementServiceInteface{}
+	textService.On("Insert", ctx, mock.Anything).Return(entity.TextManagement{Uuid: textId}, nil)
+	textService.On("Get", ctx, textId, "key", "password").Return("", wrongPassword)
+	textService.On("GetMetadata", ctx, textId).Return(entity.TextMetadata{Uuid: textId}, nil)
+	textService.On("Delete", ctx, textId, "", "").Return(errors.New("disk exploded"))
+	textService.On("ChangePassword", ctx, mock.Anything).Return("new key", nil)
+	textService.On("Rekey", ctx, textId, mock.Anything).Return("new key", nil)
+	auditService := &mockservice.AuditServiceInterface{}
+	auditService.On("Record", ctx, entity.AuditActionInsert, textId, nil).Once()
+	auditService.On("Record", ctx, entity.AuditActionRead, textId, wrongPassword).Once()
+	auditService.On("Record", ctx, entity.AuditActionReadMetadata, textId, nil).Once()
+	auditService.On("Record", ctx, entity.AuditActionDelete, textId, mock.Anything).Once()
+	auditService.On("Record", ctx, entity.AuditActionPasswordChange, "", nil).Once()
+	auditService.On("Record", ctx, entity.AuditActionRekey, textId, nil).Once()
+
+	audited := service.NewAuditedService(textService, auditService)
+	if inserted, err := audited.Insert(ctx, entity.TextManagement{TextData: "text"}); err != nil || inserted.Uuid != textId {
+		t.Errorf("Insert() = %v, %v", inserted, err)
+	}
+	if _, err := audited.Get(ctx, textId, "key", "password"); err != wrongPassword {
+		t.Errorf("Get() error = %v, want %v", err, wrongPassword)
+	}
+	if _, err := audited.GetMetadata(ctx, textId); err != nil {
+		t.Errorf("GetMetadata() error = %v", err)
+	}
+	if err := audited.Delete(ctx, textId, "", ""); err == nil {
+		t.Error("Delete() error = nil, want the service error")
+	}
+	if privateKey, err := audited.ChangePassword(ctx, entity.PasswordChange{PrivateKey: "key"}); err != nil || privateKey != "new key" {
+		t.Errorf("ChangePassword() = %v, %v", privateKey, err)
+	}
+	if privateKey, err := audited.Rekey(ctx, textId, entity.Rekey{PrivateKey: "key"}); err != nil || privateKey != "new key" {
+		t.Errorf("Rekey() = %v, %v", privateKey, err)
+	}
+	auditService.AssertExpectations(t)
+}
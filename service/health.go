@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/keypool"
+	"zcelero/logging"
+	"zcelero/repository"
+)
+
+// probeContent is saved and read back by the repository check
+const probeContent = `{"probe":true}`
+
+type HealthServiceInterface interface {
+	Live() entity.Health
+	Ready(ctx context.Context) entity.Health
+}
+
+type HealthService struct {
+	TextManagementRepository repository.TextManagementInterface
+	Helper                   helper.HelperInterface
+	KeyPool                  keypool.KeyPoolInterface
+	Config                   config.Config
+}
+
+// NewHealthService checks the dependencies the application needs to serve requests
+func NewHealthService(textManagementRepository repository.TextManagementInterface, helper helper.HelperInterface, keyPool keypool.KeyPoolInterface, config config.Config) HealthServiceInterface {
+	return &HealthService{
+		TextManagementRepository: textManagementRepository,
+		Helper:                   helper,
+		KeyPool:                  keyPool,
+		Config:                   config,
+	}
+}
+
+// Live tells the process is running, it doesn't check any dependency
+func (h *HealthService) Live() entity.Health {
+	return entity.Health{Status: entity.HealthStatusOk}
+}
+
+// Ready runs every check, the application is unavailable when one of them fails and degraded when one of
+// them reports it serves requests slower
+func (h *HealthService) Ready(ctx context.Context) entity.Health {
+	health := entity.Health{
+		Status: entity.HealthStatusOk,
+		Checks: map[string]entity.HealthCheck{
+			"config":     h.checkConfig(),
+			"repository": h.checkRepository(ctx),
+			"key_pool":   h.checkKeyPool(),
+		},
+	}
+
+	for name, check := range health.Checks {
+		switch check.Status {
+		case entity.HealthStatusUnavailable:
+			health.Status = entity.HealthStatusUnavailable
+		case entity.HealthStatusDegraded:
+			if health.Status == entity.HealthStatusOk {
+				health.Status = entity.HealthStatusDegraded
+			}
+		default:
+			continue
+		}
+		logging.FromContext(ctx).Warn().Str("check", name).Msg(check.Message)
+	}
+
+	return health
+}
+
+func (h *HealthService) checkConfig() entity.HealthCheck {
+	if err := h.Config.Validate(); err != nil {
+		return unavailable(err.Error())
+	}
+
+	return entity.HealthCheck{Status: entity.HealthStatusOk}
+}
+
+// checkRepository writes, reads back and removes a probe record. Its name is not a uuid, so the probe
+// can't be reached through the API, and each check uses its own record
+func (h *HealthService) checkRepository(ctx context.Context) entity.HealthCheck {
+	probe := "probe-" + h.Helper.GenerateUuid()
+
+	if err := h.TextManagementRepository.Save(ctx, probe, probeContent); err != nil {
+		return unavailable("probe record could not be written")
+	}
+	data, err := h.TextManagementRepository.Load(ctx, probe)
+	if err != nil {
+		h.TextManagementRepository.Delete(ctx, probe)
+		return unavailable("probe record could not be read")
+	}
+	if err := h.TextManagementRepository.Delete(ctx, probe); err != nil {
+		return unavailable("probe record could not be removed")
+	}
+	if string(data) != probeContent {
+		return unavailable("probe record was read back changed")
+	}
+
+	return entity.HealthCheck{Status: entity.HealthStatusOk}
+}
+
+// checkKeyPool reports a drained pool as degraded, the keys of that size are generated synchronously until
+// the pool refills, so the application is slower but still serves requests
+func (h *HealthService) checkKeyPool() entity.HealthCheck {
+	if h.KeyPool == nil || h.Config.KeyPool.Size <= 0 || h.Config.KeyPool.Workers <= 0 {
+		return entity.HealthCheck{Status: entity.HealthStatusOk, Message: "key pool is disabled"}
+	}
+
+	depths := []string{}
+	cold := false
+	for _, keySize := range h.Config.KeySizes {
+		depth := h.KeyPool.Depth(keySize)
+		cold = cold || depth == 0
+		depths = append(depths, fmt.Sprintf("%d: %d/%d", keySize, depth, h.Config.KeyPool.Size))
+	}
+
+	message := "keys ready " + strings.Join(depths, ", ")
+	if cold {
+		return entity.HealthCheck{Status: entity.HealthStatusDegraded, Message: message}
+	}
+
+	return entity.HealthCheck{Status: entity.HealthStatusOk, Message: message}
+}
+
+func unavailable(message string) entity.HealthCheck {
+	return entity.HealthCheck{Status: entity.HealthStatusUnavailable, Message: message}
+}
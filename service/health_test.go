@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"zcelero/config"
+	"zcelero/entity"
+	mockhelper "zcelero/mocks/helper"
+	mockkeypool "zcelero/mocks/keypool"
+	mockrepository "zcelero/mocks/repository"
+	"zcelero/service"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHealthService_Ready(t *testing.T) {
+	probe := "probe-47b416d1-c5f2-417e-929e-7b83667c6654"
+	probeContent := `{"probe":true}`
+	pooled := config.Default()
+	pooled.KeySizes = []uint64{1024, 2048}
+	invalid := config.Default()
+	invalid.Port = 0
+
+	tests := []struct {
+		name         string
+		config       config.Config
+		mockBehavior func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface)
+		wantStatus   string
+		wantFailed   []string
+	}{
+		{
+			name:   "Ready",
+			config: pooled,
+			mockBehavior: func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface) {
+				r.On("Save", mock.Anything, probe, probeContent).Return(nil)
+				r.On("Load", mock.Anything, probe).Return([]byte(probeContent), nil)
+				r.On("Delete", mock.Anything, probe).Return(nil)
+				k.On("Depth", uint64(1024)).Return(5)
+				k.On("Depth", uint64(2048)).Return(1)
+			},
+			wantStatus: entity.HealthStatusOk,
+		},
+		{
+			name:   "Cold key pool",
+			config: pooled,
+			mockBehavior: func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface) {
+				r.On("Save", mock.Anything, probe, probeContent).Return(nil)
+				r.On("Load", mock.Anything, probe).Return([]byte(probeContent), nil)
+				r.On("Delete", mock.Anything, probe).Return(nil)
+				k.On("Depth", uint64(1024)).Return(5)
+				k.On("Depth", uint64(2048)).Return(0)
+			},
+			wantStatus: entity.HealthStatusDegraded,
+			wantFailed: []string{"key_pool"},
+		},
+		{
+			name:   "Read only storage",
+			config: pooled,
+			mockBehavior: func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface) {
+				r.On("Save", mock.Anything, probe, probeContent).Return(errors.New("read-only file system"))
+				k.On("Depth", mock.Anything).Return(5)
+			},
+			wantStatus: entity.HealthStatusUnavailable,
+			wantFailed: []string{"repository"},
+		},
+		{
+			name:   "Unreadable storage",
+			config: pooled,
+			mockBehavior: func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface) {
+				r.On("Save", mock.Anything, probe, probeContent).Return(nil)
+				r.On("Load", mock.Anything, probe).Return(nil, errors.New("permission denied"))
+				r.On("Delete", mock.Anything, probe).Return(nil).Once()
+				k.On("Depth", mock.Anything).Return(5)
+			},
+			wantStatus: entity.HealthStatusUnavailable,
+			wantFailed: []string{"repository"},
+		},
+		{
+			name:   "Invalid config",
+			config: invalid,
+			mockBehavior: func(r *mockrepository.TextManagementInterface, k *mockkeypool.KeyPoolInterface) {
+				r.On("Save", mock.Anything, probe, probeContent).Return(nil)
+				r.On("Load", mock.Anything, probe).Return([]byte(probeContent), nil)
+				r.On("Delete", mock.Anything, probe).Return(nil)
+				k.On("Depth", mock.Anything).Return(5)
+			},
+			wantStatus: entity.HealthStatusUnavailable,
+			wantFailed: []string{"config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockrepository.TextManagementInterface{}
+			keyPool := &mockkeypool.KeyPoolInterface{}
+			helper := &mockhelper.HelperInterface{}
+			helper.On("GenerateUuid").Return("47b416d1-c5f2-417e-929e-7b83667c6654")
+			tt.mockBehavior(repository, keyPool)
+
+			got := service.NewHealthService(repository, helper, keyPool, tt.config).Ready(context.Background())
+			if got.Status != tt.wantStatus {
+				t.Errorf("HealthService.Ready() status = %v, want %v: %v", got.Status, tt.wantStatus, got.Checks)
+			}
+			for _, name := range []string{"config", "repository", "key_pool"} {
+				check, found := got.Checks[name]
+				if !found {
+					t.Fatalf("HealthService.Ready() has no %s check", name)
+				}
+				wantFailed := false
+				for _, failed := range tt.wantFailed {
+					wantFailed = wantFailed || failed == name
+				}
+				if (check.Status != entity.HealthStatusOk) != wantFailed {
+					t.Errorf("HealthService.Ready() %s check = %v", name, check)
+				}
+			}
+			repository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHealthService_ReadyWithoutKeyPool(t *testing.T) {
+	repository := &mockrepository.TextManagementInterface{}
+	repository.On("Save", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	repository.On("Load", mock.Anything, mock.Anything).Return([]byte(`{"probe":true}`), nil)
+	repository.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	helper := &mockhelper.HelperInterface{}
+	helper.On("GenerateUuid").Return("47b416d1-c5f2-417e-929e-7b83667c6654")
+
+	got := service.NewHealthService(repository, helper, nil, config.Default()).Ready(context.Background())
+	if got.Status != entity.HealthStatusOk || got.Checks["key_pool"].Message != "key pool is disabled" {
+		t.Errorf("HealthService.Ready() = %v", got)
+	}
+}
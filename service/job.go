@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/principal"
+	"zcelero/repository"
+	"zcelero/tracing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrJobQueueFull = apperror.New(apperror.ServiceUnavailable, "job queue is full, try again later")
+
+var ErrJobServiceStopped = apperror.New(apperror.ServiceUnavailable, "job service is shutting down, try again later")
+
+// jobSweepInterval is how often the expired jobs are looked for
+const jobSweepInterval = time.Minute
+
+type JobServiceInterface interface {
+	Enqueue(ctx context.Context, text entity.TextManagement) (entity.Job, error)
+	Get(jobId string) (entity.Job, error)
+	Stop()
+}
+
+type queuedJob struct {
+	job  entity.Job
+	text entity.TextManagement
+	// link ties the job trace to the request that queued it
+	link trace.Link
+	// logger keeps the request id of the request that queued it
+	logger *zerolog.Logger
+	// principal and clientIP keep who queued it for the audit log
+	principal string
+	clientIP  string
+}
+
+type JobService struct {
+	TextManagementService TextManagementServiceInteface
+	JobRepository         repository.JobInterface
+	Helper                helper.HelperInterface
+	queue                 chan queuedJob
+	// ttl is how long a finished job is kept, zero keeps them
+	ttl     time.Duration
+	done    chan struct{}
+	stopped bool
+	mutex   sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewJobService starts the workers that run the queued inserts and the removal of the jobs finished for
+// longer than ttl
+func NewJobService(textManagementService TextManagementServiceInteface, jobRepository repository.JobInterface, helper helper.HelperInterface, workers int, queueSize int, ttl time.Duration) JobServiceInterface {
+	if queueSize < 0 {
+		log.Warn().Int("queue_size", queueSize).Msg("Negative job queue size, queueing is disabled")
+		queueSize = 0
+	}
+
+	jobService := &JobService{
+		TextManagementService: textManagementService,
+		JobRepository:         jobRepository,
+		Helper:                helper,
+		queue:                 make(chan queuedJob, queueSize),
+		ttl:                   ttl,
+		done:                  make(chan struct{}),
+	}
+
+	jobService.failInterruptedJobs()
+	jobService.removeExpiredJobs()
+	if ttl > 0 {
+		jobService.wg.Add(1)
+		go jobService.expire()
+	}
+
+	for i := 0; i < workers; i++ {
+		jobService.wg.Add(1)
+		go jobService.work()
+	}
+
+	return jobService
+}
+
+// Enqueue persists a pending job and schedules the insert
+func (j *JobService) Enqueue(ctx context.Context, text entity.TextManagement) (entity.Job, error) {
+	if j.isStopped() {
+		return entity.Job{}, ErrJobServiceStopped
+	}
+
+	now := j.Helper.Now()
+	job := entity.Job{
+		Id:        j.Helper.GenerateUuid(),
+		Status:    entity.JobStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := j.JobRepository.Save(job)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	ctx = logging.With(ctx, "job_id", job.Id)
+	err = j.push(queuedJob{
+		job:       job,
+		text:      text,
+		link:      trace.LinkFromContext(ctx),
+		logger:    logging.FromContext(ctx),
+		principal: principal.FromContext(ctx),
+		clientIP:  principal.ClientIPFromContext(ctx),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Info().Msg(err.Error())
+		j.finish(job, entity.TextManagement{}, err)
+		return entity.Job{}, err
+	}
+
+	logging.FromContext(ctx).Debug().Msg("Job queued")
+
+	return job, nil
+}
+
+// Get returns the job state, handing over the private key only on the first read after completion
+func (j *JobService) Get(jobId string) (entity.Job, error) {
+	if _, err := uuid.Parse(jobId); err != nil {
+		return entity.Job{}, apperror.New(apperror.InvalidId, "id must be a valid uuid")
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	job, err := j.JobRepository.Load(jobId)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	if job.Status != entity.JobStatusDone || job.PrivateKey == "" {
+		return job, nil
+	}
+
+	delivered := job
+	delivered.PrivateKey = ""
+	delivered.KeyDelivered = true
+	delivered.UpdatedAt = j.Helper.Now()
+
+	err = j.JobRepository.Save(delivered)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	job.KeyDelivered = true
+	return job, nil
+}
+
+// Stop stops accepting jobs and waits for the queued ones to finish
+func (j *JobService) Stop() {
+	j.mutex.Lock()
+	if !j.stopped {
+		j.stopped = true
+		close(j.queue)
+		close(j.done)
+	}
+	j.mutex.Unlock()
+
+	j.wg.Wait()
+}
+
+// push schedules the job without blocking, the lock keeps it from racing with Stop closing the queue
+func (j *JobService) push(queued queuedJob) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.stopped {
+		return ErrJobServiceStopped
+	}
+
+	select {
+	case j.queue <- queued:
+		return nil
+	default:
+		return ErrJobQueueFull
+	}
+}
+
+func (j *JobService) isStopped() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.stopped
+}
+
+func (j *JobService) work() {
+	defer j.wg.Done()
+
+	for queued := range j.queue {
+		queued.logger.Debug().Msg("Running job")
+
+		running := queued.job
+		running.Status = entity.JobStatusRunning
+		running.UpdatedAt = j.Helper.Now()
+		j.save(running)
+
+		// the job outlives the request, so it starts its own trace
+		ctx := principal.NewClientIPContext(principal.NewContext(logging.NewContext(context.Background(), *queued.logger), queued.principal), queued.clientIP)
+		ctx, span := tracing.Start(ctx, "job.Run", trace.WithLinks(queued.link), trace.WithAttributes(attribute.String("job_id", queued.job.Id)))
+		text, err := j.TextManagementService.Insert(ctx, queued.text)
+		tracing.End(span, err)
+		j.finish(running, text, err)
+	}
+}
+
+func (j *JobService) finish(job entity.Job, text entity.TextManagement, err error) {
+	job.UpdatedAt = j.Helper.Now()
+	if err != nil {
+		job.Status = entity.JobStatusFailed
+		job.Error = apperror.ToProblem(err, "").Detail
+		job.ErrorCode = string(apperror.CodeOf(err))
+	} else {
+		job.Status = entity.JobStatusDone
+		job.Uuid = text.Uuid
+		job.PrivateKey = text.PrivateKey
+	}
+
+	j.save(job)
+}
+
+func (j *JobService) save(job entity.Job) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	err := j.JobRepository.Save(job)
+	if err != nil {
+		log.Error().Str("job_id", job.Id).Msg(err.Error())
+	}
+}
+
+// failInterruptedJobs marks jobs left unfinished by a previous process as failed,
+// since their requests are never persisted and cannot be resumed
+func (j *JobService) failInterruptedJobs() {
+	jobs, err := j.JobRepository.List()
+	if err != nil {
+		log.Error().Msg("interrupted jobs could not be listed: " + err.Error())
+		return
+	}
+
+	for _, job := range jobs {
+		if job.Status == entity.JobStatusPending || job.Status == entity.JobStatusRunning {
+			j.finish(job, entity.TextManagement{}, apperror.New(apperror.Internal, "job interrupted by a server restart"))
+		}
+	}
+}
+
+// expire removes the expired jobs every jobSweepInterval until the service stops
+func (j *JobService) expire() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(jobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.removeExpiredJobs()
+		case <-j.done:
+			return
+		}
+	}
+}
+
+// removeExpiredJobs deletes the finished jobs that haven't changed for longer than the ttl, along with a
+// private key that was never read
+func (j *JobService) removeExpiredJobs() {
+	if j.ttl <= 0 {
+		return
+	}
+
+	jobs, err := j.JobRepository.List()
+	if err != nil {
+		log.Error().Msg("expired jobs could not be listed: " + err.Error())
+		return
+	}
+
+	now := j.Helper.Now()
+	for _, job := range jobs {
+		finished := job.Status == entity.JobStatusDone || job.Status == entity.JobStatusFailed
+		if !finished || now.Sub(job.UpdatedAt) <= j.ttl {
+			continue
+		}
+
+		j.mutex.Lock()
+		err := j.JobRepository.Delete(job.Id)
+		j.mutex.Unlock()
+		if err != nil && apperror.CodeOf(err) != apperror.NotFound {
+			log.Error().Str("job_id", job.Id).Msg(err.Error())
+			continue
+		}
+		log.Debug().Str("job_id", job.Id).Msg("Expired job removed")
+	}
+}
@@ -0,0 +1,298 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	mockhelper "zcelero/mocks/helper"
+	mockrepository "zcelero/mocks/repository"
+	mockservice "zcelero/mocks/service"
+	"zcelero/service"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var jobTime = time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+
+type savedJobs struct {
+	mutex sync.Mutex
+	jobs  map[string]entity.Job
+}
+
+func (s *savedJobs) save(args mock.Arguments) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job := args.Get(0).(entity.Job)
+	s.jobs[job.Id] = job
+}
+
+func (s *savedJobs) get(jobId string) entity.Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.jobs[jobId]
+}
+
+func TestJobService_Enqueue(t *testing.T) {
+	jobId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	encryption := true
+	text := entity.TextManagement{
+		TextData:           "aaaaaaaa",
+		Encryption:         &encryption,
+		KeySize:            1024,
+		PrivateKeyPassword: "aaa",
+	}
+
+	tests := []struct {
+		name         string
+		insertResult entity.TextManagement
+		insertErr    error
+		wantStatus   string
+		wantUuid     string
+		wantKey      string
+		wantError    string
+	}{
+		{
+			name:         "Run queued insert",
+			insertResult: entity.TextManagement{Uuid: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", PrivateKey: "private key"},
+			wantStatus:   entity.JobStatusDone,
+			wantUuid:     "2f13ed58-afc9-477a-bf0d-c90eb1b7db90",
+			wantKey:      "private key",
+		},
+		{
+			name:       "Run queued insert with error",
+			insertErr:  apperror.New(apperror.ValidationFailed, "text_data is too long for the key_size"),
+			wantStatus: entity.JobStatusFailed,
+			wantError:  "text_data is too long for the key_size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := &savedJobs{jobs: map[string]entity.Job{}}
+			textService := &mockservice.TextManagementServiceInteface{}
+			jobRepository := &mockrepository.JobInterface{}
+			helper := &mockhelper.HelperInterface{}
+
+			helper.On("GenerateUuid").Return(jobId)
+			helper.On("Now").Return(jobTime)
+			jobRepository.On("List").Return([]entity.Job{}, nil)
+			jobRepository.On("Save", mock.AnythingOfType("entity.Job")).Run(saved.save).Return(nil)
+			textService.On("Insert", mock.Anything, text).Return(tt.insertResult, tt.insertErr)
+
+			jobService := service.NewJobService(textService, jobRepository, helper, 1, 1, 0)
+
+			job, err := jobService.Enqueue(context.Background(), text)
+			if err != nil {
+				t.Fatalf("JobService.Enqueue() error = %v", err)
+			}
+			if job.Id != jobId || job.Status != entity.JobStatusPending {
+				t.Errorf("JobService.Enqueue() = %v, want pending job %s", job, jobId)
+			}
+
+			jobService.Stop()
+
+			got := saved.get(jobId)
+			if got.Status != tt.wantStatus || got.Uuid != tt.wantUuid || got.PrivateKey != tt.wantKey || got.Error != tt.wantError {
+				t.Errorf("saved job = %v, want status %s", got, tt.wantStatus)
+			}
+			if !got.CreatedAt.Equal(jobTime) || !got.UpdatedAt.Equal(jobTime) {
+				t.Errorf("saved job times = %v %v, want %v", got.CreatedAt, got.UpdatedAt, jobTime)
+			}
+			textService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestJobService_EnqueueQueueFull(t *testing.T) {
+	saved := &savedJobs{jobs: map[string]entity.Job{}}
+	jobRepository := &mockrepository.JobInterface{}
+	helper := &mockhelper.HelperInterface{}
+
+	helper.On("GenerateUuid").Return("47b416d1-c5f2-417e-929e-7b83667c6654").Once()
+	helper.On("GenerateUuid").Return("2f13ed58-afc9-477a-bf0d-c90eb1b7db90").Once()
+	helper.On("Now").Return(jobTime)
+	jobRepository.On("List").Return([]entity.Job{}, nil)
+	jobRepository.On("Save", mock.AnythingOfType("entity.Job")).Run(saved.save).Return(nil)
+
+	jobService := service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, helper, 0, 1, 0)
+
+	if _, err := jobService.Enqueue(context.Background(), entity.TextManagement{}); err != nil {
+		t.Fatalf("JobService.Enqueue() error = %v", err)
+	}
+
+	_, err := jobService.Enqueue(context.Background(), entity.TextManagement{})
+	if !errors.Is(err, service.ErrJobQueueFull) {
+		t.Errorf("JobService.Enqueue() error = %v, want %v", err, service.ErrJobQueueFull)
+	}
+	if saved.get("2f13ed58-afc9-477a-bf0d-c90eb1b7db90").Status != entity.JobStatusFailed {
+		t.Errorf("rejected job status = %s, want %s", saved.get("2f13ed58-afc9-477a-bf0d-c90eb1b7db90").Status, entity.JobStatusFailed)
+	}
+}
+
+func TestJobService_EnqueueAfterStop(t *testing.T) {
+	jobRepository := &mockrepository.JobInterface{}
+	jobRepository.On("List").Return([]entity.Job{}, nil)
+
+	jobService := service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, &mockhelper.HelperInterface{}, 1, 1, 0)
+	jobService.Stop()
+	jobService.Stop()
+
+	_, err := jobService.Enqueue(context.Background(), entity.TextManagement{})
+	if !errors.Is(err, service.ErrJobServiceStopped) {
+		t.Errorf("JobService.Enqueue() error = %v, want %v", err, service.ErrJobServiceStopped)
+	}
+	jobRepository.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestJobService_EnqueueNegativeQueueSize(t *testing.T) {
+	saved := &savedJobs{jobs: map[string]entity.Job{}}
+	jobRepository := &mockrepository.JobInterface{}
+	helper := &mockhelper.HelperInterface{}
+
+	helper.On("GenerateUuid").Return("47b416d1-c5f2-417e-929e-7b83667c6654")
+	helper.On("Now").Return(jobTime)
+	jobRepository.On("List").Return([]entity.Job{}, nil)
+	jobRepository.On("Save", mock.AnythingOfType("entity.Job")).Run(saved.save).Return(nil)
+
+	jobService := service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, helper, 0, -1, 0)
+
+	_, err := jobService.Enqueue(context.Background(), entity.TextManagement{})
+	if !errors.Is(err, service.ErrJobQueueFull) {
+		t.Errorf("JobService.Enqueue() error = %v, want %v", err, service.ErrJobQueueFull)
+	}
+}
+
+func TestJobService_Get(t *testing.T) {
+	jobId := "47b416d1-c5f2-417e-929e-7b83667c6654"
+	tests := []struct {
+		name         string
+		jobId        string
+		mockBehavior func(r *mockrepository.JobInterface)
+		wantKey      string
+		wantErr      bool
+	}{
+		{
+			name:  "Get finished job delivers private key",
+			jobId: jobId,
+			mockBehavior: func(r *mockrepository.JobInterface) {
+				r.On("Load", jobId).Return(entity.Job{Id: jobId, Status: entity.JobStatusDone, PrivateKey: "private key"}, nil)
+				r.On("Save", mock.MatchedBy(func(job entity.Job) bool {
+					return job.PrivateKey == "" && job.KeyDelivered && job.UpdatedAt.Equal(jobTime)
+				})).Return(nil)
+			},
+			wantKey: "private key",
+			wantErr: false,
+		},
+		{
+			name:  "Get finished job after key delivery",
+			jobId: jobId,
+			mockBehavior: func(r *mockrepository.JobInterface) {
+				r.On("Load", jobId).Return(entity.Job{Id: jobId, Status: entity.JobStatusDone, KeyDelivered: true}, nil)
+			},
+			wantKey: "",
+			wantErr: false,
+		},
+		{
+			name:  "Get finished job with save error",
+			jobId: jobId,
+			mockBehavior: func(r *mockrepository.JobInterface) {
+				r.On("Load", jobId).Return(entity.Job{Id: jobId, Status: entity.JobStatusDone, PrivateKey: "private key"}, nil)
+				r.On("Save", mock.AnythingOfType("entity.Job")).Return(errors.New("error"))
+			},
+			wantKey: "",
+			wantErr: true,
+		},
+		{
+			name:  "Get job with load error",
+			jobId: jobId,
+			mockBehavior: func(r *mockrepository.JobInterface) {
+				r.On("Load", jobId).Return(entity.Job{}, errors.New("error"))
+			},
+			wantKey: "",
+			wantErr: true,
+		},
+		{
+			name:    "Get job with invalid id",
+			jobId:   "../storage/2f13ed58",
+			wantKey: "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobRepository := &mockrepository.JobInterface{}
+			jobRepository.On("List").Return([]entity.Job{}, nil)
+			if tt.mockBehavior != nil {
+				tt.mockBehavior(jobRepository)
+			}
+
+			helper := &mockhelper.HelperInterface{}
+			helper.On("Now").Return(jobTime)
+
+			jobService := service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, helper, 0, 1, 0)
+
+			got, err := jobService.Get(tt.jobId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JobService.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.PrivateKey != tt.wantKey {
+				t.Errorf("JobService.Get() private key = %v, want %v", got.PrivateKey, tt.wantKey)
+			}
+
+			jobRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestJobService_FailInterruptedJobs(t *testing.T) {
+	saved := &savedJobs{jobs: map[string]entity.Job{}}
+	jobRepository := &mockrepository.JobInterface{}
+	jobRepository.On("List").Return([]entity.Job{
+		{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.JobStatusRunning},
+		{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Status: entity.JobStatusDone},
+	}, nil)
+	jobRepository.On("Save", mock.AnythingOfType("entity.Job")).Run(saved.save).Return(nil)
+	helper := &mockhelper.HelperInterface{}
+	helper.On("Now").Return(jobTime)
+
+	service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, helper, 0, 1, 0)
+
+	if saved.get("47b416d1-c5f2-417e-929e-7b83667c6654").Status != entity.JobStatusFailed {
+		t.Errorf("interrupted job status = %s, want %s", saved.get("47b416d1-c5f2-417e-929e-7b83667c6654").Status, entity.JobStatusFailed)
+	}
+	if _, found := saved.jobs["2f13ed58-afc9-477a-bf0d-c90eb1b7db90"]; found {
+		t.Error("finished job must not be saved again")
+	}
+}
+
+func TestJobService_FailInterruptedJobsListError(t *testing.T) {
+	jobRepository := &mockrepository.JobInterface{}
+	jobRepository.On("List").Return(nil, errors.New("error"))
+
+	service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, &mockhelper.HelperInterface{}, 0, 1, 0)
+
+	jobRepository.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestJobService_RemoveExpiredJobs(t *testing.T) {
+	jobRepository := &mockrepository.JobInterface{}
+	jobRepository.On("List").Return([]entity.Job{
+		{Id: "47b416d1-c5f2-417e-929e-7b83667c6654", Status: entity.JobStatusDone, PrivateKey: "private key", UpdatedAt: jobTime.Add(-2 * time.Hour)},
+		{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Status: entity.JobStatusFailed, UpdatedAt: jobTime.Add(-2 * time.Hour)},
+		{Id: "6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11", Status: entity.JobStatusDone, UpdatedAt: jobTime.Add(-time.Minute)},
+	}, nil)
+	jobRepository.On("Delete", "47b416d1-c5f2-417e-929e-7b83667c6654").Return(nil)
+	jobRepository.On("Delete", "2f13ed58-afc9-477a-bf0d-c90eb1b7db90").Return(apperror.New(apperror.NotFound, "job not found"))
+	helper := &mockhelper.HelperInterface{}
+	helper.On("Now").Return(jobTime)
+
+	jobService := service.NewJobService(&mockservice.TextManagementServiceInteface{}, jobRepository, helper, 0, 1, time.Hour)
+	jobService.Stop()
+
+	jobRepository.AssertExpectations(t)
+	jobRepository.AssertNotCalled(t, "Delete", "6c2a5a0e-3f4b-4f7e-8d7a-1f0f3c9c2b11")
+}
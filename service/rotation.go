@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+// rotationCheckpoint is the number of texts processed between two saves of the rotation progress
+const rotationCheckpoint = 50
+
+type RotationServiceInterface interface {
+	Start(ctx context.Context) (entity.Rotation, error)
+	Status(ctx context.Context) (entity.Rotation, error)
+	Stop()
+}
+
+type RotationService struct {
+	EncryptedRepository repository.EncryptedInterface
+	RotationRepository  repository.RotationInterface
+	AuditService        AuditServiceInterface
+	Helper              helper.HelperInterface
+	keyId               string
+	adminPrincipals     []string
+	rate                int
+	current             entity.Rotation
+	running             bool
+	mutex               sync.Mutex
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	wg                  sync.WaitGroup
+}
+
+// NewRotationService rewraps the stored texts with the primary master key keyId on demand, throttled to rate
+// texts per second. A rotation interrupted by a restart is resumed, or started over when the key changed
+func NewRotationService(encryptedRepository repository.EncryptedInterface, rotationRepository repository.RotationInterface, auditService AuditServiceInterface, helper helper.HelperInterface, keyId string, adminPrincipals []string, rate int) RotationServiceInterface {
+	ctx, cancel := context.WithCancel(context.Background())
+	rotationService := &RotationService{
+		EncryptedRepository: encryptedRepository,
+		RotationRepository:  rotationRepository,
+		AuditService:        auditService,
+		Helper:              helper,
+		keyId:               keyId,
+		adminPrincipals:     adminPrincipals,
+		rate:                rate,
+		ctx:                 ctx,
+		cancel:              cancel,
+	}
+
+	rotation, err := rotationRepository.Load()
+	if err != nil && apperror.CodeOf(err) != apperror.NotFound {
+		log.Error().Msg("last master key rotation could not be read: " + err.Error())
+	}
+	rotationService.current = rotation
+
+	if rotation.Status == entity.RotationStatusRunning {
+		if rotation.KeyId != keyId {
+			log.Info().Str("key_id", rotation.KeyId).Msg("Master key changed during the rotation, starting it over")
+			rotation = rotationService.newRotation()
+			if err := rotationRepository.Save(rotation); err != nil {
+				log.Error().Msg("master key rotation could not be saved: " + err.Error())
+			}
+		} else {
+			log.Info().Str("rotation_id", rotation.Id).Int("processed", rotation.Processed).Msg("Resuming the master key rotation")
+		}
+		rotationService.launch(rotation)
+	}
+
+	return rotationService
+}
+
+// Start rewraps every stored text with the primary master key in the background, admin principals only.
+// A rotation already running is returned instead of starting another one
+func (r *RotationService) Start(ctx context.Context) (entity.Rotation, error) {
+	rotation, err := r.start(ctx)
+	r.AuditService.Record(ctx, entity.AuditActionKeyRotation, "", err)
+
+	return rotation, err
+}
+
+func (r *RotationService) start(ctx context.Context) (entity.Rotation, error) {
+	if !isAdmin(ctx, r.adminPrincipals) {
+		logging.FromContext(ctx).Info().Msg("master key rotation denied")
+		return entity.Rotation{}, apperror.New(apperror.Forbidden, "an admin client certificate is required to rotate the master key")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.running {
+		return r.current, nil
+	}
+	if r.ctx.Err() != nil {
+		return entity.Rotation{}, apperror.New(apperror.ServiceUnavailable, "rotation service is shutting down, try again later")
+	}
+
+	rotation := r.newRotation()
+	if err := r.RotationRepository.Save(rotation); err != nil {
+		return entity.Rotation{}, err
+	}
+	logging.FromContext(ctx).Info().Str("rotation_id", rotation.Id).Str("key_id", rotation.KeyId).Msg("Master key rotation started")
+
+	r.current = rotation
+	r.running = true
+	r.wg.Add(1)
+	go r.run(rotation)
+
+	return rotation, nil
+}
+
+// Status returns the progress of the running or last rotation, admin principals only
+func (r *RotationService) Status(ctx context.Context) (entity.Rotation, error) {
+	if !isAdmin(ctx, r.adminPrincipals) {
+		return entity.Rotation{}, apperror.New(apperror.Forbidden, "an admin client certificate is required to read the master key rotation")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.current.Id == "" {
+		return entity.Rotation{}, apperror.New(apperror.NotFound, "no master key rotation has run")
+	}
+
+	return r.current, nil
+}
+
+// Stop interrupts the running rotation, saving its progress so it's resumed in the next start
+func (r *RotationService) Stop() {
+	r.mutex.Lock()
+	r.cancel()
+	r.mutex.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *RotationService) newRotation() entity.Rotation {
+	now := r.Helper.Now()
+
+	return entity.Rotation{
+		Id:        r.Helper.GenerateUuid(),
+		Status:    entity.RotationStatusRunning,
+		KeyId:     r.keyId,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func (r *RotationService) launch(rotation entity.Rotation) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.current = rotation
+	r.running = true
+	r.wg.Add(1)
+	go r.run(rotation)
+}
+
+// run rewraps the texts after the cursor in order, the deleted ones are skipped
+func (r *RotationService) run(rotation entity.Rotation) {
+	defer r.wg.Done()
+	logger := log.With().Str("rotation_id", rotation.Id).Logger()
+	ctx := logging.NewContext(r.ctx, logger)
+
+	fileNames, err := r.EncryptedRepository.List(ctx)
+	if err != nil {
+		rotation.Error = err.Error()
+		r.finish(rotation, entity.RotationStatusFailed)
+		return
+	}
+	rotation.Total = len(fileNames)
+
+	var throttle *time.Ticker
+	if r.rate > 0 {
+		throttle = time.NewTicker(time.Second / time.Duration(r.rate))
+		defer throttle.Stop()
+	}
+
+	for _, fileName := range fileNames {
+		if fileName <= rotation.Cursor {
+			continue
+		}
+		if throttle != nil {
+			select {
+			case <-throttle.C:
+			case <-r.ctx.Done():
+			}
+		}
+		if r.ctx.Err() != nil {
+			logger.Info().Int("processed", rotation.Processed).Int("total", rotation.Total).Msg("Master key rotation interrupted")
+			r.update(rotation, true)
+			return
+		}
+
+		changed, err := r.EncryptedRepository.Rewrap(ctx, fileName)
+		switch {
+		case apperror.CodeOf(err) == apperror.NotFound:
+			// deleted since the texts were listed
+		case err != nil:
+			rotation.Failed++
+			logger.Error().Str("text_id", fileName).Msg("text could not be rewrapped: " + err.Error())
+		case changed:
+			rotation.Rewrapped++
+		}
+		rotation.Processed++
+		rotation.Cursor = fileName
+
+		checkpoint := rotation.Processed%rotationCheckpoint == 0
+		if checkpoint {
+			logger.Info().Int("processed", rotation.Processed).Int("total", rotation.Total).Msg("Master key rotation progress")
+		}
+		r.update(rotation, checkpoint)
+	}
+
+	status := entity.RotationStatusDone
+	if rotation.Failed > 0 {
+		status = entity.RotationStatusFailed
+		rotation.Error = fmt.Sprintf("%d texts could not be rewrapped, start the rotation again to retry them", rotation.Failed)
+	}
+	r.finish(rotation, status)
+}
+
+// update publishes the progress, saving it when asked to
+func (r *RotationService) update(rotation entity.Rotation, save bool) {
+	rotation.UpdatedAt = r.Helper.Now()
+
+	r.mutex.Lock()
+	r.current = rotation
+	r.mutex.Unlock()
+
+	if save {
+		if err := r.RotationRepository.Save(rotation); err != nil {
+			log.Error().Str("rotation_id", rotation.Id).Msg("rotation progress could not be saved: " + err.Error())
+		}
+	}
+}
+
+func (r *RotationService) finish(rotation entity.Rotation, status string) {
+	now := r.Helper.Now()
+	rotation.Status = status
+	rotation.FinishedAt = &now
+	r.update(rotation, true)
+
+	r.mutex.Lock()
+	r.running = false
+	r.mutex.Unlock()
+
+	log.Info().Str("rotation_id", rotation.Id).Str("status", status).Int("rewrapped", rotation.Rewrapped).Int("failed", rotation.Failed).Msg("Master key rotation finished")
+}
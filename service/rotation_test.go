@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/helper"
+	mockrepository "zcelero/mocks/repository"
+	mockservice "zcelero/mocks/service"
+	"zcelero/principal"
+	"zcelero/repository"
+	"zcelero/service"
+
+	"github.com/stretchr/testify/mock"
+)
+
+const rotationAdmin = "CN=admin,O=Zcelero"
+
+// waitRotation polls the rotation until it isn't running anymore
+func waitRotation(t *testing.T, rotationService service.RotationServiceInterface) entity.Rotation {
+	t.Helper()
+	ctx := principal.NewContext(context.Background(), rotationAdmin)
+
+	for i := 0; i < 200; i++ {
+		rotation, err := rotationService.Status(ctx)
+		if err == nil && rotation.Status != entity.RotationStatusRunning {
+			return rotation
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("rotation is still running")
+	return entity.Rotation{}
+}
+
+func newRotationRepository(t *testing.T) repository.RotationInterface {
+	rotationRepository, err := repository.NewRotationRepository(helper.NewHelper(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRotationRepository() error = %v", err)
+	}
+
+	return rotationRepository
+}
+
+func TestRotationService_Start(t *testing.T) {
+	encryptedRepository := &mockrepository.EncryptedInterface{}
+	encryptedRepository.On("List", mock.Anything).Return([]string{"a", "b", "c", "d"}, nil)
+	encryptedRepository.On("Rewrap", mock.Anything, "a").Return(true, nil)
+	encryptedRepository.On("Rewrap", mock.Anything, "b").Return(false, nil)
+	encryptedRepository.On("Rewrap", mock.Anything, "c").Return(false, apperror.New(apperror.NotFound, "text not found"))
+	encryptedRepository.On("Rewrap", mock.Anything, "d").Return(false, errors.New("master key \"old\" is not in the keyring"))
+	auditService := &mockservice.AuditServiceInterface{}
+	auditService.On("Record", mock.Anything, entity.AuditActionKeyRotation, "", mock.Anything).Return()
+	rotationRepository := newRotationRepository(t)
+
+	rotationService := service.NewRotationService(encryptedRepository, rotationRepository, auditService, helper.NewHelper(), "new", []string{rotationAdmin}, 0)
+	defer rotationService.Stop()
+
+	if _, err := rotationService.Start(principal.NewContext(context.Background(), "CN=alice")); apperror.CodeOf(err) != apperror.Forbidden {
+		t.Errorf("RotationService.Start() error = %v, want %v", err, apperror.Forbidden)
+	}
+	if _, err := rotationService.Status(principal.NewContext(context.Background(), rotationAdmin)); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("RotationService.Status() error = %v, want %v", err, apperror.NotFound)
+	}
+
+	started, err := rotationService.Start(principal.NewContext(context.Background(), rotationAdmin))
+	if err != nil {
+		t.Fatalf("RotationService.Start() error = %v", err)
+	}
+	if started.Status != entity.RotationStatusRunning || started.KeyId != "new" {
+		t.Errorf("RotationService.Start() = %+v, want a running rotation to new", started)
+	}
+
+	got := waitRotation(t, rotationService)
+	if got.Id != started.Id || got.Status != entity.RotationStatusFailed || got.Total != 4 || got.Processed != 4 || got.Rewrapped != 1 || got.Failed != 1 || got.Error == "" || got.FinishedAt == nil {
+		t.Errorf("RotationService.Status() = %+v, want one text rewrapped and one failed", got)
+	}
+	if saved, _ := rotationRepository.Load(); saved.Status != entity.RotationStatusFailed || saved.Cursor != "d" {
+		t.Errorf("saved rotation = %+v, want the finished one", saved)
+	}
+
+	auditService.AssertNumberOfCalls(t, "Record", 2)
+}
+
+func TestRotationService_Resume(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyId       string
+		wantRewraps []string
+	}{
+		{name: "Resume after the cursor", keyId: "new", wantRewraps: []string{"c"}},
+		{name: "Start over when the key changed", keyId: "newer", wantRewraps: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptedRepository := &mockrepository.EncryptedInterface{}
+			encryptedRepository.On("List", mock.Anything).Return([]string{"a", "b", "c"}, nil)
+			for _, fileName := range tt.wantRewraps {
+				encryptedRepository.On("Rewrap", mock.Anything, fileName).Return(true, nil).Once()
+			}
+			rotationRepository := newRotationRepository(t)
+			rotationRepository.Save(entity.Rotation{Id: "2f13ed58-afc9-477a-bf0d-c90eb1b7db90", Status: entity.RotationStatusRunning, KeyId: "new", Processed: 2, Rewrapped: 2, Cursor: "b"})
+
+			rotationService := service.NewRotationService(encryptedRepository, rotationRepository, &mockservice.AuditServiceInterface{}, helper.NewHelper(), tt.keyId, []string{rotationAdmin}, 0)
+			defer rotationService.Stop()
+
+			got := waitRotation(t, rotationService)
+			if got.Status != entity.RotationStatusDone || got.KeyId != tt.keyId || got.Processed != 3 || got.Rewrapped != 3 {
+				t.Errorf("RotationService.Status() = %+v, want every text processed with %s", got, tt.keyId)
+			}
+			encryptedRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRotationService_Stop(t *testing.T) {
+	fileNames := []string{}
+	for i := 0; i < 100; i++ {
+		fileNames = append(fileNames, fmt.Sprintf("%03d", i))
+	}
+	encryptedRepository := &mockrepository.EncryptedInterface{}
+	encryptedRepository.On("List", mock.Anything).Return(fileNames, nil)
+	encryptedRepository.On("Rewrap", mock.Anything, mock.Anything).Return(true, nil)
+	auditService := &mockservice.AuditServiceInterface{}
+	auditService.On("Record", mock.Anything, entity.AuditActionKeyRotation, "", nil).Return()
+	rotationRepository := newRotationRepository(t)
+
+	// 100 texts per second, the rotation is interrupted before the end
+	rotationService := service.NewRotationService(encryptedRepository, rotationRepository, auditService, helper.NewHelper(), "new", []string{rotationAdmin}, 100)
+	if _, err := rotationService.Start(principal.NewContext(context.Background(), rotationAdmin)); err != nil {
+		t.Fatalf("RotationService.Start() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	rotationService.Stop()
+
+	saved, err := rotationRepository.Load()
+	if err != nil {
+		t.Fatalf("rotationRepository.Load() error = %v", err)
+	}
+	if saved.Status != entity.RotationStatusRunning || saved.Processed == 0 || saved.Processed >= len(fileNames) || saved.Cursor != fileNames[saved.Processed-1] {
+		t.Fatalf("saved rotation = %+v, want the progress of the interrupted rotation", saved)
+	}
+
+	rotationService = service.NewRotationService(encryptedRepository, rotationRepository, auditService, helper.NewHelper(), "new", []string{rotationAdmin}, 0)
+	defer rotationService.Stop()
+	if got := waitRotation(t, rotationService); got.Id != saved.Id || got.Status != entity.RotationStatusDone || got.Processed != len(fileNames) {
+		t.Errorf("RotationService.Status() = %+v, want the rotation resumed until the end", got)
+	}
+	encryptedRepository.AssertNumberOfCalls(t, "Rewrap", len(fileNames))
+}
@@ -1,197 +1,530 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"io"
+	"strconv"
+	"sync"
+	"time"
+	"zcelero/apperror"
 	"zcelero/entity"
 	"zcelero/helper"
+	"zcelero/keypool"
+	"zcelero/logging"
+	"zcelero/metrics"
+	"zcelero/principal"
 	"zcelero/repository"
+	"zcelero/textcrypto"
+	"zcelero/tracing"
 
-	"github.com/rs/zerolog/log"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-type fileContent struct {
-	Content   string
-	Encrypted bool
-}
+var ErrPassphraseBusy = apperror.New(apperror.ServiceUnavailable, "too many passphrase texts are being processed, try again later")
 
 type TextManagementServiceInteface interface {
-	Get(textId, privateKey, password string) (string, error)
-	Insert(text entity.TextManagement) (entity.TextManagement, error)
+	Get(ctx context.Context, textId, privateKey, password string) (string, error)
+	GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error)
+	Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error)
+	Delete(ctx context.Context, textId, privateKey, password string) error
+	ChangePassword(ctx context.Context, change entity.PasswordChange) (string, error)
+	Rekey(ctx context.Context, textId string, rekey entity.Rekey) (string, error)
 }
 
 type TextManagementService struct {
 	TextManagementRepository repository.TextManagementInterface
 	Helper                   helper.HelperInterface
+	KeyPool                  keypool.KeyPoolInterface
+	// rekeyLocks serializes the rekeys of each text, so the second rekey of a text finds it under the new key
+	// and fails instead of both answering with a private key when only the last one opens the text. Rekeys of
+	// different texts run in parallel
+	rekeyLocks textLocks
+	// kdfSlots limits the Argon2id derivations of the passphrase texts running at the same time, each one
+	// takes 64 MiB and 4 threads. A nil channel removes the limit
+	kdfSlots chan struct{}
+}
+
+// textLocks holds a mutex for each text id in use, the zero value is ready to use
+type textLocks struct {
+	mutex sync.Mutex
+	locks map[string]*textLock
+}
+
+// textLock counts the holders and waiters of the mutex, it's removed from textLocks when none is left
+type textLock struct {
+	sync.Mutex
+	users int
 }
 
-func NewService(textManagementRepository repository.TextManagementInterface, helper helper.HelperInterface) TextManagementServiceInteface {
-	return &TextManagementService{
+// lock waits for the mutex of the text id, returning the function releasing it
+func (l *textLocks) lock(textId string) func() {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*textLock{}
+	}
+	lock, found := l.locks[textId]
+	if !found {
+		lock = &textLock{}
+		l.locks[textId] = lock
+	}
+	lock.users++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(l.locks, textId)
+		}
+		l.mutex.Unlock()
+	}
+}
+
+// NewService builds the service, maxConcurrentKDF is how many passphrase texts can be encrypted or decrypted
+// at the same time, zero removes the limit
+func NewService(textManagementRepository repository.TextManagementInterface, helper helper.HelperInterface, keyPool keypool.KeyPoolInterface, maxConcurrentKDF int) TextManagementServiceInteface {
+	service := &TextManagementService{
 		TextManagementRepository: textManagementRepository,
 		Helper:                   helper,
+		KeyPool:                  keyPool,
+	}
+	if maxConcurrentKDF > 0 {
+		service.kdfSlots = make(chan struct{}, maxConcurrentKDF)
 	}
+
+	return service
 }
 
 // Get load the file content and decrypt it if necessary
-func (t *TextManagementService) Get(textId, privateKeyString, password string) (string, error) {
-	log.Debug().Msg("Loading message from file")
+func (t *TextManagementService) Get(ctx context.Context, textId, privateKeyString, password string) (string, error) {
+	ctx = logging.With(ctx, "text_id", textId)
+	logging.FromContext(ctx).Debug().Msg("Loading message from file")
 
-	log.Debug().Msg("Opening file")
-
-	data, err := t.TextManagementRepository.Load(textId)
+	fileData, err := t.load(ctx, textId)
 	if err != nil {
 		return "", err
 	}
 
-	fileData := fileContent{}
-	json.Unmarshal(data, &fileData)
-
-	message := string(fileData.Content)
-	if fileData.Encrypted {
-		if privateKeyString == "" {
-			err := errors.New("private key is required to read this file")
-			log.Info().Msg(err.Error())
-			return "", err
-		}
-		if password == "" {
-			err := errors.New("password is required to read this file")
-			log.Info().Msg(err.Error())
-			return "", err
+	message, err := t.decrypt(ctx, fileData, privateKeyString, password)
+	if err != nil {
+		logError(ctx, err)
+		if code := apperror.CodeOf(err); code != apperror.ValidationFailed && code != apperror.ServiceUnavailable {
+			metrics.CryptoFailures.WithLabelValues("decrypt", string(code)).Inc()
 		}
+		return "", err
+	}
 
-		log.Debug().Msg("Decoding base64")
+	logging.FromContext(ctx).Debug().Msg("Message loaded successfully")
 
-		content, _ := base64.StdEncoding.DecodeString(fileData.Content)
+	return message, nil
+}
 
-		log.Debug().Msg("Decrypting message")
+// GetMetadata load the file and describe it without decrypting the content
+func (t *TextManagementService) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	ctx = logging.With(ctx, "text_id", textId)
+	logging.FromContext(ctx).Debug().Msg("Loading metadata from file")
 
-		privateKey, err := decryptPrivateKey(privateKeyString, password)
-		if err != nil {
-			log.Error().Msg(err.Error())
-			return "", err
-		}
+	fileData, err := t.load(ctx, textId)
+	if err != nil {
+		return entity.TextMetadata{}, err
+	}
 
-		message, err = decryptMessage(privateKey, content)
-		if err != nil {
-			log.Error().Msg(err.Error())
-			return "", err
+	metadata := entity.TextMetadata{
+		Uuid:      textId,
+		Encrypted: fileData.Encrypted,
+		KeySize:   fileData.KeySize,
+		CreatedAt: fileData.CreatedAt,
+		Owner:     fileData.Owner,
+		RekeyedAt: fileData.RekeyedAt,
+	}
+	if fileData.Encrypted {
+		metadata.EncryptionMode = entity.EncryptionModeRSA
+		if fileData.Mode == textcrypto.ModePassphrase {
+			metadata.EncryptionMode = entity.EncryptionModePassphrase
 		}
 	}
 
-	log.Debug().Msg("Message loaded successfully")
-
-	return message, nil
+	return metadata, nil
 }
 
 // Insert encrypt the message if necessary and save into a file
-func (t *TextManagementService) Insert(text entity.TextManagement) (entity.TextManagement, error) {
-	log.Debug().Msg("Creating new file with message")
+func (t *TextManagementService) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	logging.FromContext(ctx).Debug().Msg("Creating new file with message")
 
 	text.Uuid = t.Helper.GenerateUuid()
+	ctx = logging.With(ctx, "text_id", text.Uuid)
+
+	var kdf *textcrypto.KDF
+	if *text.Encryption && text.EncryptionMode == entity.EncryptionModePassphrase {
+		logging.FromContext(ctx).Debug().Msg("Encrypting message with the passphrase")
+
+		var err error
+		text.TextData, kdf, err = t.sealWithPassphrase(ctx, text)
+		if err != nil {
+			logError(ctx, err)
+			if code := apperror.CodeOf(err); code != apperror.ServiceUnavailable {
+				metrics.CryptoFailures.WithLabelValues("encrypt", string(code)).Inc()
+			}
+			return entity.TextManagement{}, err
+		}
 
-	if *text.Encryption {
-		log.Debug().Msg("Encrypting message")
+		logging.FromContext(ctx).Debug().Msg("Encryption finished")
+	} else if *text.Encryption {
+		logging.FromContext(ctx).Debug().Msg("Encrypting message")
 
 		var err error
 		var encodedMessage []byte
 		randReader := rand.Reader
-		publicKey, privateKey, err := generatePairKey(randReader, text.KeySize, text.PrivateKeyPassword)
+		rsaKey, err := t.generateKey(ctx, randReader, text.KeySize)
 		if err != nil {
-			log.Error().Msg(err.Error())
-			return entity.TextManagement{}, err
+			logging.FromContext(ctx).Error().Msg(err.Error())
+			return entity.TextManagement{}, apperror.Wrap(apperror.Internal, "key pair could not be generated", err)
 		}
 
-		encodedMessage, err = encryptMessage(randReader, publicKey, text.TextData)
+		var privateKey string
+		privateKey, encodedMessage, err = t.encrypt(ctx, randReader, rsaKey, text)
 		if err != nil {
-			log.Error().Msg(err.Error())
+			logError(ctx, err)
+			metrics.CryptoFailures.WithLabelValues("encrypt", string(apperror.CodeOf(err))).Inc()
 			return entity.TextManagement{}, err
 		}
 
-		log.Debug().Msg("Encoding into base64")
+		logging.FromContext(ctx).Debug().Msg("Encoding into base64")
 
 		text.PrivateKey = privateKey
 		text.TextData = base64.StdEncoding.EncodeToString(encodedMessage)
 
-		log.Debug().Msg("Encryption finished")
+		logging.FromContext(ctx).Debug().Msg("Encryption finished")
 	}
 
-	log.Debug().Msg("Saving data into file")
+	logging.FromContext(ctx).Debug().Msg("Saving data into file")
 
-	fileData := fileContent{
+	createdAt := t.Helper.Now()
+	fileData := textcrypto.FileContent{
 		Content:   text.TextData,
 		Encrypted: *text.Encryption,
+		KeySize:   text.KeySize,
+		CreatedAt: &createdAt,
+		Owner:     principal.FromContext(ctx),
+	}
+	if !*text.Encryption {
+		fileData.KeySize = 0
+	}
+	if kdf != nil {
+		fileData.Mode = textcrypto.ModePassphrase
+		fileData.KDF = kdf
+		fileData.KeySize = 0
 	}
 	b, _ := json.Marshal(fileData)
 
-	err := t.TextManagementRepository.Save(text.Uuid, string(b))
+	err := t.TextManagementRepository.Save(ctx, text.Uuid, string(b))
 	if err != nil {
 		return entity.TextManagement{}, err
 	}
+	metrics.Inserts.WithLabelValues(strconv.FormatBool(fileData.Encrypted), strconv.FormatUint(fileData.KeySize, 10)).Inc()
 
-	log.Debug().Msg("Message saved successfully")
+	logging.FromContext(ctx).Debug().Msg("Message saved successfully")
 
 	return text, nil
 }
 
-func generatePairKey(randReader io.Reader, keySize uint64, privateKeyPassword string) (*rsa.PublicKey, string, error) {
-	privatekey, _ := rsa.GenerateKey(randReader, int(keySize))
-	publicKey := &privatekey.PublicKey
+// Delete removes the stored text once the caller proves it owns it, with the client certificate that stored
+// it or with the private key and password that open it. Unencrypted texts stored without a client certificate
+// can be removed by anyone knowing their id, like they can be read
+func (t *TextManagementService) Delete(ctx context.Context, textId, privateKeyString, password string) error {
+	ctx = logging.With(ctx, "text_id", textId)
+	logging.FromContext(ctx).Debug().Msg("Removing file")
 
-	privateKeyBytes := x509.MarshalPKCS1PrivateKey(privatekey)
-	block := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: privateKeyBytes,
+	fileData, err := t.load(ctx, textId)
+	if err != nil {
+		return err
 	}
 
-	block, err := x509.EncryptPEMBlock(randReader, block.Type, block.Bytes, []byte(privateKeyPassword), x509.PEMCipherAES256)
+	err = t.authorizeDelete(ctx, fileData, privateKeyString, password)
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return nil, "", err
+		logError(ctx, err)
+		if code := apperror.CodeOf(err); code == apperror.WrongPassword || code == apperror.DecryptionFailed {
+			metrics.CryptoFailures.WithLabelValues("decrypt", string(code)).Inc()
+		}
+		return err
 	}
 
-	return publicKey, string(pem.EncodeToMemory(block)), nil
+	err = t.TextManagementRepository.Delete(ctx, textId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug().Msg("Message removed successfully")
+
+	return nil
 }
 
-func encryptMessage(randReader io.Reader, publicKey *rsa.PublicKey, textData string) ([]byte, error) {
-	ciphertext, err := rsa.EncryptOAEP(sha256.New(), randReader, publicKey, []byte(textData), nil)
+// ChangePassword encrypts the private key again with the new password. The key is returned in the PKCS#8
+// format whatever the format it came in, nothing is stored
+func (t *TextManagementService) ChangePassword(ctx context.Context, change entity.PasswordChange) (string, error) {
+	logging.FromContext(ctx).Debug().Msg("Changing private key password")
+
+	privateKey, err := t.changePassword(ctx, change)
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return nil, err
+		logError(ctx, err)
+		if code := apperror.CodeOf(err); code != apperror.ValidationFailed {
+			metrics.CryptoFailures.WithLabelValues("change_password", string(code)).Inc()
+		}
+		return "", err
 	}
 
-	return ciphertext, nil
+	logging.FromContext(ctx).Debug().Msg("Private key password changed successfully")
+
+	return privateKey, nil
 }
 
-func decryptPrivateKey(privateKeyString string, password string) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode([]byte(privateKeyString))
-	bytePK, err := x509.DecryptPEMBlock(block, []byte(password))
+// Rekey decrypts the stored text with its private key and encrypts it again with a new key pair, or with the
+// public key sent by the client, replacing the stored text under the same id. The new private key is returned,
+// empty when the client sent its public key, and the old one can't open the text anymore
+func (t *TextManagementService) Rekey(ctx context.Context, textId string, rekey entity.Rekey) (string, error) {
+	ctx = logging.With(ctx, "text_id", textId)
+	logging.FromContext(ctx).Debug().Msg("Re-keying text")
+
+	defer t.rekeyLocks.lock(textId)()
+
+	fileData, err := t.load(ctx, textId)
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return nil, err
+		return "", err
+	}
+	if !fileData.Encrypted {
+		logging.FromContext(ctx).Info().Msg("rekey requested for an unencrypted text")
+		return "", apperror.New(apperror.ValidationFailed, "text is not encrypted, it has no key to change")
+	}
+	if fileData.Mode == textcrypto.ModePassphrase {
+		logging.FromContext(ctx).Info().Msg("rekey requested for a passphrase text")
+		return "", apperror.New(apperror.ValidationFailed, "text is encrypted with a passphrase, it has no key pair to change")
+	}
+
+	privateKey, err := t.rekey(ctx, &fileData, rekey)
+	if err != nil {
+		logError(ctx, err)
+		if code := apperror.CodeOf(err); code != apperror.ValidationFailed {
+			metrics.CryptoFailures.WithLabelValues("rekey", string(code)).Inc()
+		}
+		return "", err
 	}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(bytePK)
+	logging.FromContext(ctx).Debug().Msg("Replacing stored text")
+
+	rekeyedAt := t.Helper.Now()
+	fileData.RekeyedAt = &rekeyedAt
+	b, _ := json.Marshal(fileData)
+
+	err = t.TextManagementRepository.Replace(ctx, textId, string(b))
 	if err != nil {
-		log.Error().Msg(err.Error())
-		return nil, err
+		return "", err
 	}
 
+	logging.FromContext(ctx).Debug().Msg("Text re-keyed successfully")
+
 	return privateKey, nil
 }
 
-func decryptMessage(privateKey *rsa.PrivateKey, data []byte) (string, error) {
-	decriptedData, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, data, nil)
+// authorizeDelete accepts the owner of the text, and for encrypted texts the private key and password, or the
+// passphrase, that decrypt it
+func (t *TextManagementService) authorizeDelete(ctx context.Context, fileData textcrypto.FileContent, privateKey, password string) error {
+	if fileData.Owner != "" && principal.FromContext(ctx) == fileData.Owner {
+		return nil
+	}
+
+	if !fileData.Encrypted {
+		if fileData.Owner != "" {
+			return apperror.New(apperror.Forbidden, "only the client certificate that stored the text can remove it")
+		}
+		return nil
+	}
+
+	if password == "" || (privateKey == "" && fileData.Mode != textcrypto.ModePassphrase) {
+		return apperror.New(apperror.Forbidden, "the private key and password of the text, or the client certificate that stored it, are required to remove it")
+	}
+	_, err := t.decrypt(ctx, fileData, privateKey, password)
+
+	return err
+}
+
+// load validates the id and reads the stored file
+func (t *TextManagementService) load(ctx context.Context, textId string) (textcrypto.FileContent, error) {
+	if _, err := uuid.Parse(textId); err != nil {
+		logging.FromContext(ctx).Info().Msg("invalid text id")
+		return textcrypto.FileContent{}, apperror.New(apperror.InvalidId, "id must be a valid uuid")
+	}
+
+	logging.FromContext(ctx).Debug().Msg("Opening file")
+
+	data, err := t.TextManagementRepository.Load(ctx, textId)
+	if err != nil {
+		return textcrypto.FileContent{}, err
+	}
+
+	fileData := textcrypto.FileContent{}
+	err = json.Unmarshal(data, &fileData)
+	if err != nil {
+		logging.FromContext(ctx).Error().Msg(err.Error())
+		return textcrypto.FileContent{}, apperror.Wrap(apperror.Internal, "stored text could not be read", err)
+	}
+
+	return fileData, nil
+}
+
+// generateKey takes a pre-generated key from the pool when available
+func (t *TextManagementService) generateKey(ctx context.Context, randReader io.Reader, keySize uint64) (key *rsa.PrivateKey, err error) {
+	_, span := tracing.Start(ctx, "keys.Generate")
+	span.SetAttributes(attribute.Int64("key_size", int64(keySize)), attribute.Bool("key_pool", t.KeyPool != nil))
+	defer func() { tracing.End(span, err) }()
+
+	if t.KeyPool == nil {
+		start := time.Now()
+		key, err = rsa.GenerateKey(randReader, int(keySize))
+		metrics.KeyGenerationDuration.WithLabelValues(strconv.FormatUint(keySize, 10)).Observe(metrics.Since(start))
+		return key, err
+	}
+
+	return t.KeyPool.Get(keySize)
+}
+
+// encrypt protects the private key with the password and encrypts the text with the public key
+func (t *TextManagementService) encrypt(ctx context.Context, randReader io.Reader, rsaKey *rsa.PrivateKey, text entity.TextManagement) (privateKey string, encodedMessage []byte, err error) {
+	_, span := tracing.Start(ctx, "crypto.Encrypt")
+	span.SetAttributes(attribute.Int64("key_size", int64(text.KeySize)))
+	defer func() { tracing.End(span, err) }()
+
+	publicKey, privateKey, err := textcrypto.GeneratePairKey(randReader, rsaKey, text.PrivateKeyPassword)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encodedMessage, err = textcrypto.EncryptMessage(randReader, publicKey, text.TextData)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return privateKey, encodedMessage, nil
+}
+
+// sealWithPassphrase encrypts the text with a key derived from its password
+func (t *TextManagementService) sealWithPassphrase(ctx context.Context, text entity.TextManagement) (content string, kdf *textcrypto.KDF, err error) {
+	_, span := tracing.Start(ctx, "crypto.Encrypt")
+	span.SetAttributes(attribute.String("encryption_mode", entity.EncryptionModePassphrase))
+	defer func() { tracing.End(span, err) }()
+
+	release, err := t.acquireKDF()
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+
+	return textcrypto.SealWithPassphrase(rand.Reader, text.PrivateKeyPassword, text.TextData)
+}
+
+// decrypt opens the stored text with the private key and its password
+func (t *TextManagementService) decrypt(ctx context.Context, fileData textcrypto.FileContent, privateKey, password string) (message string, err error) {
+	_, span := tracing.Start(ctx, "crypto.Decrypt")
+	span.SetAttributes(attribute.Bool("encrypted", fileData.Encrypted))
+	defer func() { tracing.End(span, err) }()
+
+	if fileData.Encrypted && fileData.Mode == textcrypto.ModePassphrase {
+		release, err := t.acquireKDF()
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	}
+
+	return textcrypto.Open(fileData, privateKey, password)
+}
+
+// acquireKDF takes a slot for an Argon2id derivation, returning the function releasing it. It fails at once
+// when every slot is taken, so a burst of passphrase requests is answered with 503 instead of piling up
+// their memory
+func (t *TextManagementService) acquireKDF() (func(), error) {
+	if t.kdfSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case t.kdfSlots <- struct{}{}:
+		return func() { <-t.kdfSlots }, nil
+	default:
+		return nil, ErrPassphraseBusy
+	}
+}
+
+// changePassword decrypts the private key with the current password and encrypts it with the new one
+func (t *TextManagementService) changePassword(ctx context.Context, change entity.PasswordChange) (privateKey string, err error) {
+	_, span := tracing.Start(ctx, "crypto.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	return textcrypto.ChangePassword(rand.Reader, change.PrivateKey, change.PrivateKeyPassword, change.NewPrivateKeyPassword)
+}
+
+// rekey decrypts the content of the file and encrypts it again with the new public key, returning the new
+// private key protected by the new password when the key pair was generated here
+func (t *TextManagementService) rekey(ctx context.Context, fileData *textcrypto.FileContent, rekey entity.Rekey) (privateKey string, err error) {
+	message, err := t.decrypt(ctx, *fileData, rekey.PrivateKey, rekey.PrivateKeyPassword)
 	if err != nil {
-		log.Error().Msg(err.Error())
 		return "", err
 	}
 
-	return string(decriptedData), nil
+	var publicKey *rsa.PublicKey
+	if rekey.PublicKey != "" {
+		publicKey, err = textcrypto.ParsePublicKey(rekey.PublicKey)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		keySize := rekey.KeySize
+		if keySize == 0 {
+			keySize = fileData.KeySize
+		}
+		if keySize == 0 {
+			return "", apperror.New(apperror.ValidationFailed, "key_size is required, the size of the stored key is unknown")
+		}
+
+		rsaKey, err := t.generateKey(ctx, rand.Reader, keySize)
+		if err != nil {
+			return "", apperror.Wrap(apperror.Internal, "key pair could not be generated", err)
+		}
+		privateKey, err = textcrypto.EncryptPrivateKey(rand.Reader, rsaKey, rekey.NewPrivateKeyPassword)
+		if err != nil {
+			return "", err
+		}
+		publicKey = &rsaKey.PublicKey
+	}
+
+	_, span := tracing.Start(ctx, "crypto.Encrypt")
+	span.SetAttributes(attribute.Int64("key_size", int64(publicKey.N.BitLen())))
+	encodedMessage, err := textcrypto.EncryptMessage(rand.Reader, publicKey, message)
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+
+	fileData.Content = base64.StdEncoding.EncodeToString(encodedMessage)
+	fileData.KeySize = uint64(publicKey.N.BitLen())
+
+	return privateKey, nil
+}
+
+// logError logs the errors caused by the client as info and the others as errors, with their cause
+func logError(ctx context.Context, err error) {
+	logger := logging.FromContext(ctx)
+	event := logger.Error()
+	if apperror.Status(apperror.CodeOf(err)) < 500 {
+		event = logger.Info()
+	}
+
+	event.Str("code", string(apperror.CodeOf(err))).AnErr("cause", errors.Unwrap(err)).Msg(err.Error())
 }
@@ -0,0 +1,493 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/helper"
+	"zcelero/logging"
+	"zcelero/metrics"
+	"zcelero/principal"
+	"zcelero/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// maxWebhookBackoff caps the wait between two delivery attempts
+const maxWebhookBackoff = time.Hour
+
+type WebhookServiceInterface interface {
+	Subscribe(ctx context.Context, subscription entity.WebhookSubscription) (entity.WebhookSubscription, error)
+	List(ctx context.Context) ([]entity.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, subscriptionId string) error
+	Notify(ctx context.Context, event entity.WebhookEvent)
+	HasOwnerSubscriptions() bool
+	Stop()
+}
+
+type WebhookService struct {
+	WebhookRepository     repository.WebhookInterface
+	TextManagementService TextManagementServiceInteface
+	Helper                helper.HelperInterface
+	config                config.Webhooks
+	client                *http.Client
+	subscriptions         map[string]entity.WebhookSubscription
+	mutex                 sync.Mutex
+	wake                  chan struct{}
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	wg                    sync.WaitGroup
+}
+
+// NewWebhookService loads the subscriptions and starts the dispatcher, which delivers the pending events
+// left by a previous process first. The text service is only used to find the owner of the texts
+func NewWebhookService(webhookRepository repository.WebhookInterface, textManagementService TextManagementServiceInteface, helper helper.HelperInterface, config config.Webhooks) WebhookServiceInterface {
+	ctx, cancel := context.WithCancel(context.Background())
+	webhookService := &WebhookService{
+		WebhookRepository:     webhookRepository,
+		TextManagementService: textManagementService,
+		Helper:                helper,
+		config:                config,
+		client:                &http.Client{Timeout: config.Timeout},
+		subscriptions:         map[string]entity.WebhookSubscription{},
+		wake:                  make(chan struct{}, 1),
+		ctx:                   ctx,
+		cancel:                cancel,
+	}
+
+	subscriptions, err := webhookRepository.ListSubscriptions()
+	if err != nil {
+		log.Error().Msg("webhook subscriptions could not be listed: " + err.Error())
+	}
+	for _, subscription := range subscriptions {
+		webhookService.subscriptions[subscription.Id] = subscription
+	}
+
+	webhookService.wg.Add(1)
+	go webhookService.dispatch()
+
+	return webhookService
+}
+
+// Subscribe registers the subscription for the caller, who must own the text when TextId is set. The
+// returned subscription carries the secret signing the deliveries, which is not shown again
+func (w *WebhookService) Subscribe(ctx context.Context, subscription entity.WebhookSubscription) (entity.WebhookSubscription, error) {
+	owner := principal.FromContext(ctx)
+	if owner == "" {
+		return entity.WebhookSubscription{}, apperror.New(apperror.Forbidden, "a client certificate is required to manage webhooks")
+	}
+
+	if subscription.TextId != "" {
+		metadata, err := w.TextManagementService.GetMetadata(ctx, subscription.TextId)
+		if err != nil {
+			return entity.WebhookSubscription{}, err
+		}
+		if metadata.Owner != owner {
+			logging.FromContext(ctx).Info().Str("text_id", subscription.TextId).Msg("webhook subscription denied")
+			return entity.WebhookSubscription{}, apperror.New(apperror.Forbidden, "only the owner of the text can subscribe to its events")
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return entity.WebhookSubscription{}, apperror.Wrap(apperror.Internal, "webhook secret could not be generated", err)
+	}
+
+	subscription.Id = w.Helper.GenerateUuid()
+	subscription.Owner = owner
+	subscription.Secret = hex.EncodeToString(secret)
+	subscription.CreatedAt = w.Helper.Now()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.WebhookRepository.SaveSubscription(subscription); err != nil {
+		return entity.WebhookSubscription{}, err
+	}
+	w.subscriptions[subscription.Id] = subscription
+
+	logging.FromContext(ctx).Info().Str("subscription_id", subscription.Id).Msg("Webhook subscribed")
+
+	return subscription, nil
+}
+
+// List returns the subscriptions of the caller, oldest first and without their secrets
+func (w *WebhookService) List(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	owner := principal.FromContext(ctx)
+	if owner == "" {
+		return nil, apperror.New(apperror.Forbidden, "a client certificate is required to manage webhooks")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	subscriptions := []entity.WebhookSubscription{}
+	for _, subscription := range w.subscriptions {
+		if subscription.Owner == owner {
+			subscription.Secret = ""
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt) })
+
+	return subscriptions, nil
+}
+
+// Unsubscribe removes a subscription of the caller, its pending deliveries are dropped by the dispatcher
+func (w *WebhookService) Unsubscribe(ctx context.Context, subscriptionId string) error {
+	if _, err := uuid.Parse(subscriptionId); err != nil {
+		return apperror.New(apperror.InvalidId, "id must be a valid uuid")
+	}
+
+	owner := principal.FromContext(ctx)
+	if owner == "" {
+		return apperror.New(apperror.Forbidden, "a client certificate is required to manage webhooks")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// someone else's subscription is reported as missing, so its id isn't confirmed
+	subscription, ok := w.subscriptions[subscriptionId]
+	if !ok || subscription.Owner != owner {
+		return apperror.New(apperror.NotFound, "webhook subscription not found")
+	}
+
+	if err := w.WebhookRepository.DeleteSubscription(subscriptionId); err != nil {
+		return err
+	}
+	delete(w.subscriptions, subscriptionId)
+
+	logging.FromContext(ctx).Info().Str("subscription_id", subscriptionId).Msg("Webhook unsubscribed")
+
+	return nil
+}
+
+// Notify queues a delivery of the event for every subscription of its text or owner asking for its type.
+// The deliveries are stored before returning, so they are sent even if the process restarts
+func (w *WebhookService) Notify(ctx context.Context, event entity.WebhookEvent) {
+	event.Id = w.Helper.GenerateUuid()
+	event.Time = w.Helper.Now()
+
+	queued := 0
+	for _, subscription := range w.matching(event) {
+		delivery := entity.WebhookDelivery{
+			Id:             w.Helper.GenerateUuid(),
+			SubscriptionId: subscription.Id,
+			Event:          event,
+			NextAttempt:    event.Time,
+		}
+		if err := w.WebhookRepository.SaveDelivery(delivery); err != nil {
+			logging.FromContext(ctx).Error().Str("subscription_id", subscription.Id).Str("event", event.Type).Msg("webhook delivery could not be queued")
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		logging.FromContext(ctx).Debug().Str("event", event.Type).Int("deliveries", queued).Msg("Webhook event queued")
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// HasOwnerSubscriptions tells whether any subscription follows every text of its owner, the callers only
+// need to find the owner of a text when it does
+func (w *WebhookService) HasOwnerSubscriptions() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, subscription := range w.subscriptions {
+		if subscription.TextId == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stop interrupts the dispatcher and waits for it, the undelivered events are sent in the next start
+func (w *WebhookService) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *WebhookService) matching(event entity.WebhookEvent) []entity.WebhookSubscription {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	matching := []entity.WebhookSubscription{}
+	for _, subscription := range w.subscriptions {
+		if subscription.TextId != "" && subscription.TextId != event.TextId {
+			continue
+		}
+		if subscription.TextId == "" && (event.Owner == "" || subscription.Owner != event.Owner) {
+			continue
+		}
+		for _, eventType := range subscription.Events {
+			if eventType == event.Type {
+				matching = append(matching, subscription)
+				break
+			}
+		}
+	}
+
+	return matching
+}
+
+func (w *WebhookService) subscription(subscriptionId string) (entity.WebhookSubscription, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	subscription, ok := w.subscriptions[subscriptionId]
+	return subscription, ok
+}
+
+// dispatch delivers the due deliveries, then sleeps until the next one is due or a new event is queued
+func (w *WebhookService) dispatch() {
+	defer w.wg.Done()
+
+	for {
+		timer := time.NewTimer(w.deliverDue())
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliverDue attempts the deliveries whose time has come and returns the wait until the next one
+func (w *WebhookService) deliverDue() time.Duration {
+	deliveries, err := w.WebhookRepository.ListDeliveries()
+	if err != nil {
+		log.Error().Msg("webhook deliveries could not be listed: " + err.Error())
+		return w.config.Backoff
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].NextAttempt.Before(deliveries[j].NextAttempt) })
+
+	wait := maxWebhookBackoff
+	for _, delivery := range deliveries {
+		if w.ctx.Err() != nil {
+			break
+		}
+		if next := delivery.NextAttempt.Sub(w.Helper.Now()); next > 0 {
+			if next < wait {
+				wait = next
+			}
+			continue
+		}
+		if retry := w.deliver(delivery); retry > 0 && retry < wait {
+			wait = retry
+		}
+	}
+
+	return wait
+}
+
+// deliver sends the delivery once and returns the wait before the next attempt, 0 when there is none
+func (w *WebhookService) deliver(delivery entity.WebhookDelivery) time.Duration {
+	logger := log.With().Str("delivery_id", delivery.Id).Str("subscription_id", delivery.SubscriptionId).Str("event", delivery.Event.Type).Logger()
+
+	subscription, ok := w.subscription(delivery.SubscriptionId)
+	if !ok {
+		logger.Debug().Msg("Dropping the delivery of a removed webhook subscription")
+		w.remove(delivery)
+		return 0
+	}
+
+	err := w.post(subscription, delivery)
+	if w.ctx.Err() != nil {
+		// interrupted by Stop, the attempt doesn't count
+		return 0
+	}
+	if err == nil {
+		logger.Debug().Msg("Webhook delivered")
+		metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+		w.remove(delivery)
+		return 0
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= w.config.MaxAttempts {
+		logger.Error().Int("attempts", delivery.Attempts).Str("last_error", delivery.LastError).Msg("webhook delivery dropped after the last attempt")
+		metrics.WebhookDeliveries.WithLabelValues("dropped").Inc()
+		w.remove(delivery)
+		return 0
+	}
+
+	wait := w.backoff(delivery.Attempts)
+	delivery.NextAttempt = w.Helper.Now().Add(wait)
+	logger.Info().Int("attempts", delivery.Attempts).Time("next_attempt", delivery.NextAttempt).Msg("webhook delivery failed: " + delivery.LastError)
+	metrics.WebhookDeliveries.WithLabelValues("retried").Inc()
+	if err := w.WebhookRepository.SaveDelivery(delivery); err != nil {
+		logger.Error().Msg("webhook delivery could not be rescheduled: " + err.Error())
+	}
+
+	return wait
+}
+
+// backoff doubles the configured wait after each failed attempt
+func (w *WebhookService) backoff(attempts int) time.Duration {
+	wait := w.config.Backoff
+	for i := 1; i < attempts && wait < maxWebhookBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxWebhookBackoff {
+		wait = maxWebhookBackoff
+	}
+
+	return wait
+}
+
+func (w *WebhookService) remove(delivery entity.WebhookDelivery) {
+	if err := w.WebhookRepository.DeleteDelivery(delivery.Id); err != nil {
+		log.Error().Str("delivery_id", delivery.Id).Msg("webhook delivery could not be removed: " + err.Error())
+	}
+}
+
+// post sends the event signed with the subscription secret, any answer but a 2xx is a failure
+func (w *WebhookService) post(subscription entity.WebhookSubscription, delivery entity.WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(w.Helper.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "zcelero-webhooks")
+	req.Header.Set("X-Zcelero-Event", delivery.Event.Type)
+	req.Header.Set("X-Zcelero-Delivery", delivery.Id)
+	req.Header.Set("X-Zcelero-Timestamp", timestamp)
+	req.Header.Set("X-Zcelero-Signature", "sha256="+WebhookSignature(subscription.Secret, timestamp, body))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", res.Status)
+	}
+
+	return nil
+}
+
+// WebhookSignature is the hex HMAC-SHA256 of the timestamp and the body, joined by a dot, keyed by the
+// subscription secret. Receivers compute it again to check the delivery came from zcelero
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type notifyingService struct {
+	TextManagementService TextManagementServiceInteface
+	WebhookService        WebhookServiceInterface
+}
+
+// NewNotifyingService notifies the webhook subscriptions of the reads, failed decryptions, rekeys and deletes
+// done through the text service
+func NewNotifyingService(textManagementService TextManagementServiceInteface, webhookService WebhookServiceInterface) TextManagementServiceInteface {
+	return &notifyingService{TextManagementService: textManagementService, WebhookService: webhookService}
+}
+
+func (n *notifyingService) Get(ctx context.Context, textId, privateKey, password string) (string, error) {
+	text, err := n.TextManagementService.Get(ctx, textId, privateKey, password)
+	if err == nil {
+		n.notify(ctx, entity.WebhookEventRead, textId, n.owner(ctx, textId))
+	} else if code := apperror.CodeOf(err); code == apperror.DecryptionFailed || code == apperror.WrongPassword {
+		n.notify(ctx, entity.WebhookEventDecryptionFailed, textId, n.owner(ctx, textId))
+	}
+
+	return text, err
+}
+
+func (n *notifyingService) GetMetadata(ctx context.Context, textId string) (entity.TextMetadata, error) {
+	return n.TextManagementService.GetMetadata(ctx, textId)
+}
+
+func (n *notifyingService) Insert(ctx context.Context, text entity.TextManagement) (entity.TextManagement, error) {
+	return n.TextManagementService.Insert(ctx, text)
+}
+
+func (n *notifyingService) Delete(ctx context.Context, textId, privateKey, password string) error {
+	// the owner can't be found once the text is gone
+	owner := n.owner(ctx, textId)
+
+	err := n.TextManagementService.Delete(ctx, textId, privateKey, password)
+	if err == nil {
+		n.notify(ctx, entity.WebhookEventDeleted, textId, owner)
+	} else if code := apperror.CodeOf(err); code == apperror.DecryptionFailed || code == apperror.WrongPassword {
+		n.notify(ctx, entity.WebhookEventDecryptionFailed, textId, owner)
+	}
+
+	return err
+}
+
+func (n *notifyingService) Rekey(ctx context.Context, textId string, rekey entity.Rekey) (string, error) {
+	privateKey, err := n.TextManagementService.Rekey(ctx, textId, rekey)
+	if err == nil {
+		n.notify(ctx, entity.WebhookEventRekeyed, textId, n.owner(ctx, textId))
+	} else if code := apperror.CodeOf(err); code == apperror.DecryptionFailed || code == apperror.WrongPassword {
+		n.notify(ctx, entity.WebhookEventDecryptionFailed, textId, n.owner(ctx, textId))
+	}
+
+	return privateKey, err
+}
+
+func (n *notifyingService) ChangePassword(ctx context.Context, change entity.PasswordChange) (string, error) {
+	return n.TextManagementService.ChangePassword(ctx, change)
+}
+
+func (n *notifyingService) notify(ctx context.Context, eventType, textId, owner string) {
+	n.WebhookService.Notify(ctx, entity.WebhookEvent{
+		Type:      eventType,
+		TextId:    textId,
+		Owner:     owner,
+		Principal: principal.FromContext(ctx),
+		ClientIP:  principal.ClientIPFromContext(ctx),
+	})
+}
+
+// owner finds the owner of the text for the per-owner subscriptions, skipping the lookup when there are none
+func (n *notifyingService) owner(ctx context.Context, textId string) string {
+	if !n.WebhookService.HasOwnerSubscriptions() {
+		return ""
+	}
+
+	metadata, err := n.TextManagementService.GetMetadata(ctx, textId)
+	if err != nil {
+		return ""
+	}
+
+	return metadata.Owner
+}
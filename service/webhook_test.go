@@ -0,0 +1,414 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"zcelero/apperror"
+	"zcelero/config"
+	"zcelero/entity"
+	"zcelero/helper"
+	mockservice "zcelero/mocks/service"
+	"zcelero/principal"
+	"zcelero/repository"
+	"zcelero/service"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var webhookConfig = config.Webhooks{MaxAttempts: 3, Backoff: 10 * time.Millisecond, Timeout: time.Second}
+
+// receiver is a webhook endpoint answering with the given statuses in turn, then 200
+type receiver struct {
+	t        *testing.T
+	statuses []int
+	mutex    sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{t: t, statuses: statuses, received: make(chan struct{}, 10)}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return r, server
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mutex.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mutex.Unlock()
+
+	w.WriteHeader(status)
+	r.received <- struct{}{}
+}
+
+func (r *receiver) wait(calls int) {
+	r.t.Helper()
+
+	for i := 0; i < calls; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			r.t.Fatalf("webhook endpoint received %d calls, want %d", i, calls)
+		}
+	}
+}
+
+// waitDeliveries waits for the dispatcher to empty the delivery queue
+func waitDeliveries(t *testing.T, repository repository.WebhookInterface) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, _ := repository.ListDeliveries()
+		if len(deliveries) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("webhook deliveries = %v, want none", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newWebhookService(t *testing.T, storagePath string, textService service.TextManagementServiceInteface, config config.Webhooks) (service.WebhookServiceInterface, repository.WebhookInterface) {
+	webhookRepository, err := repository.NewWebhookRepository(helper.NewHelper(), storagePath)
+	if err != nil {
+		t.Fatalf("NewWebhookRepository() error = %v", err)
+	}
+	webhookService := service.NewWebhookService(webhookRepository, textService, helper.NewHelper(), config)
+	t.Cleanup(webhookService.Stop)
+
+	return webhookService, webhookRepository
+}
+
+func TestWebhookService_Subscribe(t *testing.T) {
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	missingId := "2f13ed58-afc9-477a-bf0d-c90eb1b7db90"
+	tests := []struct {
+		name         string
+		principal    string
+		subscription entity.WebhookSubscription
+		wantErr      apperror.Code
+	}{
+		{
+			name:         "Subscribe to the texts of the owner",
+			principal:    "CN=alice",
+			subscription: entity.WebhookSubscription{Url: "https://example.com/hook", Events: entity.WebhookEvents},
+		},
+		{
+			name:         "Subscribe to an owned text",
+			principal:    "CN=alice",
+			subscription: entity.WebhookSubscription{Url: "https://example.com/hook", Events: []string{entity.WebhookEventRead}, TextId: textId},
+		},
+		{
+			name:         "Subscribe to a text of someone else",
+			principal:    "CN=mallory",
+			subscription: entity.WebhookSubscription{Url: "https://example.com/hook", Events: []string{entity.WebhookEventRead}, TextId: textId},
+			wantErr:      apperror.Forbidden,
+		},
+		{
+			name:         "Subscribe to a missing text",
+			principal:    "CN=alice",
+			subscription: entity.WebhookSubscription{Url: "https://example.com/hook", Events: []string{entity.WebhookEventRead}, TextId: missingId},
+			wantErr:      apperror.NotFound,
+		},
+		{
+			name:         "Subscribe without principal",
+			subscription: entity.WebhookSubscription{Url: "https://example.com/hook", Events: entity.WebhookEvents},
+			wantErr:      apperror.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			textService := &mockservice.TextManagementServiceInteface{}
+			textService.On("GetMetadata", mock.Anything, textId).Return(entity.TextMetadata{Uuid: textId, Owner: "CN=alice"}, nil)
+			textService.On("GetMetadata", mock.Anything, missingId).Return(entity.TextMetadata{}, apperror.New(apperror.NotFound, "text not found"))
+			webhookService, repository := newWebhookService(t, t.TempDir(), textService, webhookConfig)
+
+			got, err := webhookService.Subscribe(principal.NewContext(context.Background(), tt.principal), tt.subscription)
+			if tt.wantErr != "" {
+				if apperror.CodeOf(err) != tt.wantErr {
+					t.Errorf("WebhookService.Subscribe() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WebhookService.Subscribe() error = %v", err)
+			}
+			if got.Id == "" || got.Owner != tt.principal || len(got.Secret) != 64 || got.CreatedAt.IsZero() {
+				t.Errorf("WebhookService.Subscribe() = %+v", got)
+			}
+			if stored, _ := repository.ListSubscriptions(); len(stored) != 1 || stored[0].Secret != got.Secret {
+				t.Errorf("stored subscriptions = %v, want %v", stored, got)
+			}
+		})
+	}
+}
+
+func TestWebhookService_ListAndUnsubscribe(t *testing.T) {
+	webhookService, _ := newWebhookService(t, t.TempDir(), nil, webhookConfig)
+	alice := principal.NewContext(context.Background(), "CN=alice")
+	bob := principal.NewContext(context.Background(), "CN=bob")
+
+	subscription, _ := webhookService.Subscribe(alice, entity.WebhookSubscription{Url: "https://example.com/hook", Events: entity.WebhookEvents})
+	webhookService.Subscribe(bob, entity.WebhookSubscription{Url: "https://example.com/bob", Events: entity.WebhookEvents})
+
+	got, err := webhookService.List(alice)
+	if err != nil {
+		t.Fatalf("WebhookService.List() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Id != subscription.Id || got[0].Secret != "" {
+		t.Errorf("WebhookService.List() = %v, want the subscription of alice without its secret", got)
+	}
+	if !webhookService.HasOwnerSubscriptions() {
+		t.Error("WebhookService.HasOwnerSubscriptions() = false, want true")
+	}
+
+	if err := webhookService.Unsubscribe(bob, subscription.Id); apperror.CodeOf(err) != apperror.NotFound {
+		t.Errorf("WebhookService.Unsubscribe() of someone else error = %v, want %v", err, apperror.NotFound)
+	}
+	if err := webhookService.Unsubscribe(alice, "invalid"); apperror.CodeOf(err) != apperror.InvalidId {
+		t.Errorf("WebhookService.Unsubscribe() error = %v, want %v", err, apperror.InvalidId)
+	}
+	if err := webhookService.Unsubscribe(alice, subscription.Id); err != nil {
+		t.Fatalf("WebhookService.Unsubscribe() error = %v", err)
+	}
+	if got, _ := webhookService.List(alice); len(got) != 0 {
+		t.Errorf("WebhookService.List() = %v, want none", got)
+	}
+}
+
+func TestWebhookService_Notify(t *testing.T) {
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	receiver, server := newReceiver(t)
+	textService := &mockservice.TextManagementServiceInteface{}
+	textService.On("GetMetadata", mock.Anything, textId).Return(entity.TextMetadata{Uuid: textId, Owner: "CN=alice"}, nil)
+	webhookService, repository := newWebhookService(t, t.TempDir(), textService, webhookConfig)
+	alice := principal.NewContext(context.Background(), "CN=alice")
+
+	perText, _ := webhookService.Subscribe(alice, entity.WebhookSubscription{Url: server.URL + "/text", Events: []string{entity.WebhookEventRead}, TextId: textId})
+	webhookService.Subscribe(alice, entity.WebhookSubscription{Url: server.URL + "/owner", Events: []string{entity.WebhookEventDeleted}})
+	webhookService.Subscribe(principal.NewContext(context.Background(), "CN=bob"), entity.WebhookSubscription{Url: server.URL + "/bob", Events: entity.WebhookEvents})
+
+	webhookService.Notify(context.Background(), entity.WebhookEvent{Type: entity.WebhookEventRead, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"})
+	receiver.wait(1)
+	waitDeliveries(t, repository)
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if len(receiver.requests) != 1 || receiver.requests[0].URL.Path != "/text" {
+		t.Fatalf("webhook requests = %v, want only the text subscription", receiver.requests)
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if want := "sha256=" + service.WebhookSignature(perText.Secret, req.Header.Get("X-Zcelero-Timestamp"), body); req.Header.Get("X-Zcelero-Signature") != want {
+		t.Errorf("X-Zcelero-Signature = %s, want %s", req.Header.Get("X-Zcelero-Signature"), want)
+	}
+	if req.Header.Get("X-Zcelero-Event") != entity.WebhookEventRead || req.Header.Get("X-Zcelero-Delivery") == "" {
+		t.Errorf("webhook headers = %v", req.Header)
+	}
+	event := map[string]any{}
+	json.Unmarshal(body, &event)
+	if event["type"] != entity.WebhookEventRead || event["text_id"] != textId || event["principal"] != "CN=bob" || event["client_ip"] != "10.0.0.7" || event["owner"] != nil {
+		t.Errorf("webhook body = %s", body)
+	}
+}
+
+func TestWebhookService_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int
+	}{
+		{
+			name:      "Delivered after failures",
+			statuses:  []int{http.StatusInternalServerError, http.StatusServiceUnavailable},
+			wantCalls: 3,
+		},
+		{
+			name:      "Dropped after the last attempt",
+			statuses:  []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver, server := newReceiver(t, tt.statuses...)
+			webhookService, repository := newWebhookService(t, t.TempDir(), nil, webhookConfig)
+			alice := principal.NewContext(context.Background(), "CN=alice")
+			webhookService.Subscribe(alice, entity.WebhookSubscription{Url: server.URL, Events: entity.WebhookEvents})
+
+			webhookService.Notify(alice, entity.WebhookEvent{Type: entity.WebhookEventDeleted, TextId: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", Owner: "CN=alice"})
+			receiver.wait(tt.wantCalls)
+			waitDeliveries(t, repository)
+
+			receiver.mutex.Lock()
+			defer receiver.mutex.Unlock()
+			if len(receiver.requests) != tt.wantCalls {
+				t.Errorf("webhook calls = %d, want %d", len(receiver.requests), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWebhookService_DeliveriesSurviveRestart(t *testing.T) {
+	storagePath := t.TempDir()
+	receiver, server := newReceiver(t)
+	alice := principal.NewContext(context.Background(), "CN=alice")
+
+	stopped, _ := newWebhookService(t, storagePath, nil, webhookConfig)
+	stopped.Subscribe(alice, entity.WebhookSubscription{Url: server.URL, Events: entity.WebhookEvents})
+	// without the dispatcher the delivery stays queued, as in a process stopped before delivering it
+	stopped.Stop()
+	stopped.Notify(alice, entity.WebhookEvent{Type: entity.WebhookEventRead, TextId: "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec", Owner: "CN=alice"})
+
+	_, repository := newWebhookService(t, storagePath, nil, webhookConfig)
+	receiver.wait(1)
+	waitDeliveries(t, repository)
+}
+
+func TestNotifyingService(t *testing.T) {
+	textId := "154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec"
+	ctx := principal.NewClientIPContext(principal.NewContext(context.Background(), "CN=bob"), "10.0.0.7")
+	tests := []struct {
+		name          string
+		ownerWebhooks bool
+		call          func(s service.TextManagementServiceInteface) error
+		mockBehavior  func(s *mockservice.TextManagementServiceInteface)
+		wantEvent     *entity.WebhookEvent
+	}{
+		{
+			name:          "Read",
+			ownerWebhooks: true,
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Get(ctx, textId, "", "")
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Get", ctx, textId, "", "").Return("text", nil)
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventRead, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name: "Read without owner subscriptions",
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Get(ctx, textId, "", "")
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Get", ctx, textId, "", "").Return("text", nil)
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventRead, TextId: textId, Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name:          "Wrong password",
+			ownerWebhooks: true,
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Get(ctx, textId, "key", "password")
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Get", ctx, textId, "key", "password").Return("", apperror.New(apperror.WrongPassword, "private_key_password is incorrect"))
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventDecryptionFailed, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name:          "Rekey",
+			ownerWebhooks: true,
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Rekey(ctx, textId, entity.Rekey{PrivateKey: "key"})
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Rekey", ctx, textId, entity.Rekey{PrivateKey: "key"}).Return("new key", nil)
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventRekeyed, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name: "Rekey with wrong password",
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Rekey(ctx, textId, entity.Rekey{PrivateKey: "key"})
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Rekey", ctx, textId, entity.Rekey{PrivateKey: "key"}).Return("", apperror.New(apperror.WrongPassword, "private_key_password is incorrect"))
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventDecryptionFailed, TextId: textId, Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name: "Missing text",
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Get(ctx, textId, "", "")
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Get", ctx, textId, "", "").Return("", apperror.New(apperror.NotFound, "text not found"))
+			},
+		},
+		{
+			name:          "Delete",
+			ownerWebhooks: true,
+			call: func(s service.TextManagementServiceInteface) error {
+				return s.Delete(ctx, textId, "private key", "password123")
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Delete", ctx, textId, "private key", "password123").Return(nil)
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventDeleted, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name:          "Delete with wrong password",
+			ownerWebhooks: true,
+			call: func(s service.TextManagementServiceInteface) error {
+				return s.Delete(ctx, textId, "private key", "password")
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Delete", ctx, textId, "private key", "password").Return(apperror.New(apperror.WrongPassword, "private_key_password is incorrect"))
+			},
+			wantEvent: &entity.WebhookEvent{Type: entity.WebhookEventDecryptionFailed, TextId: textId, Owner: "CN=alice", Principal: "CN=bob", ClientIP: "10.0.0.7"},
+		},
+		{
+			name: "Insert",
+			call: func(s service.TextManagementServiceInteface) error {
+				_, err := s.Insert(ctx, entity.TextManagement{TextData: "text"})
+				return err
+			},
+			mockBehavior: func(s *mockservice.TextManagementServiceInteface) {
+				s.On("Insert", ctx, entity.TextManagement{TextData: "text"}).Return(entity.TextManagement{Uuid: textId}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			textService := &mockservice.TextManagementServiceInteface{}
+			textService.On("GetMetadata", ctx, textId).Return(entity.TextMetadata{Uuid: textId, Owner: "CN=alice"}, nil)
+			tt.mockBehavior(textService)
+			webhookService := &mockservice.WebhookServiceInterface{}
+			webhookService.On("HasOwnerSubscriptions").Return(tt.ownerWebhooks).Maybe()
+			if tt.wantEvent != nil {
+				webhookService.On("Notify", ctx, *tt.wantEvent).Return().Once()
+			}
+
+			tt.call(service.NewNotifyingService(textService, webhookService))
+			webhookService.AssertExpectations(t)
+			if tt.wantEvent == nil {
+				webhookService.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package textcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"zcelero/apperror"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ModePassphrase marks the texts encrypted with a key derived from the password instead of a key pair
+const ModePassphrase = "passphrase"
+
+// KDFArgon2id is the only key derivation of the passphrase texts
+const KDFArgon2id = "argon2id"
+
+// The Argon2id cost of the texts encrypted here is the second recommendation of RFC 9106, the limits keep a
+// tampered file from holding the memory or the CPU when it is read
+const (
+	argon2Time      = 3
+	argon2Memory    = 64 * 1024
+	argon2Threads   = 4
+	maxArgon2Time   = 10
+	maxArgon2Memory = 256 * 1024
+	argon2SaltSize  = 16
+	argon2KeySize   = 32
+)
+
+// KDF describes how the key of a passphrase text is derived from the password, Memory is in KiB
+type KDF struct {
+	Algorithm string
+	Salt      []byte
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+}
+
+// SealWithPassphrase encrypts the text with AES-256-GCM and a key derived from the password with Argon2id,
+// returning the base64 of the nonce and the ciphertext and the parameters needed to derive the key again
+func SealWithPassphrase(randReader io.Reader, password, textData string) (string, *KDF, error) {
+	kdf := &KDF{Algorithm: KDFArgon2id, Salt: make([]byte, argon2SaltSize), Time: argon2Time, Memory: argon2Memory, Threads: argon2Threads}
+	if _, err := io.ReadFull(randReader, kdf.Salt); err != nil {
+		return "", nil, apperror.Wrap(apperror.Internal, "text could not be encrypted", err)
+	}
+
+	gcm, err := passphraseCipher(kdf, password)
+	if err != nil {
+		return "", nil, apperror.Wrap(apperror.Internal, "text could not be encrypted", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(randReader, nonce); err != nil {
+		return "", nil, apperror.Wrap(apperror.Internal, "text could not be encrypted", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(textData), nil)), kdf, nil
+}
+
+// openWithPassphrase decrypts a passphrase text. GCM can't tell a wrong password from a corrupted file, both
+// are reported as a wrong password
+func openWithPassphrase(fileData FileContent, password string) (string, error) {
+	kdf := fileData.KDF
+	if kdf == nil || kdf.Algorithm != KDFArgon2id {
+		return "", apperror.New(apperror.Internal, "stored text has no supported key derivation")
+	}
+	if kdf.Time < 1 || kdf.Time > maxArgon2Time || kdf.Memory < 8*uint32(kdf.Threads) || kdf.Memory > maxArgon2Memory || kdf.Threads < 1 {
+		return "", apperror.Wrap(apperror.Internal, "stored text could not be read", fmt.Errorf("argon2id cost t=%d m=%d p=%d is out of bounds", kdf.Time, kdf.Memory, kdf.Threads))
+	}
+
+	content, err := base64.StdEncoding.DecodeString(fileData.Content)
+	if err != nil {
+		return "", apperror.Wrap(apperror.Internal, "stored text could not be read", err)
+	}
+
+	gcm, err := passphraseCipher(kdf, password)
+	if err != nil {
+		return "", apperror.Wrap(apperror.Internal, "stored text could not be read", err)
+	}
+	if len(content) < gcm.NonceSize() {
+		return "", apperror.New(apperror.Internal, "stored text is too short")
+	}
+
+	message, err := gcm.Open(nil, content[:gcm.NonceSize()], content[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", apperror.Wrap(apperror.WrongPassword, "private_key_password is incorrect", err)
+	}
+
+	return string(message), nil
+}
+
+func passphraseCipher(kdf *KDF, password string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(password), kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, argon2KeySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
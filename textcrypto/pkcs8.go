@@ -0,0 +1,182 @@
+package textcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// The private keys are encrypted as PKCS#8 EncryptedPrivateKeyInfo with PBES2 (RFC 8018), the format of
+// "openssl pkcs8 -topk8 -v2 aes-256-cbc", since the legacy PEM encryption of x509.EncryptPEMBlock derives
+// the key with a single MD5 round
+
+// pbkdf2Iterations is the PBKDF2-HMAC-SHA256 cost of the keys encrypted here, maxPBKDF2Iterations limits the
+// cost of the keys read to twice that, so a key sent with a huge count can't hold the CPU
+const (
+	pbkdf2Iterations    = 600000
+	maxPBKDF2Iterations = 2 * pbkdf2Iterations
+	saltSize            = 16
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// errWrongPassword is returned when the decrypted key is not even padded, the password is wrong
+var errWrongPassword = errors.New("decrypted key has an invalid padding")
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// encryptPKCS8 encrypts the DER of a PKCS#8 PrivateKeyInfo with AES-256-CBC and a key derived from the
+// password with PBKDF2-HMAC-SHA256, returning the DER of the EncryptedPrivateKeyInfo
+func encryptPKCS8(randReader io.Reader, privateKeyInfo []byte, password []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(randReader, salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(randReader, iv); err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key(password, salt, pbkdf2Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(privateKeyInfo)%aes.BlockSize
+	encrypted := append(append([]byte{}, privateKeyInfo...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: encrypted,
+	})
+}
+
+// decryptPKCS8 decrypts the DER of an EncryptedPrivateKeyInfo encrypted with PBES2, PBKDF2 with HMAC-SHA1 or
+// HMAC-SHA256 and AES-CBC, which covers the keys written by OpenSSL. It returns errWrongPassword when the
+// padding shows the password is wrong
+func decryptPKCS8(der []byte, password []byte) ([]byte, error) {
+	info := encryptedPrivateKeyInfo{}
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("encrypted private key is not valid DER")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("encrypted private key uses the unsupported scheme %s, PBES2 is expected", info.Algorithm.Algorithm)
+	}
+
+	scheme := pbes2Params{}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &scheme); err != nil {
+		return nil, fmt.Errorf("PBES2 parameters are not valid")
+	}
+	if !scheme.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("encrypted private key uses the unsupported key derivation %s, PBKDF2 is expected", scheme.KeyDerivationFunc.Algorithm)
+	}
+	kdf := pbkdf2Params{}
+	if _, err := asn1.Unmarshal(scheme.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("PBKDF2 parameters are not valid")
+	}
+	if kdf.IterationCount < 1 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("PBKDF2 iteration count %d must be between 1 and %d", kdf.IterationCount, maxPBKDF2Iterations)
+	}
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("encrypted private key uses the unsupported PRF %s", kdf.PRF.Algorithm)
+	}
+
+	var keySize int
+	switch {
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keySize = 16
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keySize = 24
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, fmt.Errorf("encrypted private key uses the unsupported cipher %s", scheme.EncryptionScheme.Algorithm)
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != keySize {
+		return nil, fmt.Errorf("PBKDF2 key length %d doesn't match the cipher", kdf.KeyLength)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(scheme.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("AES-CBC parameters are not valid")
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted private key has an invalid length")
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(password, kdf.Salt, kdf.IterationCount, keySize, prf))
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	padding := int(decrypted[len(decrypted)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		return nil, errWrongPassword
+	}
+	if !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errWrongPassword
+	}
+
+	return decrypted[:len(decrypted)-padding], nil
+}
@@ -0,0 +1,223 @@
+package textcrypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"time"
+	"zcelero/apperror"
+)
+
+// FileContent is the JSON document stored for each text, shared by the service and the offline tools
+type FileContent struct {
+	Content   string
+	Encrypted bool
+	KeySize   uint64     `json:",omitempty"`
+	CreatedAt *time.Time `json:",omitempty"`
+	// Owner is the principal that stored the text, empty for anonymous clients
+	Owner string `json:",omitempty"`
+	// RekeyedAt is the last time the text was encrypted again with a new key pair
+	RekeyedAt *time.Time `json:",omitempty"`
+	// Mode is ModePassphrase for the texts encrypted with a key derived from the password, described by KDF,
+	// and empty for the texts encrypted with a key pair
+	Mode string `json:",omitempty"`
+	KDF  *KDF   `json:",omitempty"`
+}
+
+// Open returns the text of the stored document, decrypting it with the private key, or only the password for
+// the passphrase texts, when necessary. Nothing is logged here, the callers log the returned errors with their
+// own context
+func Open(fileData FileContent, privateKeyString, password string) (string, error) {
+	if !fileData.Encrypted {
+		return fileData.Content, nil
+	}
+
+	if fileData.Mode == ModePassphrase {
+		if password == "" {
+			return "", apperror.New(apperror.ValidationFailed, "private_key_password is required to read this text")
+		}
+		return openWithPassphrase(fileData, password)
+	}
+
+	if privateKeyString == "" {
+		return "", apperror.New(apperror.ValidationFailed, "private_key is required to read this text")
+	}
+	if password == "" {
+		return "", apperror.New(apperror.ValidationFailed, "private_key_password is required to read this text")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(fileData.Content)
+	if err != nil {
+		return "", apperror.Wrap(apperror.Internal, "stored text could not be read", err)
+	}
+
+	privateKey, err := DecryptPrivateKey(privateKeyString, password)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := DecryptMessage(privateKey, content)
+	if err != nil {
+		return "", err
+	}
+
+	return message, nil
+}
+
+// GeneratePairKey returns the public key and the private key as a PEM encrypted with the password, in the
+// PKCS#8 format of EncryptPrivateKey
+func GeneratePairKey(randReader io.Reader, privatekey *rsa.PrivateKey, privateKeyPassword string) (*rsa.PublicKey, string, error) {
+	privateKey, err := EncryptPrivateKey(randReader, privatekey, privateKeyPassword)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &privatekey.PublicKey, privateKey, nil
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key, either a PKIX "PUBLIC KEY" as written by OpenSSL or a
+// PKCS#1 "RSA PUBLIC KEY"
+func ParsePublicKey(publicKeyString string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyString))
+	if block == nil {
+		return nil, apperror.New(apperror.ValidationFailed, "public_key is not a valid PEM encoded key")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, apperror.Wrap(apperror.ValidationFailed, "public_key is not a PKCS#1 RSA public key", err)
+		}
+		return publicKey, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.ValidationFailed, "public_key is not a PKIX public key", err)
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, apperror.New(apperror.ValidationFailed, "public_key is not an RSA public key")
+	}
+
+	return publicKey, nil
+}
+
+// EncryptMessage encrypts the text with RSA-OAEP
+func EncryptMessage(randReader io.Reader, publicKey *rsa.PublicKey, textData string) ([]byte, error) {
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), randReader, publicKey, []byte(textData), nil)
+	if errors.Is(err, rsa.ErrMessageTooLong) {
+		return nil, apperror.Wrap(apperror.ValidationFailed, "text_data is too long for the key_size", err)
+	}
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, "text could not be encrypted", err)
+	}
+
+	return ciphertext, nil
+}
+
+// EncryptPrivateKey returns the private key as a PEM encoded PKCS#8 key encrypted with the password, using
+// PBES2 with PBKDF2-HMAC-SHA256 and AES-256-CBC, which OpenSSL and most tools read
+func EncryptPrivateKey(randReader io.Reader, privateKey *rsa.PrivateKey, password string) (string, error) {
+	privateKeyInfo, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", apperror.Wrap(apperror.Internal, "private key could not be encoded", err)
+	}
+
+	der, err := encryptPKCS8(randReader, privateKeyInfo, []byte(password))
+	if err != nil {
+		return "", apperror.Wrap(apperror.Internal, "private key could not be encrypted", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der})), nil
+}
+
+// ChangePassword decrypts the PEM with the password and encrypts the key again with the new password, in the
+// PKCS#8 format of EncryptPrivateKey whatever the format it came in
+func ChangePassword(randReader io.Reader, privateKeyString, password, newPassword string) (string, error) {
+	privateKey, err := DecryptPrivateKey(privateKeyString, password)
+	if err != nil {
+		return "", err
+	}
+
+	return EncryptPrivateKey(randReader, privateKey, newPassword)
+}
+
+// DecryptPrivateKey decrypts the PEM with the password and parses the RSA key inside it. Both the PKCS#8 keys
+// of EncryptPrivateKey and the legacy encrypted PEM that inserts returned before are accepted
+func DecryptPrivateKey(privateKeyString string, password string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyString))
+	if block == nil {
+		return nil, apperror.New(apperror.DecryptionFailed, "private_key is not a valid PEM encoded key")
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return decryptPKCS8PrivateKey(block, password)
+	}
+
+	bytePK, err := x509.DecryptPEMBlock(block, []byte(password))
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, apperror.Wrap(apperror.WrongPassword, "private_key_password is incorrect", err)
+	}
+	if err != nil {
+		return nil, apperror.Wrap(apperror.DecryptionFailed, "private_key could not be decrypted", err)
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(bytePK)
+	if err != nil {
+		if !isDER(bytePK) {
+			return nil, apperror.Wrap(apperror.WrongPassword, "private_key_password is incorrect", err)
+		}
+		return nil, apperror.Wrap(apperror.DecryptionFailed, "private_key is not a PKCS#1 RSA private key", err)
+	}
+
+	return privateKey, nil
+}
+
+func decryptPKCS8PrivateKey(block *pem.Block, password string) (*rsa.PrivateKey, error) {
+	privateKeyInfo, err := decryptPKCS8(block.Bytes, []byte(password))
+	if errors.Is(err, errWrongPassword) {
+		return nil, apperror.Wrap(apperror.WrongPassword, "private_key_password is incorrect", err)
+	}
+	if err != nil {
+		return nil, apperror.Wrap(apperror.DecryptionFailed, "private_key could not be decrypted", err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(privateKeyInfo)
+	if err != nil {
+		if !isDER(privateKeyInfo) {
+			return nil, apperror.Wrap(apperror.WrongPassword, "private_key_password is incorrect", err)
+		}
+		return nil, apperror.Wrap(apperror.DecryptionFailed, "private_key is not a PKCS#8 private key", err)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, apperror.New(apperror.DecryptionFailed, "private_key is not an RSA private key")
+	}
+
+	return privateKey, nil
+}
+
+// isDER tells whether the decrypted bytes are DER. A wrong password is not always detected by the padding
+// check and decrypts into bytes that are not even DER, while a correctly decrypted key of another kind still is
+func isDER(data []byte) bool {
+	var der asn1.RawValue
+	rest, err := asn1.Unmarshal(data, &der)
+
+	return err == nil && len(rest) == 0
+}
+
+// DecryptMessage decrypts the RSA-OAEP encrypted text
+func DecryptMessage(privateKey *rsa.PrivateKey, data []byte) (string, error) {
+	decriptedData, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, data, nil)
+	if err != nil {
+		return "", apperror.Wrap(apperror.DecryptionFailed, "text could not be decrypted with this private_key", err)
+	}
+
+	return string(decriptedData), nil
+}
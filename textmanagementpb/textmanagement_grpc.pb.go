@@ -0,0 +1,231 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: textmanagement.proto
+
+package textmanagementpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	TextManagement_Insert_FullMethodName      = "/zcelero.v1.TextManagement/Insert"
+	TextManagement_Get_FullMethodName         = "/zcelero.v1.TextManagement/Get"
+	TextManagement_GetMetadata_FullMethodName = "/zcelero.v1.TextManagement/GetMetadata"
+	TextManagement_Delete_FullMethodName      = "/zcelero.v1.TextManagement/Delete"
+)
+
+// TextManagementClient is the client API for TextManagement service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TextManagementClient interface {
+	// Insert stores the text, returning the private key when it is encrypted
+	Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error)
+	// Get returns the stored text, decrypting it with the private key when necessary
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// GetMetadata describes the stored text without decrypting it
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*Metadata, error)
+	// Delete removes the stored text, the caller proves it owns it with the client certificate that stored it or
+	// with the private key and password of the text
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type textManagementClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTextManagementClient(cc grpc.ClientConnInterface) TextManagementClient {
+	return &textManagementClient{cc}
+}
+
+func (c *textManagementClient) Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error) {
+	out := new(InsertResponse)
+	err := c.cc.Invoke(ctx, TextManagement_Insert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *textManagementClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, TextManagement_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *textManagementClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*Metadata, error) {
+	out := new(Metadata)
+	err := c.cc.Invoke(ctx, TextManagement_GetMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *textManagementClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TextManagement_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TextManagementServer is the server API for TextManagement service.
+// All implementations must embed UnimplementedTextManagementServer
+// for forward compatibility
+type TextManagementServer interface {
+	// Insert stores the text, returning the private key when it is encrypted
+	Insert(context.Context, *InsertRequest) (*InsertResponse, error)
+	// Get returns the stored text, decrypting it with the private key when necessary
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// GetMetadata describes the stored text without decrypting it
+	GetMetadata(context.Context, *GetMetadataRequest) (*Metadata, error)
+	// Delete removes the stored text, the caller proves it owns it with the client certificate that stored it or
+	// with the private key and password of the text
+	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedTextManagementServer()
+}
+
+// UnimplementedTextManagementServer must be embedded to have forward compatible implementations.
+type UnimplementedTextManagementServer struct {
+}
+
+func (UnimplementedTextManagementServer) Insert(context.Context, *InsertRequest) (*InsertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+}
+func (UnimplementedTextManagementServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedTextManagementServer) GetMetadata(context.Context, *GetMetadataRequest) (*Metadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadata not implemented")
+}
+func (UnimplementedTextManagementServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedTextManagementServer) mustEmbedUnimplementedTextManagementServer() {}
+
+// UnsafeTextManagementServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TextManagementServer will
+// result in compilation errors.
+type UnsafeTextManagementServer interface {
+	mustEmbedUnimplementedTextManagementServer()
+}
+
+func RegisterTextManagementServer(s grpc.ServiceRegistrar, srv TextManagementServer) {
+	s.RegisterService(&TextManagement_ServiceDesc, srv)
+}
+
+func _TextManagement_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextManagementServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TextManagement_Insert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextManagementServer).Insert(ctx, req.(*InsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TextManagement_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextManagementServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TextManagement_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextManagementServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TextManagement_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextManagementServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TextManagement_GetMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextManagementServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TextManagement_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextManagementServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TextManagement_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextManagementServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TextManagement_ServiceDesc is the grpc.ServiceDesc for TextManagement service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TextManagement_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "zcelero.v1.TextManagement",
+	HandlerType: (*TextManagementServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Insert",
+			Handler:    _TextManagement_Insert_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _TextManagement_Get_Handler,
+		},
+		{
+			MethodName: "GetMetadata",
+			Handler:    _TextManagement_GetMetadata_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _TextManagement_Delete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "textmanagement.proto",
+}
@@ -0,0 +1,209 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"zcelero/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReloadInterval is how often the certificate files are checked for changes
+var ReloadInterval = 10 * time.Second
+
+type ReloaderInterface interface {
+	TLSConfig(nextProtos ...string) *tls.Config
+	Stop()
+}
+
+type reloaderStruct struct {
+	settings config.TLS
+	mutex    sync.RWMutex
+	current  *tls.Config
+	modTimes []time.Time
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewReloader loads the certificate, key and client CAs and reloads them whenever one of the files changes
+func NewReloader(settings config.TLS) (ReloaderInterface, error) {
+	r := &reloaderStruct{
+		settings: settings,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.modTimes = modTimes
+
+	go r.watch()
+
+	return r, nil
+}
+
+// TLSConfig returns the server configuration, each handshake uses the last loaded certificate
+func (r *reloaderStruct) TLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			config := r.current.Clone()
+			r.mutex.RUnlock()
+
+			config.NextProtos = nextProtos
+			return config, nil
+		},
+	}
+}
+
+// Stop terminates the file watcher
+func (r *reloaderStruct) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+func (r *reloaderStruct) watch() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged keeps serving the previous certificate when the new files can't be loaded, e.g. when
+// only the certificate was replaced so far, and tries again in the next check
+func (r *reloaderStruct) reloadIfChanged() {
+	modTimes, err := r.stat()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+	if equal(modTimes, r.modTimes) {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+	r.modTimes = modTimes
+
+	log.Info().Str("file", r.settings.CertFile).Msg("TLS certificate reloaded")
+}
+
+func (r *reloaderStruct) load() error {
+	certificate, err := tls.LoadX509KeyPair(r.settings.CertFile, r.settings.KeyFile)
+	if err != nil {
+		return fmt.Errorf("TLS certificate could not be loaded: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   clientAuth(r.settings.ClientAuth),
+	}
+	if r.settings.ClientCAFile != "" {
+		data, err := os.ReadFile(r.settings.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("TLS client CAs could not be loaded: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("TLS client CAs could not be loaded: %s has no PEM certificate", r.settings.ClientCAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	r.current = config
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// stat returns the modification time of every file, following symbolic links swapped by secret managers
+func (r *reloaderStruct) stat() ([]time.Time, error) {
+	modTimes := []time.Time{}
+	for _, file := range []string{r.settings.CertFile, r.settings.KeyFile, r.settings.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("TLS file could not be read: %w", err)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
+
+func equal(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func clientAuth(mode string) tls.ClientAuthType {
+	switch mode {
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// RedirectHandler answers every request with a permanent redirect to the same URL in HTTPS, keeping
+// the method and body of the request
+func RedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,320 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zcelero/config"
+	"zcelero/principal"
+)
+
+type certificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newCertificate creates a certificate signed by the parent, or self signed when parent is nil
+func newCertificate(t *testing.T, commonName string, parent *certificate) *certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Zcelero"}},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &certificate{cert: cert, key: key}
+}
+
+func (c *certificate) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *certificate) keyPEM() []byte {
+	der, _ := x509.MarshalECPrivateKey(c.key)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *certificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// writeFiles writes the server certificate, its key and the client CA, returning the settings using them
+func writeFiles(t *testing.T, dir string, server, clientCA *certificate, clientAuth string) config.TLS {
+	settings := config.TLS{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		ClientAuth:   clientAuth,
+	}
+	os.WriteFile(settings.CertFile, server.certPEM(), 0600)
+	os.WriteFile(settings.KeyFile, server.keyPEM(), 0600)
+	os.WriteFile(settings.ClientCAFile, clientCA.certPEM(), 0600)
+
+	return settings
+}
+
+// startServer serves the principal of each request over TLS with the reloader configuration
+func startServer(t *testing.T, reloader ReloaderInterface) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, principal.FromTLS(r.TLS))
+	}))
+	server.TLS = reloader.TLSConfig("http/1.1")
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(serverCA *certificate, clientCert *certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	config := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if clientCert != nil {
+		config.Certificates = []tls.Certificate{clientCert.tlsCertificate()}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func TestReloader_ClientAuth(t *testing.T) {
+	serverCA := newCertificate(t, "server-ca", nil)
+	clientCA := newCertificate(t, "client-ca", nil)
+	serverCert := newCertificate(t, "localhost", serverCA)
+	clientCert := newCertificate(t, "client", clientCA)
+	unknownClientCert := newCertificate(t, "intruder", newCertificate(t, "other-ca", nil))
+
+	tests := []struct {
+		name          string
+		clientAuth    string
+		clientCert    *certificate
+		wantPrincipal string
+		wantErr       bool
+	}{
+		{
+			name:          "Required client certificate",
+			clientAuth:    "require",
+			clientCert:    clientCert,
+			wantPrincipal: "CN=client,O=Zcelero",
+		},
+		{
+			name:       "Missing required client certificate",
+			clientAuth: "require",
+			wantErr:    true,
+		},
+		{
+			name:       "Client certificate from unknown CA",
+			clientAuth: "require",
+			clientCert: unknownClientCert,
+			wantErr:    true,
+		},
+		{
+			name:          "Optional client certificate sent",
+			clientAuth:    "optional",
+			clientCert:    clientCert,
+			wantPrincipal: "CN=client,O=Zcelero",
+		},
+		{
+			name:          "Optional client certificate missing",
+			clientAuth:    "optional",
+			wantPrincipal: "",
+		},
+		{
+			name:          "Client certificate ignored",
+			clientAuth:    "none",
+			clientCert:    clientCert,
+			wantPrincipal: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, err := NewReloader(writeFiles(t, t.TempDir(), serverCert, clientCA, tt.clientAuth))
+			if err != nil {
+				t.Fatalf("NewReloader() error = %v", err)
+			}
+			defer reloader.Stop()
+			server := startServer(t, reloader)
+
+			res, err := newClient(serverCA, tt.clientCert).Get(server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("request error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+
+			body, _ := io.ReadAll(res.Body)
+			if string(body) != tt.wantPrincipal {
+				t.Errorf("principal = %q, want %q", body, tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	interval := ReloadInterval
+	ReloadInterval = 10 * time.Millisecond
+	defer func() { ReloadInterval = interval }()
+
+	dir := t.TempDir()
+	serverCA := newCertificate(t, "server-ca", nil)
+	clientCA := newCertificate(t, "client-ca", nil)
+	reloader, err := NewReloader(writeFiles(t, dir, newCertificate(t, "localhost", serverCA), clientCA, "none"))
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	defer reloader.Stop()
+	server := startServer(t, reloader)
+
+	servedSerial := func() *big.Int {
+		client := newClient(serverCA, nil)
+		client.Transport.(*http.Transport).DisableKeepAlives = true
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		res.Body.Close()
+		return res.TLS.PeerCertificates[0].SerialNumber
+	}
+	before := servedSerial()
+
+	// a half written pair keeps the previous certificate until the key is written as well
+	renewed := newCertificate(t, "localhost", serverCA)
+	os.WriteFile(filepath.Join(dir, "cert.pem"), renewed.certPEM(), 0600)
+	time.Sleep(50 * time.Millisecond)
+	if got := servedSerial(); got.Cmp(before) != 0 {
+		t.Fatalf("served certificate %v changed before the key was replaced", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "key.pem"), renewed.keyPEM(), 0600)
+	deadline := time.Now().Add(2 * time.Second)
+	for servedSerial().Cmp(renewed.cert.SerialNumber) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("renewed certificate was not served")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewReloader_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newCertificate(t, "server-ca", nil)
+	serverCert := newCertificate(t, "localhost", serverCA)
+	valid := writeFiles(t, dir, serverCert, serverCA, "require")
+	notPEM := filepath.Join(dir, "not.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name     string
+		settings func(s *config.TLS)
+	}{
+		{
+			name:     "Missing certificate",
+			settings: func(s *config.TLS) { s.CertFile = filepath.Join(dir, "missing.pem") },
+		},
+		{
+			name:     "Key of another certificate",
+			settings: func(s *config.TLS) { s.CertFile = s.ClientCAFile },
+		},
+		{
+			name:     "Client CA without certificates",
+			settings: func(s *config.TLS) { s.ClientCAFile = notPEM },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := valid
+			tt.settings(&settings)
+
+			if _, err := NewReloader(settings); err == nil {
+				t.Errorf("NewReloader() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		target    string
+		host      string
+		want      string
+	}{
+		{
+			name:      "Host with port",
+			httpsPort: 8443,
+			target:    "/v1/text-management?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec",
+			host:      "example.com:8080",
+			want:      "https://example.com:8443/v1/text-management?id=154ad8a0-1e42-4cf6-9d7b-e49f71dcc4ec",
+		},
+		{
+			name:      "Default HTTPS port",
+			httpsPort: 443,
+			target:    "/docs",
+			host:      "example.com",
+			want:      "https://example.com/docs",
+		},
+		{
+			name:      "IPv6 host",
+			httpsPort: 8443,
+			target:    "/docs",
+			host:      "[::1]:8080",
+			want:      "https://[::1]:8443/docs",
+		},
+		{
+			name:      "IPv6 host on default HTTPS port",
+			httpsPort: 443,
+			target:    "/docs",
+			host:      "[::1]",
+			want:      "https://[::1]/docs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			request.Host = tt.host
+			recorder := httptest.NewRecorder()
+
+			RedirectHandler(tt.httpsPort).ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusPermanentRedirect {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusPermanentRedirect)
+			}
+			if got := recorder.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
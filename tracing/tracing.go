@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"zcelero/apperror"
+	"zcelero/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the application in the exported spans
+const ServiceName = "zcelero"
+
+// Setup installs the W3C trace context propagation and, when an endpoint is configured, a tracer provider
+// exporting the spans over OTLP gRPC. The returned function flushes the pending spans and stops the export
+func Setup(settings config.Tracing) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if settings.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(settings.Endpoint)}
+	if settings.Insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	// the exporter connects in background, so an unreachable collector doesn't stop the application
+	exporter, err := otlptracegrpc.New(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("trace exporter could not be created: %w", err)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), settings.SampleRatio)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider sending the spans to the processor, tests use it with an in-memory
+// exporter. Children follow the sampling decision of their parent
+func NewProvider(processor sdktrace.SpanProcessor, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+}
+
+// Start creates a span named after the operation as a child of the span in the context
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, options...)
+}
+
+// End records the error in the span, if any, and ends it. Errors caused by the client, e.g. a wrong
+// password, are recorded as events but don't mark the span as failed
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		code := apperror.CodeOf(err)
+		span.SetAttributes(attribute.String("error.code", string(code)))
+		if apperror.Status(code) >= 500 {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"zcelero/apperror"
+	"zcelero/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantCode   string
+	}{
+		{
+			name:       "Success",
+			err:        nil,
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "Client error",
+			err:        apperror.New(apperror.WrongPassword, "private_key_password is wrong"),
+			wantStatus: codes.Unset,
+			wantCode:   "wrong_password",
+		},
+		{
+			name:       "Server error",
+			err:        apperror.Wrap(apperror.StorageUnavailable, "text could not be stored", errors.New("disk full")),
+			wantStatus: codes.Error,
+			wantCode:   "storage_unavailable",
+		},
+		{
+			name:       "Error without code",
+			err:        errors.New("error"),
+			wantStatus: codes.Error,
+			wantCode:   "internal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			provider := NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1)
+			_, span := provider.Tracer(ServiceName).Start(context.Background(), "operation")
+
+			End(span, tt.err)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("exported %d spans, want 1", len(spans))
+			}
+			if spans[0].Status.Code != tt.wantStatus {
+				t.Errorf("span status = %v, want %v", spans[0].Status.Code, tt.wantStatus)
+			}
+			code := ""
+			for _, attribute := range spans[0].Attributes {
+				if attribute.Key == "error.code" {
+					code = attribute.Value.AsString()
+				}
+			}
+			if code != tt.wantCode {
+				t.Errorf("span error.code = %q, want %q", code, tt.wantCode)
+			}
+			if (len(spans[0].Events) > 0) != (tt.err != nil) {
+				t.Errorf("span events = %v, want the error recorded", spans[0].Events)
+			}
+		})
+	}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	provider := otel.GetTracerProvider()
+
+	shutdown, err := Setup(config.Tracing{SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+	if otel.GetTracerProvider() != provider {
+		t.Error("Setup() replaced the tracer provider without an endpoint")
+	}
+	if fields := otel.GetTextMapPropagator().Fields(); len(fields) == 0 {
+		t.Error("Setup() did not install the trace context propagation")
+	}
+}
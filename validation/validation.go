@@ -0,0 +1,287 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"zcelero/apperror"
+	"zcelero/entity"
+	"zcelero/textcrypto"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxTextLength is the maximum size in bytes of a text stored without encryption
+var MaxTextLength = 65536
+
+// MinPasswordLength is the minimum size of a private key password
+var MinPasswordLength = 8
+
+// oaepOverhead is the padding added by RSA-OAEP with SHA-256, 2*hLen+2 bytes
+const oaepOverhead = 2*32 + 2
+
+type structValidator struct {
+	once     sync.Once
+	validate *validator.Validate
+	keySizes []uint64
+}
+
+// NewValidator creates the validator used by Gin bindings, reporting every invalid field as an apperror,
+// keySizes are the RSA key sizes accepted in key_size
+func NewValidator(keySizes []uint64) binding.StructValidator {
+	return &structValidator{keySizes: append([]uint64(nil), keySizes...)}
+}
+
+// ValidateStruct validates the struct and returns an apperror listing every invalid field
+func (v *structValidator) ValidateStruct(obj any) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	v.lazyinit()
+	err := v.validate.Struct(obj)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return apperror.Wrap(apperror.ValidationFailed, "request is not valid", err)
+	}
+
+	fields := make([]apperror.FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fields = append(fields, apperror.FieldError{
+			Field:   fieldError.Field(),
+			Code:    fieldError.Tag(),
+			Message: v.message(fieldError),
+		})
+	}
+
+	return apperror.NewValidation(fields)
+}
+
+// Engine returns the underlying validator
+func (v *structValidator) Engine() any {
+	v.lazyinit()
+	return v.validate
+}
+
+func (v *structValidator) lazyinit() {
+	v.once.Do(func() {
+		v.validate = validator.New()
+		v.validate.SetTagName("binding")
+		v.validate.RegisterTagNameFunc(jsonFieldName)
+		v.validate.RegisterValidation("notblank", notBlank)
+		v.validate.RegisterValidation("keysize", v.keySize)
+		v.validate.RegisterValidation("password", password)
+		v.validate.RegisterValidation("maxbytes", maxBytes)
+		v.validate.RegisterValidation("webhookurl", webhookURL)
+		v.validate.RegisterValidation("webhookevent", webhookEvent)
+		v.validate.RegisterValidation("publickey", v.publicKey)
+		v.validate.RegisterStructValidation(v.textManagementCombinations, entity.TextManagement{})
+		v.validate.RegisterStructValidation(rekeyCombinations, entity.Rekey{})
+	})
+}
+
+// MaxEncryptedTextLength returns how many bytes RSA-OAEP can encrypt with the key size
+func MaxEncryptedTextLength(keySize uint64) int {
+	return int(keySize/8) - oaepOverhead
+}
+
+// textManagementCombinations checks the fields that depend on the encryption flag and mode
+func (v *structValidator) textManagementCombinations(sl validator.StructLevel) {
+	text := sl.Current().Interface().(entity.TextManagement)
+	if text.Encryption == nil {
+		return
+	}
+
+	if !*text.Encryption {
+		if text.KeySize != 0 {
+			sl.ReportError(text.KeySize, "key_size", "KeySize", "excluded_without_encryption", "")
+		}
+		if text.PrivateKeyPassword != "" {
+			sl.ReportError(text.PrivateKeyPassword, "private_key_password", "PrivateKeyPassword", "excluded_without_encryption", "")
+		}
+		if text.EncryptionMode != "" {
+			sl.ReportError(text.EncryptionMode, "encryption_mode", "EncryptionMode", "excluded_without_encryption", "")
+		}
+		return
+	}
+
+	if text.EncryptionMode == entity.EncryptionModePassphrase {
+		if text.KeySize != 0 {
+			sl.ReportError(text.KeySize, "key_size", "KeySize", "excluded_with_passphrase", "")
+		}
+		if text.PrivateKeyPassword == "" {
+			sl.ReportError(text.PrivateKeyPassword, "private_key_password", "PrivateKeyPassword", "required_with_encryption", "")
+		}
+		return
+	}
+
+	if text.KeySize == 0 {
+		sl.ReportError(text.KeySize, "key_size", "KeySize", "required_with_encryption", "")
+	}
+	if text.PrivateKeyPassword == "" {
+		sl.ReportError(text.PrivateKeyPassword, "private_key_password", "PrivateKeyPassword", "required_with_encryption", "")
+	}
+	if v.isSupportedKeySize(text.KeySize) && len(text.TextData) > MaxEncryptedTextLength(text.KeySize) {
+		sl.ReportError(text.TextData, "text_data", "TextData", "fits_key_size", fmt.Sprint(MaxEncryptedTextLength(text.KeySize)))
+	}
+}
+
+// rekeyCombinations checks the fields that depend on whether the client sent its own public key
+func rekeyCombinations(sl validator.StructLevel) {
+	rekey := sl.Current().Interface().(entity.Rekey)
+	if rekey.PublicKey == "" {
+		if rekey.NewPrivateKeyPassword == "" {
+			sl.ReportError(rekey.NewPrivateKeyPassword, "new_private_key_password", "NewPrivateKeyPassword", "required_without_public_key", "")
+		}
+		return
+	}
+
+	if rekey.KeySize != 0 {
+		sl.ReportError(rekey.KeySize, "key_size", "KeySize", "excluded_with_public_key", "")
+	}
+	if rekey.NewPrivateKeyPassword != "" {
+		sl.ReportError(rekey.NewPrivateKeyPassword, "new_private_key_password", "NewPrivateKeyPassword", "excluded_with_public_key", "")
+	}
+}
+
+func notBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// webhookURL requires an absolute http or https URL
+func webhookURL(fl validator.FieldLevel) bool {
+	target, err := url.Parse(fl.Field().String())
+	return err == nil && (target.Scheme == "http" || target.Scheme == "https") && target.Host != ""
+}
+
+func webhookEvent(fl validator.FieldLevel) bool {
+	for _, event := range entity.WebhookEvents {
+		if fl.Field().String() == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *structValidator) keySize(fl validator.FieldLevel) bool {
+	return v.isSupportedKeySize(fl.Field().Uint())
+}
+
+// publicKey requires a PEM encoded RSA public key of one of the supported key sizes
+func (v *structValidator) publicKey(fl validator.FieldLevel) bool {
+	publicKey, err := textcrypto.ParsePublicKey(fl.Field().String())
+	return err == nil && v.isSupportedKeySize(uint64(publicKey.N.BitLen()))
+}
+
+func maxBytes(fl validator.FieldLevel) bool {
+	return len(fl.Field().String()) <= MaxTextLength
+}
+
+// password requires a minimum length with both letters and digits
+func password(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if len([]rune(value)) < MinPasswordLength {
+		return false
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, r := range value {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+
+	return hasLetter && hasDigit
+}
+
+func (v *structValidator) isSupportedKeySize(size uint64) bool {
+	for _, supported := range v.keySizes {
+		if size == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func (v *structValidator) message(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "maxbytes":
+		return fmt.Sprintf("must have at most %d bytes", MaxTextLength)
+	case "keysize":
+		return fmt.Sprintf("must be one of %s", v.supportedKeySizes())
+	case "password":
+		return fmt.Sprintf("must have at least %d characters with letters and digits", MinPasswordLength)
+	case "required_with_encryption":
+		return "is required when encryption is true"
+	case "excluded_without_encryption":
+		return "must not be sent when encryption is false"
+	case "excluded_with_passphrase":
+		return "must not be sent with the passphrase encryption_mode"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	case "required_without_public_key":
+		return "is required when public_key is not sent"
+	case "excluded_with_public_key":
+		return "must not be sent with public_key"
+	case "publickey":
+		return fmt.Sprintf("must be a PEM encoded RSA public key of %s bits", v.supportedKeySizes())
+	case "fits_key_size":
+		return fmt.Sprintf("must have at most %s bytes for the key_size", fieldError.Param())
+	case "uuid":
+		return "must be a valid uuid"
+	case "webhookurl":
+		return "must be an absolute http or https URL"
+	case "webhookevent":
+		return fmt.Sprintf("must be one of %s", strings.Join(entity.WebhookEvents, ", "))
+	case "min":
+		if fieldError.Param() == "1" {
+			return "must not be empty"
+		}
+		return fmt.Sprintf("must have at least %s values", fieldError.Param())
+	default:
+		return fmt.Sprintf("failed on the %s rule", fieldError.Tag())
+	}
+}
+
+func (v *structValidator) supportedKeySizes() string {
+	sizes := make([]string, 0, len(v.keySizes))
+	for _, size := range v.keySizes {
+		sizes = append(sizes, fmt.Sprint(size))
+	}
+
+	return strings.Join(sizes, ", ")
+}
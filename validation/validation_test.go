@@ -0,0 +1,296 @@
+package validation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"zcelero/apperror"
+	"zcelero/entity"
+)
+
+func TestStructValidator_ValidateStruct(t *testing.T) {
+	encrypted := true
+	unencrypted := false
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}))
+	tests := []struct {
+		name       string
+		obj        any
+		keySizes   []uint64
+		wantFields []apperror.FieldError
+	}{
+		{
+			name: "Valid encrypted text",
+			obj: &entity.TextManagement{
+				TextData:           "text data",
+				Encryption:         &encrypted,
+				KeySize:            2048,
+				PrivateKeyPassword: "password123",
+			},
+			wantFields: nil,
+		},
+		{
+			name: "Valid unencrypted text",
+			obj: &entity.TextManagement{
+				TextData:   "text data",
+				Encryption: &unencrypted,
+			},
+			wantFields: nil,
+		},
+		{
+			name: "Missing required fields",
+			obj:  &entity.TextManagement{},
+			wantFields: []apperror.FieldError{
+				{Field: "text_data", Code: "required", Message: "is required"},
+				{Field: "encryption", Code: "required", Message: "is required"},
+			},
+		},
+		{
+			name: "Blank text",
+			obj: &entity.TextManagement{
+				TextData:   "   ",
+				Encryption: &unencrypted,
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "text_data", Code: "notblank", Message: "must not be blank"},
+			},
+		},
+		{
+			name: "Text too long",
+			obj: &entity.TextManagement{
+				TextData:   strings.Repeat("a", MaxTextLength+1),
+				Encryption: &unencrypted,
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "text_data", Code: "maxbytes", Message: "must have at most 65536 bytes"},
+			},
+		},
+		{
+			name: "Encryption fields sent without encryption",
+			obj: &entity.TextManagement{
+				TextData:           "text data",
+				Encryption:         &unencrypted,
+				KeySize:            1024,
+				PrivateKeyPassword: "password123",
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "excluded_without_encryption", Message: "must not be sent when encryption is false"},
+				{Field: "private_key_password", Code: "excluded_without_encryption", Message: "must not be sent when encryption is false"},
+			},
+		},
+		{
+			name: "Encryption without key size and password",
+			obj: &entity.TextManagement{
+				TextData:   "text data",
+				Encryption: &encrypted,
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "required_with_encryption", Message: "is required when encryption is true"},
+				{Field: "private_key_password", Code: "required_with_encryption", Message: "is required when encryption is true"},
+			},
+		},
+		{
+			name: "Unsupported key size and weak password",
+			obj: &entity.TextManagement{
+				TextData:           "text data",
+				Encryption:         &encrypted,
+				KeySize:            512,
+				PrivateKeyPassword: "aaa",
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "keysize", Message: "must be one of 1024, 2048, 4096"},
+				{Field: "private_key_password", Code: "password", Message: "must have at least 8 characters with letters and digits"},
+			},
+		},
+		{
+			name: "Text too long for the key size",
+			obj: &entity.TextManagement{
+				TextData:           strings.Repeat("a", 63),
+				Encryption:         &encrypted,
+				KeySize:            1024,
+				PrivateKeyPassword: "password123",
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "text_data", Code: "fits_key_size", Message: "must have at most 62 bytes for the key_size"},
+			},
+		},
+		{
+			name: "Key size not configured",
+			obj: &entity.TextManagement{
+				TextData:           "text data",
+				Encryption:         &encrypted,
+				KeySize:            1024,
+				PrivateKeyPassword: "password123",
+			},
+			keySizes: []uint64{2048},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "keysize", Message: "must be one of 2048"},
+			},
+		},
+		{
+			name: "Valid passphrase text",
+			obj: &entity.TextManagement{
+				TextData:           strings.Repeat("a", MaxTextLength),
+				Encryption:         &encrypted,
+				EncryptionMode:     entity.EncryptionModePassphrase,
+				PrivateKeyPassword: "password123",
+			},
+		},
+		{
+			name: "Passphrase text with key size and without password",
+			obj: &entity.TextManagement{
+				TextData:       "text data",
+				Encryption:     &encrypted,
+				EncryptionMode: entity.EncryptionModePassphrase,
+				KeySize:        2048,
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "excluded_with_passphrase", Message: "must not be sent with the passphrase encryption_mode"},
+				{Field: "private_key_password", Code: "required_with_encryption", Message: "is required when encryption is true"},
+			},
+		},
+		{
+			name: "Unknown encryption mode",
+			obj: &entity.TextManagement{
+				TextData:           "text data",
+				Encryption:         &encrypted,
+				EncryptionMode:     "aes",
+				KeySize:            2048,
+				PrivateKeyPassword: "password123",
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "encryption_mode", Code: "oneof", Message: "must be one of rsa, passphrase"},
+			},
+		},
+		{
+			name: "Encryption mode sent without encryption",
+			obj: &entity.TextManagement{
+				TextData:       "text data",
+				Encryption:     &unencrypted,
+				EncryptionMode: entity.EncryptionModePassphrase,
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "encryption_mode", Code: "excluded_without_encryption", Message: "must not be sent when encryption is false"},
+			},
+		},
+		{
+			name: "Valid webhook subscription",
+			obj: &entity.WebhookSubscription{
+				Url:    "https://hooks.example.com/zcelero",
+				Events: []string{entity.WebhookEventRead, entity.WebhookEventDeleted},
+			},
+			wantFields: nil,
+		},
+		{
+			name: "Invalid webhook subscription",
+			obj: &entity.WebhookSubscription{
+				Url:    "file:///etc/passwd",
+				Events: []string{entity.WebhookEventRead, "text.created"},
+				TextId: "not a uuid",
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "url", Code: "webhookurl", Message: "must be an absolute http or https URL"},
+				{Field: "events[1]", Code: "webhookevent", Message: "must be one of text.read, text.deleted, text.decryption_failed, text.rekeyed"},
+				{Field: "text_id", Code: "uuid", Message: "must be a valid uuid"},
+			},
+		},
+		{
+			name: "Webhook subscription without events",
+			obj: &entity.WebhookSubscription{
+				Url:    "http://localhost:9000",
+				Events: []string{},
+			},
+			wantFields: []apperror.FieldError{
+				{Field: "events", Code: "min", Message: "must not be empty"},
+			},
+		},
+		{
+			name: "Valid rekey to a new key pair",
+			obj:  &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123", NewPrivateKeyPassword: "password456"},
+		},
+		{
+			name: "Valid rekey to a public key",
+			obj:  &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123", PublicKey: publicKey},
+		},
+		{
+			name: "Rekey without new password nor public key",
+			obj:  &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123"},
+			wantFields: []apperror.FieldError{
+				{Field: "new_private_key_password", Code: "required_without_public_key", Message: "is required when public_key is not sent"},
+			},
+		},
+		{
+			name: "Rekey with a public key and key pair fields",
+			obj:  &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123", KeySize: 2048, NewPrivateKeyPassword: "password456", PublicKey: publicKey},
+			wantFields: []apperror.FieldError{
+				{Field: "key_size", Code: "excluded_with_public_key", Message: "must not be sent with public_key"},
+				{Field: "new_private_key_password", Code: "excluded_with_public_key", Message: "must not be sent with public_key"},
+			},
+		},
+		{
+			name:     "Rekey with a public key of an unsupported size",
+			obj:      &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123", PublicKey: publicKey},
+			keySizes: []uint64{2048, 4096},
+			wantFields: []apperror.FieldError{
+				{Field: "public_key", Code: "publickey", Message: "must be a PEM encoded RSA public key of 2048, 4096 bits"},
+			},
+		},
+		{
+			name: "Rekey with an invalid public key",
+			obj:  &entity.Rekey{PrivateKey: "key", PrivateKeyPassword: "password123", PublicKey: "not a key"},
+			wantFields: []apperror.FieldError{
+				{Field: "public_key", Code: "publickey", Message: "must be a PEM encoded RSA public key of 1024, 2048, 4096 bits"},
+			},
+		},
+		{
+			name:       "Non struct value",
+			obj:        "text data",
+			wantFields: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySizes := tt.keySizes
+			if keySizes == nil {
+				keySizes = []uint64{1024, 2048, 4096}
+			}
+
+			err := NewValidator(keySizes).ValidateStruct(tt.obj)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("structValidator.ValidateStruct() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var appError *apperror.Error
+			if !errors.As(err, &appError) || appError.Code != apperror.ValidationFailed {
+				t.Fatalf("structValidator.ValidateStruct() error = %v, want %s", err, apperror.ValidationFailed)
+			}
+			if !reflect.DeepEqual(appError.Fields, tt.wantFields) {
+				t.Errorf("structValidator.ValidateStruct() fields = %v, want %v", appError.Fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestMaxEncryptedTextLength(t *testing.T) {
+	tests := []struct {
+		keySize uint64
+		want    int
+	}{
+		{1024, 62},
+		{2048, 190},
+		{4096, 446},
+	}
+	for _, tt := range tests {
+		if got := MaxEncryptedTextLength(tt.keySize); got != tt.want {
+			t.Errorf("MaxEncryptedTextLength(%d) = %d, want %d", tt.keySize, got, tt.want)
+		}
+	}
+}